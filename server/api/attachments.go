@@ -0,0 +1,206 @@
+package api
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// defaultMaxFileSize is the largest file accepted when the server does not configure a maximum
+// file size.
+const defaultMaxFileSize = 100 * 1024 * 1024
+
+// inlineMimeTypes are the types of the attachments displayed by browsers rather than downloaded.
+var inlineMimeTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AttachmentHandler is the API handler for the attachments of wikiDocs.
+type AttachmentHandler struct {
+	*ErrorHandler
+	attachmentService app.AttachmentService
+	wikiDocService    app.WikiDocService
+	permissions       *app.PermissionsService
+	pluginAPI         *pluginapi.Client
+	log               bot.Logger
+}
+
+// NewAttachmentHandler Creates a new attachment API handler.
+func NewAttachmentHandler(
+	router *mux.Router,
+	attachmentService app.AttachmentService,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *AttachmentHandler {
+	handler := &AttachmentHandler{
+		ErrorHandler:      &ErrorHandler{log: log},
+		attachmentService: attachmentService,
+		wikiDocService:    wikiDocService,
+		permissions:       permissions,
+		pluginAPI:         api,
+		log:               log,
+	}
+
+	attachmentsRouter := router.PathPrefix("/wikiDocs/{id:[A-Za-z0-9]+}/attachments").Subrouter()
+	attachmentsRouter.HandleFunc("", handler.uploadAttachment).Methods(http.MethodPost)
+
+	attachmentsRouterViewable := attachmentsRouter.PathPrefix("").Subrouter()
+	attachmentsRouterViewable.Use(checkViewPermissions(handler.ErrorHandler, permissions))
+	attachmentsRouterViewable.HandleFunc("", handler.getAttachments).Methods(http.MethodGet)
+	attachmentsRouterViewable.HandleFunc("/{attachment_id:[A-Za-z0-9]+}", handler.downloadAttachment).Methods(http.MethodGet)
+	attachmentsRouterViewable.HandleFunc("/{attachment_id:[A-Za-z0-9]+}", handler.deleteAttachment).Methods(http.MethodDelete)
+
+	return handler
+}
+
+// getAttachments handles the GET /wikiDocs/{id}/attachments endpoint, user has view permissions.
+func (h *AttachmentHandler) getAttachments(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+
+	attachments, err := h.attachmentService.GetAttachments(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, attachments, http.StatusOK)
+}
+
+// uploadAttachment handles the POST /wikiDocs/{id}/attachments endpoint, attaching the file of the
+// multipart "file" field. The user must be able to edit the wikiDoc, and the file must not be larger
+// than the maximum file size of the server.
+func (h *AttachmentHandler) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	} else if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc)) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileSize(h.pluginAPI))
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to read the file, or it is too large", err)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(wikiDoc, userID, header.Filename, file)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedAttachment) || errors.Is(err, app.ErrWikiDocInTrash) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to attach the file", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, attachment, http.StatusCreated)
+}
+
+// maxFileSize returns the maximum file size of the server.
+func maxFileSize(api *pluginapi.Client) int64 {
+	config := api.Configuration.GetConfig()
+	if config == nil || config.FileSettings.MaxFileSize == nil || *config.FileSettings.MaxFileSize <= 0 {
+		return defaultMaxFileSize
+	}
+
+	return *config.FileSettings.MaxFileSize
+}
+
+// downloadAttachment handles the GET /wikiDocs/{id}/attachments/{attachment_id} endpoint, user has
+// view permissions. Images are displayed inline, other files are downloaded.
+func (h *AttachmentHandler) downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.loadAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := h.attachmentService.Open(attachment)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	disposition := "attachment"
+	if inlineMimeTypes[attachment.MimeType] {
+		disposition = "inline"
+	}
+	contentType := attachment.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name}))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, content); err != nil {
+		h.log.Warnf("failed to send attachment %s: %v", attachment.ID, err)
+	}
+}
+
+// deleteAttachment handles the DELETE /wikiDocs/{id}/attachments/{attachment_id} endpoint. The user
+// must be able to edit the wikiDoc.
+func (h *AttachmentHandler) deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	attachment, ok := h.loadAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(attachment.WikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc)) {
+		return
+	}
+
+	if err = h.attachmentService.Delete(attachment.ID); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadAttachment returns the attachment of the request. Otherwise, it writes the error response and
+// returns false.
+func (h *AttachmentHandler) loadAttachment(w http.ResponseWriter, r *http.Request) (app.Attachment, bool) {
+	attachment, err := h.attachmentService.Get(mux.Vars(r)["attachment_id"])
+	if !h.CheckChildOfWikiDoc(w, r, "attachment", attachment.WikiDocID, err) {
+		return app.Attachment{}, false
+	}
+
+	return attachment, true
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// ChannelSettingsHandler is the API handler for the wiki settings of channels.
+type ChannelSettingsHandler struct {
+	*ErrorHandler
+	settingsService app.ChannelSettingsService
+	permissions     *app.PermissionsService
+	pluginAPI       *pluginapi.Client
+	log             bot.Logger
+}
+
+// NewChannelSettingsHandler Creates a new channel settings API handler.
+func NewChannelSettingsHandler(
+	router *mux.Router,
+	settingsService app.ChannelSettingsService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *ChannelSettingsHandler {
+	handler := &ChannelSettingsHandler{
+		ErrorHandler:    &ErrorHandler{log: log},
+		settingsService: settingsService,
+		permissions:     permissions,
+		pluginAPI:       api,
+		log:             log,
+	}
+
+	settingsRouter := router.PathPrefix("/channels/{channel_id:[A-Za-z0-9]+}/settings").Subrouter()
+	settingsRouter.HandleFunc("", handler.getSettings).Methods(http.MethodGet)
+	settingsRouter.HandleFunc("", handler.updateSettings).Methods(http.MethodPut)
+
+	return handler
+}
+
+// getSettings handles the GET /channels/{channel_id}/settings endpoint. The user must be able to
+// list the wikiDocs of the channel.
+func (h *ChannelSettingsHandler) getSettings(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	settings, err := h.settingsService.Get(channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, settings, http.StatusOK)
+}
+
+// updateSettings handles the PUT /channels/{channel_id}/settings endpoint. The user must be able to
+// edit the wikiDocs of the channel, and to own its wiki to change the required approvals.
+func (h *ChannelSettingsHandler) updateSettings(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, app.WikiDoc{ChannelID: channelID})) {
+		return
+	}
+
+	var settings app.ChannelSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode channel settings", err)
+		return
+	}
+	settings.ChannelID = channelID
+
+	current, err := h.settingsService.Get(channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if settings.RequiredApprovals != current.RequiredApprovals {
+		if !h.PermissionsCheck(w, h.permissions.ChannelApprovals(userID, channelID)) {
+			return
+		}
+	}
+
+	updatedSettings, err := h.settingsService.Update(settings)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "invalid channel settings", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, updatedSettings, http.StatusOK)
+}
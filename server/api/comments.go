@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// CommentHandler is the API handler for the comment threads of wikiDocs.
+type CommentHandler struct {
+	*ErrorHandler
+	commentService app.CommentService
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	log            bot.Logger
+}
+
+// NewCommentHandler Creates a new comment API handler.
+func NewCommentHandler(
+	router *mux.Router,
+	commentService app.CommentService,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *CommentHandler {
+	handler := &CommentHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		commentService: commentService,
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		log:            log,
+	}
+
+	commentsRouter := router.PathPrefix("/wikiDocs/{id:[A-Za-z0-9]+}/comments").Subrouter()
+	commentsRouter.Use(checkViewPermissions(handler.ErrorHandler, permissions))
+	commentsRouter.HandleFunc("", handler.getComments).Methods(http.MethodGet)
+	commentsRouter.HandleFunc("", handler.createComment).Methods(http.MethodPost)
+
+	commentRouter := commentsRouter.PathPrefix("/{comment_id:[A-Za-z0-9]+}").Subrouter()
+	commentRouter.HandleFunc("", handler.getComment).Methods(http.MethodGet)
+	commentRouter.HandleFunc("", handler.editComment).Methods(http.MethodPatch)
+	commentRouter.HandleFunc("", handler.deleteComment).Methods(http.MethodDelete)
+	commentRouter.HandleFunc("/resolve", handler.resolve).Methods(http.MethodPost)
+	commentRouter.HandleFunc("/reopen", handler.reopen).Methods(http.MethodPost)
+
+	return handler
+}
+
+// getComments handles the GET /wikiDocs/{id}/comments endpoint, user has view permissions.
+func (h *CommentHandler) getComments(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+
+	comments, err := h.commentService.GetComments(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, comments, http.StatusOK)
+}
+
+// createCommentRequest is the body of the POST /wikiDocs/{id}/comments endpoint.
+type createCommentRequest struct {
+	// ParentID is the thread the comment replies to. A new thread is started when empty.
+	ParentID string `json:"parent_id"`
+
+	Message string `json:"message"`
+
+	// Anchor is the text a new thread is about. Its revision defaults to the current one, and its
+	// offset, when given, designates which occurrence of the quote is meant.
+	Anchor *app.CommentAnchor `json:"anchor"`
+}
+
+// createComment handles the POST /wikiDocs/{id}/comments endpoint, user has view permissions.
+func (h *CommentHandler) createComment(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request createCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode comment", err)
+		return
+	}
+
+	comment, err := h.commentService.Create(app.Comment{
+		WikiDocID: wikiDocID,
+		ParentID:  request.ParentID,
+		UserID:    userID,
+		Message:   request.Message,
+		Anchor:    request.Anchor,
+	})
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedComment) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to create comment", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, comment, http.StatusCreated)
+}
+
+// getComment handles the GET /wikiDocs/{id}/comments/{comment_id} endpoint, user has view permissions.
+func (h *CommentHandler) getComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := h.loadComment(w, r)
+	if !ok {
+		return
+	}
+
+	ReturnJSON(w, comment, http.StatusOK)
+}
+
+// editCommentRequest is the body of the PATCH /wikiDocs/{id}/comments/{comment_id} endpoint.
+type editCommentRequest struct {
+	Message string `json:"message"`
+}
+
+// editComment handles the PATCH /wikiDocs/{id}/comments/{comment_id} endpoint. Only the author of
+// the comment can edit it.
+func (h *CommentHandler) editComment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	comment, ok := h.loadComment(w, r)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID {
+		h.HandleErrorWithCode(w, http.StatusForbidden, "only the author can edit a comment", app.ErrNoPermissions)
+		return
+	}
+
+	var request editCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode comment", err)
+		return
+	}
+
+	updatedComment, err := h.commentService.Edit(comment, request.Message)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedComment) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to edit comment", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, updatedComment, http.StatusOK)
+}
+
+// deleteComment handles the DELETE /wikiDocs/{id}/comments/{comment_id} endpoint, deleting the
+// replies of a thread along with it. The author of the comment can delete it, as well as the users
+// who can edit the wikiDoc.
+func (h *CommentHandler) deleteComment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	comment, ok := h.loadComment(w, r)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID && !h.canModerate(w, userID, comment.WikiDocID) {
+		return
+	}
+
+	if err := h.commentService.Delete(comment.ID); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolve handles the POST /wikiDocs/{id}/comments/{comment_id}/resolve endpoint. The author of the
+// thread can resolve it, as well as the users who can edit the wikiDoc.
+func (h *CommentHandler) resolve(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, true)
+}
+
+// reopen handles the POST /wikiDocs/{id}/comments/{comment_id}/reopen endpoint. The author of the
+// thread can reopen it, as well as the users who can edit the wikiDoc.
+func (h *CommentHandler) reopen(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, false)
+}
+
+func (h *CommentHandler) setResolved(w http.ResponseWriter, r *http.Request, resolved bool) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	comment, ok := h.loadComment(w, r)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID && !h.canModerate(w, userID, comment.WikiDocID) {
+		return
+	}
+
+	var err error
+	if resolved {
+		comment, err = h.commentService.Resolve(comment, userID)
+	} else {
+		comment, err = h.commentService.Reopen(comment)
+	}
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedComment) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to change the resolution of the thread", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, comment, http.StatusOK)
+}
+
+// loadComment returns the comment of the request. Otherwise, it writes the error response and returns
+// false.
+func (h *CommentHandler) loadComment(w http.ResponseWriter, r *http.Request) (app.Comment, bool) {
+	comment, err := h.commentService.Get(mux.Vars(r)["comment_id"])
+	if !h.CheckChildOfWikiDoc(w, r, "comment", comment.WikiDocID, err) {
+		return app.Comment{}, false
+	}
+
+	return comment, true
+}
+
+// canModerate checks that the user can edit the wikiDoc, and so moderate its comments. Otherwise,
+// it writes the error response and returns false.
+func (h *CommentHandler) canModerate(w http.ResponseWriter, userID, wikiDocID string) bool {
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return false
+	}
+
+	return h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc))
+}
@@ -6,6 +6,10 @@ package api
 import (
 	"net/http"
 
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
 )
 
@@ -35,3 +39,18 @@ func (h *ErrorHandler) PermissionsCheck(w http.ResponseWriter, checkOutput error
 
 	return true
 }
+
+// CheckChildOfWikiDoc handles the loading of a child of a wikiDoc, such as a comment or an
+// attachment, named kind: it must exist and belong to the wikiDoc given by the {id} of the path.
+// Returns true if it does and false, after writing the error response, otherwise.
+func (h *ErrorHandler) CheckChildOfWikiDoc(w http.ResponseWriter, r *http.Request, kind string, childWikiDocID string, loadErr error) bool {
+	if errors.Is(loadErr, app.ErrNotFound) || (loadErr == nil && childWikiDocID != mux.Vars(r)["id"]) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, kind+" not found", loadErr)
+		return false
+	} else if loadErr != nil {
+		h.HandleError(w, loadErr)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+)
+
+// conflictResponse is returned along with 409 Conflict when a write is based on a stale version.
+type conflictResponse struct {
+	Error   string      `json:"error"`
+	Current app.WikiDoc `json:"current"`
+}
+
+// wikiDocETag returns the entity tag of a wikiDoc, derived from its UpdateAt version.
+func wikiDocETag(wikiDoc app.WikiDoc) string {
+	return `"` + strconv.FormatInt(wikiDoc.UpdateAt, 10) + `"`
+}
+
+// setETag sets the ETag header of the response to the version of the given wikiDoc.
+func setETag(w http.ResponseWriter, wikiDoc app.WikiDoc) {
+	w.Header().Set("ETag", wikiDocETag(wikiDoc))
+}
+
+// matchesIfMatch returns true if the If-Match header of the request is absent, is "*", or
+// lists the current version of the wikiDoc.
+func matchesIfMatch(r *http.Request, wikiDoc app.WikiDoc) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	current := wikiDocETag(wikiDoc)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch verifies the If-Match precondition of the request against the current version of
+// the wikiDoc, responding with 409 Conflict when it fails.
+// Returns true if the check passed. Correct use is: if !h.checkIfMatch(w, r, wikiDoc) { return }
+func (h *WikiDocHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, wikiDoc app.WikiDoc) bool {
+	if matchesIfMatch(r, wikiDoc) {
+		return true
+	}
+
+	h.writeConflict(w, wikiDoc)
+	return false
+}
+
+// handleConflict responds with 409 Conflict and the latest version of the wikiDoc after a write
+// failed with app.ErrConflict.
+func (h *WikiDocHandler) handleConflict(w http.ResponseWriter, wikiDocID string, internalErr error) {
+	current, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	h.log.Debugf("conflicting write to wikiDoc %s: %v", wikiDocID, internalErr)
+	h.writeConflict(w, current)
+}
+
+func (h *WikiDocHandler) writeConflict(w http.ResponseWriter, current app.WikiDoc) {
+	setETag(w, current)
+	ReturnJSON(w, conflictResponse{
+		Error:   "The wikiDoc was modified by someone else. Reconcile your changes with the current version and try again.",
+		Current: current,
+	}, http.StatusConflict)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// ExportHandler is the API handler for the export of channel and team wikis.
+type ExportHandler struct {
+	*ErrorHandler
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	log            bot.Logger
+}
+
+// NewExportHandler Creates a new export API handler.
+func NewExportHandler(
+	router *mux.Router,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *ExportHandler {
+	handler := &ExportHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		log:            log,
+	}
+
+	router.HandleFunc("/channels/{channel_id:[A-Za-z0-9]+}/export", handler.exportChannel).Methods(http.MethodGet)
+	router.HandleFunc("/teams/{team_id:[A-Za-z0-9]+}/export", handler.exportTeam).Methods(http.MethodGet)
+
+	return handler
+}
+
+// exportChannel handles the GET /channels/{channel_id}/export endpoint, streaming the wikiDocs of
+// the channel the user can read as a zip archive.
+func (h *ExportHandler) exportChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	channel, err := h.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		h.HandleError(w, errors.Wrapf(err, "failed to get channel %s", channelID))
+		return
+	}
+
+	tree, err := h.visibleTree(userID, channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	h.export(w, channel.Name, app.ExportManifest{TeamID: channel.TeamId, ChannelID: channelID}, []app.ExportChannel{
+		{ChannelID: channelID, Tree: tree},
+	})
+}
+
+// exportTeam handles the GET /teams/{team_id}/export endpoint, streaming the wikiDocs of the team
+// the user can read as a zip archive, with a folder per channel. The user must be a member of the
+// team.
+func (h *ExportHandler) exportTeam(w http.ResponseWriter, r *http.Request) {
+	teamID := mux.Vars(r)["team_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !app.IsSystemAdmin(userID, h.pluginAPI) && !app.IsMemberOfTeam(userID, teamID, h.pluginAPI) {
+		h.HandleErrorWithCode(w, http.StatusForbidden, "Not authorized", errors.Wrapf(app.ErrNoPermissions, "not a member of team %s", teamID))
+		return
+	}
+
+	team, err := h.pluginAPI.Team.Get(teamID)
+	if err != nil {
+		h.HandleError(w, errors.Wrapf(err, "failed to get team %s", teamID))
+		return
+	}
+
+	channelIDs, err := h.wikiDocService.GetChannelIDs(teamID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var channels []app.ExportChannel
+	for _, channelID := range channelIDs {
+		if h.permissions.WikiDocList(userID, channelID) != nil {
+			continue
+		}
+
+		channel, err := h.pluginAPI.Channel.Get(channelID)
+		if err != nil {
+			h.HandleError(w, errors.Wrapf(err, "failed to get channel %s", channelID))
+			return
+		}
+
+		tree, err := h.visibleTree(userID, channelID)
+		if err != nil {
+			h.HandleError(w, err)
+			return
+		}
+		if len(tree) > 0 {
+			channels = append(channels, app.ExportChannel{ChannelID: channelID, Folder: channel.Name, Tree: tree})
+		}
+	}
+
+	h.export(w, team.Name, app.ExportManifest{TeamID: teamID}, channels)
+}
+
+// visibleTree returns the wikiDocs of a channel the user can read, nested under their parents.
+func (h *ExportHandler) visibleTree(userID, channelID string) ([]*app.WikiDocNode, error) {
+	tree, err := h.wikiDocService.GetTree(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.permissions.FilterWikiDocTree(userID, channelID, tree)
+}
+
+// export streams the archive. Once it started, errors can only be logged: the archive is left
+// incomplete.
+func (h *ExportHandler) export(w http.ResponseWriter, name string, manifest app.ExportManifest, channels []app.ExportChannel) {
+	filename := fmt.Sprintf("%s-wiki.zip", name)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.wikiDocService.Export(w, manifest, channels); err != nil {
+		h.log.Warnf("failed to export the wiki %s: %v", name, err)
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"archive/zip"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// ImportHandler is the API handler for the import of wikiDocs into channels.
+type ImportHandler struct {
+	*ErrorHandler
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	log            bot.Logger
+}
+
+// NewImportHandler Creates a new import API handler.
+func NewImportHandler(
+	router *mux.Router,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *ImportHandler {
+	handler := &ImportHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		log:            log,
+	}
+
+	importRouter := router.PathPrefix("/channels/{channel_id:[A-Za-z0-9]+}/import").Subrouter()
+	importRouter.HandleFunc("", handler.importMarkdown).Methods(http.MethodPost)
+	importRouter.HandleFunc("/mediawiki", handler.importMediaWiki).Methods(http.MethodPost)
+	importRouter.HandleFunc("/confluence", handler.importConfluence).Methods(http.MethodPost)
+
+	return handler
+}
+
+// importMarkdown handles the POST /channels/{channel_id}/import endpoint, importing the zip archive
+// of Markdown files of the multipart "file" field. The dry_run=true parameter only reports what
+// would be imported, and the conflict=skip|overwrite|rename parameter says what to do with the
+// files named like a wikiDoc of the channel. The user must be able to create wikiDocs in the
+// channel, and to edit the wikiDocs it overwrites. The Markdown files must not add up to more than
+// the maximum file size of the server once uncompressed.
+func (h *ImportHandler) importMarkdown(w http.ResponseWriter, r *http.Request) {
+	h.importFile(w, r, func(file multipart.File, size int64) ([]app.ImportDoc, error) {
+		archive, err := zip.NewReader(file, size)
+		if err != nil {
+			return nil, errors.Wrapf(app.ErrMalformedImport, "not a zip archive: %v", err)
+		}
+		return app.ReadMarkdownArchive(archive, maxFileSize(h.pluginAPI))
+	})
+}
+
+// importMediaWiki handles the POST /channels/{channel_id}/import/mediawiki endpoint, importing the
+// MediaWiki XML dump of the multipart "file" field, with the revisions of its pages. The parameters
+// and permissions are those of the import of Markdown files.
+func (h *ImportHandler) importMediaWiki(w http.ResponseWriter, r *http.Request) {
+	h.importFile(w, r, func(file multipart.File, size int64) ([]app.ImportDoc, error) {
+		return app.ReadMediaWikiDump(file)
+	})
+}
+
+// importConfluence handles the POST /channels/{channel_id}/import/confluence endpoint, importing
+// the Confluence space export of the multipart "file" field, a zip archive of its entities.xml and
+// attachments, with the older versions of its pages. The parameters and permissions are those of
+// the import of Markdown files, and neither the entities nor any attachment may be larger than the
+// maximum file size of the server once uncompressed.
+func (h *ImportHandler) importConfluence(w http.ResponseWriter, r *http.Request) {
+	h.importFile(w, r, func(file multipart.File, size int64) ([]app.ImportDoc, error) {
+		archive, err := zip.NewReader(file, size)
+		if err != nil {
+			return nil, errors.Wrapf(app.ErrMalformedImport, "not a zip archive: %v", err)
+		}
+		return app.ReadConfluenceExport(archive, maxFileSize(h.pluginAPI))
+	})
+}
+
+// importFile imports the documents read from the multipart "file" field, and returns the report of
+// the import. The file must not be larger than the maximum file size of the server.
+func (h *ImportHandler) importFile(w http.ResponseWriter, r *http.Request, read func(file multipart.File, size int64) ([]app.ImportDoc, error)) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	options, ok := h.importOptions(w, r, userID, channelID)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileSize(h.pluginAPI))
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to read the file, or it is too large", err)
+		return
+	}
+	defer file.Close()
+
+	docs, err := read(file, header.Size)
+	if err != nil {
+		h.handleImportError(w, err)
+		return
+	}
+
+	report, err := h.wikiDocService.Import(docs, options)
+	if err != nil {
+		h.handleImportError(w, err)
+		return
+	}
+
+	ReturnJSON(w, report, http.StatusOK)
+}
+
+// importOptions reads the options of an import request, and checks that the user can create
+// wikiDocs in the channel. Otherwise, it writes the error response and returns false.
+func (h *ImportHandler) importOptions(w http.ResponseWriter, r *http.Request, userID, channelID string) (app.ImportOptions, bool) {
+	query := r.URL.Query()
+
+	options := app.ImportOptions{
+		ChannelID: channelID,
+		UserID:    userID,
+		Conflict:  app.ConflictPolicy(query.Get("conflict")),
+		CanEdit: func(wikiDoc app.WikiDoc) error {
+			return h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc)
+		},
+	}
+	if !app.ValidConflictPolicy(options.Conflict) {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'conflict': must be skip, overwrite or rename", nil)
+		return app.ImportOptions{}, false
+	}
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		var err error
+		if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'dry_run'", err)
+			return app.ImportOptions{}, false
+		}
+	}
+
+	if err := h.permissions.WikiDocCreate(app.WikiDoc{OwnerUserID: userID, ChannelID: channelID}); err != nil {
+		h.HandleErrorWithCode(w, http.StatusForbidden, "Not authorized to create wikiDocs in the channel", err)
+		return app.ImportOptions{}, false
+	}
+
+	channel, err := h.pluginAPI.Channel.Get(channelID)
+	if err != nil {
+		h.HandleError(w, errors.Wrapf(err, "failed to get channel %s", channelID))
+		return app.ImportOptions{}, false
+	}
+	options.TeamID = channel.TeamId
+
+	return options, true
+}
+
+func (h *ImportHandler) handleImportError(w http.ResponseWriter, err error) {
+	if errors.Is(err, app.ErrMalformedImport) {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to import the archive", err)
+		return
+	}
+	h.HandleError(w, err)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// ReportHandler is the API handler for the reports of channel wikis.
+type ReportHandler struct {
+	*ErrorHandler
+	reportService app.ReportService
+	permissions   *app.PermissionsService
+	pluginAPI     *pluginapi.Client
+	log           bot.Logger
+}
+
+// NewReportHandler Creates a new report API handler.
+func NewReportHandler(
+	router *mux.Router,
+	reportService app.ReportService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *ReportHandler {
+	handler := &ReportHandler{
+		ErrorHandler:  &ErrorHandler{log: log},
+		reportService: reportService,
+		permissions:   permissions,
+		pluginAPI:     api,
+		log:           log,
+	}
+
+	reportRouter := router.PathPrefix("/channels/{channel_id:[A-Za-z0-9]+}/report").Subrouter()
+	reportRouter.HandleFunc("", handler.getReport).Methods(http.MethodGet)
+	reportRouter.HandleFunc("", handler.generateReport).Methods(http.MethodPost)
+	reportRouter.HandleFunc("/post", handler.postReport).Methods(http.MethodPost)
+
+	return handler
+}
+
+// getReport handles the GET /channels/{channel_id}/report endpoint, returning the last report of
+// the channel wiki. The channel is scanned if it was not yet.
+func (h *ReportHandler) getReport(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiReport(userID, channelID)) {
+		return
+	}
+
+	report, err := h.reportService.Get(channelID)
+	if errors.Is(err, app.ErrNotFound) {
+		report, err = h.reportService.Generate(channelID)
+	}
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, report, http.StatusOK)
+}
+
+// generateReport handles the POST /channels/{channel_id}/report endpoint, scanning the channel wiki
+// again and returning its new report.
+func (h *ReportHandler) generateReport(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiReport(userID, channelID)) {
+		return
+	}
+
+	report, err := h.reportService.Generate(channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, report, http.StatusOK)
+}
+
+// postReport handles the POST /channels/{channel_id}/report/post endpoint, having the bot post the
+// last report of the channel wiki in the channel.
+func (h *ReportHandler) postReport(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiReport(userID, channelID)) {
+		return
+	}
+
+	err := h.reportService.Post(channelID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "the channel wiki was not scanned yet", err)
+		return
+	} else if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
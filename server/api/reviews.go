@@ -0,0 +1,372 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// outdatedReviewText answers the reviewers deciding on a version of a wikiDoc that was modified since.
+const outdatedReviewText = "The wiki doc was modified since your review was requested. Review its new version once it is requested again."
+
+// ReviewHandler is the API handler for the reviews of wikiDocs.
+type ReviewHandler struct {
+	*ErrorHandler
+	reviewService  app.ReviewService
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	botUserID      string
+	log            bot.Logger
+}
+
+// NewReviewHandler Creates a new review API handler.
+func NewReviewHandler(
+	router *mux.Router,
+	reviewService app.ReviewService,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	botUserID string,
+	log bot.Logger,
+) *ReviewHandler {
+	handler := &ReviewHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		reviewService:  reviewService,
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		botUserID:      botUserID,
+		log:            log,
+	}
+
+	wikiDocReviewsRouter := router.PathPrefix("/wikiDocs/{id:[A-Za-z0-9]+}/reviews").Subrouter()
+	wikiDocReviewsRouter.HandleFunc("", handler.getReviews).Methods(http.MethodGet)
+	wikiDocReviewsRouter.HandleFunc("", handler.requestReviews).Methods(http.MethodPost)
+	wikiDocReviewsRouter.HandleFunc("/approve", handler.approve).Methods(http.MethodPost)
+	wikiDocReviewsRouter.HandleFunc("/requestChanges", handler.requestChanges).Methods(http.MethodPost)
+
+	reviewsRouter := router.PathPrefix("/reviews").Subrouter()
+	reviewsRouter.HandleFunc("/actions/{action:approve|reject}", handler.action).Methods(http.MethodPost)
+	reviewsRouter.HandleFunc("/dialog", handler.requestChangesFromDialog).Methods(http.MethodPost)
+
+	return handler
+}
+
+// getReviews handles the GET /wikiDocs/{id}/reviews endpoint, user has view permissions.
+func (h *ReviewHandler) getReviews(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	err := h.permissions.WikiDocView(userID, wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	}
+	if !h.PermissionsCheck(w, err) {
+		return
+	}
+
+	reviews, err := h.reviewService.GetReviews(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, reviews, http.StatusOK)
+}
+
+// requestReviewsRequest is the body of the POST /wikiDocs/{id}/reviews endpoint.
+type requestReviewsRequest struct {
+	// ReviewerIDs are the users asked to review the wikiDoc. They must be able to read it.
+	ReviewerIDs []string `json:"reviewer_ids"`
+}
+
+// requestReviews handles the POST /wikiDocs/{id}/reviews endpoint, user has edit permissions.
+func (h *ReviewHandler) requestReviews(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc)) {
+		return
+	}
+
+	var request requestReviewsRequest
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode review request", err)
+		return
+	}
+
+	for _, reviewerID := range request.ReviewerIDs {
+		if !model.IsValidId(reviewerID) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, fmt.Sprintf("invalid reviewer id '%s'", reviewerID), nil)
+			return
+		}
+		if err = h.permissions.WikiDocView(reviewerID, wikiDocID); err != nil {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, fmt.Sprintf("user %s cannot read this wikiDoc", reviewerID), err)
+			return
+		}
+	}
+
+	reviews, err := h.reviewService.RequestReviews(wikiDoc, request.ReviewerIDs, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedReview) || errors.Is(err, app.ErrInvalidTransition) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to request reviews", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, reviews, http.StatusCreated)
+}
+
+// reviewDecisionRequest is the body of the endpoints recording the decision of a reviewer.
+type reviewDecisionRequest struct {
+	// UpdateAt is the version of the wikiDoc the reviewer decided on.
+	UpdateAt int64  `json:"update_at"`
+	Comment  string `json:"comment"`
+}
+
+// approve handles the POST /wikiDocs/{id}/reviews/approve endpoint. The user must have been asked
+// to review the wikiDoc.
+func (h *ReviewHandler) approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.reviewService.Approve)
+}
+
+// requestChanges handles the POST /wikiDocs/{id}/reviews/requestChanges endpoint. The user must
+// have been asked to review the wikiDoc.
+func (h *ReviewHandler) requestChanges(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.reviewService.RequestChanges)
+}
+
+func (h *ReviewHandler) decide(w http.ResponseWriter, r *http.Request, decide func(wikiDocID, reviewerID string, updateAt int64, comment string) (app.Review, error)) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request reviewDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode review", err)
+		return
+	}
+
+	if request.UpdateAt == 0 {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "missing the version of the wikiDoc reviewed in 'update_at'", nil)
+		return
+	}
+
+	review, err := decide(wikiDocID, userID, request.UpdateAt, request.Comment)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusForbidden, "You were not asked to review this wikiDoc.", err)
+			return
+		}
+		if errors.Is(err, app.ErrConflict) {
+			h.HandleErrorWithCode(w, http.StatusConflict, "The wikiDoc was modified since the version reviewed.", err)
+			return
+		}
+		if errors.Is(err, app.ErrMalformedReview) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to review wikiDoc", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, review, http.StatusOK)
+}
+
+// requestChangesDialogState is the state of the request changes dialog, identifying the review
+// request the dialog was opened from.
+type requestChangesDialogState struct {
+	WikiDocID string `json:"wiki_doc_id"`
+	UpdateAt  int64  `json:"update_at"`
+	PostID    string `json:"post_id"`
+}
+
+// action handles the POST /reviews/actions/{action} endpoint, called by the buttons of the direct
+// message sent to reviewers. Approving records the approval right away, rejecting opens a dialog
+// asking for the changes to make.
+func (h *ReviewHandler) action(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request *model.PostActionIntegrationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "failed to decode PostActionIntegrationRequest", err)
+		return
+	}
+
+	if userID != request.UserId {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "interactive action's userID must be the same as the requester's userID", nil)
+		return
+	}
+
+	wikiDocID, _ := request.Context[app.ReviewContextWikiDocIDKey].(string)
+	if wikiDocID == "" {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "missing wikiDoc id in the action context", nil)
+		return
+	}
+
+	// Review requests sent before their version was recorded have none, and are outdated.
+	updateAtValue, _ := request.Context[app.ReviewContextUpdateAtKey].(string)
+	updateAt, _ := strconv.ParseInt(updateAtValue, 10, 64)
+
+	var response model.PostActionIntegrationResponse
+	switch mux.Vars(r)["action"] {
+	case app.ReviewActionApprove:
+		_, err = h.reviewService.Approve(wikiDocID, userID, updateAt, "")
+		if errors.Is(err, app.ErrNotFound) {
+			response.EphemeralText = "You were not asked to review this wiki doc."
+			break
+		} else if errors.Is(err, app.ErrConflict) {
+			response.EphemeralText = outdatedReviewText
+			break
+		} else if err != nil {
+			h.HandleError(w, err)
+			return
+		}
+
+		response.Update = h.reviewedPost(request.PostId, userID, wikiDocID, "You approved this wiki doc.")
+	case app.ReviewActionReject:
+		state, _ := json.Marshal(requestChangesDialogState{WikiDocID: wikiDocID, UpdateAt: updateAt, PostID: request.PostId})
+		if err = h.reviewService.OpenRequestChangesDialog(request.TriggerId, string(state)); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+
+	ReturnJSON(w, response, http.StatusOK)
+}
+
+// requestChangesFromDialog handles the submission of the request changes dialog.
+func (h *ReviewHandler) requestChangesFromDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request *model.SubmitDialogRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "failed to decode SubmitDialogRequest", err)
+		return
+	}
+
+	if userID != request.UserId {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "interactive dialog's userID must be the same as the requester's userID", nil)
+		return
+	}
+
+	if request.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var state requestChangesDialogState
+	if err = json.Unmarshal([]byte(request.State), &state); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "failed to decode the dialog state", err)
+		return
+	}
+
+	comment, _ := request.Submission[app.DialogFieldCommentKey].(string)
+
+	_, err = h.reviewService.RequestChanges(state.WikiDocID, userID, state.UpdateAt, comment)
+	if err != nil {
+		var msg string
+		if errors.Is(err, app.ErrMalformedReview) {
+			msg = "Please describe the changes to make."
+		} else if errors.Is(err, app.ErrNotFound) {
+			msg = "You were not asked to review this wiki doc."
+		} else if errors.Is(err, app.ErrConflict) {
+			msg = outdatedReviewText
+		}
+
+		if msg != "" {
+			ReturnJSON(w, &model.SubmitDialogResponse{
+				Errors: map[string]string{
+					app.DialogFieldCommentKey: msg,
+				},
+			}, http.StatusOK)
+			return
+		}
+
+		h.HandleError(w, err)
+		return
+	}
+
+	if post := h.reviewedPost(state.PostID, userID, state.WikiDocID, "You requested changes to this wiki doc."); post != nil {
+		if err = h.pluginAPI.Post.UpdatePost(post); err != nil {
+			h.log.Warnf("failed to update review request post %s: %v", state.PostID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// reviewedPost returns the review request post with its buttons replaced by the decision of the
+// reviewer, or nil if the post cannot be found. The post ID comes from the client, so only the
+// request the bot sent the reviewer for the wikiDoc is returned.
+func (h *ReviewHandler) reviewedPost(postID, userID, wikiDocID, decision string) *model.Post {
+	if postID == "" {
+		return nil
+	}
+
+	post, err := h.pluginAPI.Post.GetPost(postID)
+	if err != nil {
+		h.log.Warnf("failed to get review request post %s: %v", postID, err)
+		return nil
+	}
+	if !h.isReviewRequest(post, userID, wikiDocID) {
+		h.log.Warnf("post %s is not the review request of wikiDoc %s sent to user %s", postID, wikiDocID, userID)
+		return nil
+	}
+
+	var title string
+	if attachments := post.Attachments(); len(attachments) > 0 {
+		title = attachments[0].Title
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Title: title,
+		Text:  decision,
+	}})
+
+	return post
+}
+
+// isReviewRequest returns true if the post is a review request of the wikiDoc, sent by the bot to
+// the user.
+func (h *ReviewHandler) isReviewRequest(post *model.Post, userID, wikiDocID string) bool {
+	if post.UserId != h.botUserID {
+		return false
+	}
+
+	channel, err := h.pluginAPI.Channel.GetDirect(userID, h.botUserID)
+	if err != nil || channel.Id != post.ChannelId {
+		return false
+	}
+
+	for _, attachment := range post.Attachments() {
+		for _, action := range attachment.Actions {
+			if action.Integration != nil && action.Integration.Context[app.ReviewContextWikiDocIDKey] == wikiDocID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
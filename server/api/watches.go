@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// WatchHandler is the API handler for the watches of wikiDocs.
+type WatchHandler struct {
+	*ErrorHandler
+	watchService   app.WatchService
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	log            bot.Logger
+}
+
+// NewWatchHandler Creates a new watch API handler.
+func NewWatchHandler(
+	router *mux.Router,
+	watchService app.WatchService,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *WatchHandler {
+	handler := &WatchHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		watchService:   watchService,
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		log:            log,
+	}
+
+	wikiDocRouter := router.PathPrefix("/wikiDocs/{id:[A-Za-z0-9]+}/watch").Subrouter()
+	wikiDocRouter.HandleFunc("", handler.watchWikiDoc).Methods(http.MethodPut)
+	wikiDocRouter.HandleFunc("", handler.unwatchWikiDoc).Methods(http.MethodDelete)
+
+	channelRouter := router.PathPrefix("/channels/{channel_id:[A-Za-z0-9]+}").Subrouter()
+	channelRouter.HandleFunc("/watches", handler.getWatches).Methods(http.MethodGet)
+	channelRouter.HandleFunc("/watch", handler.watchChannel).Methods(http.MethodPut)
+	channelRouter.HandleFunc("/watch", handler.unwatchChannel).Methods(http.MethodDelete)
+
+	return handler
+}
+
+// getWatches handles the GET /channels/{channel_id}/watches endpoint, listing the watches of the
+// user in the channel. The user must be able to list the wikiDocs of the channel.
+func (h *WatchHandler) getWatches(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	watches, err := h.watchService.GetWatches(userID, channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, watches, http.StatusOK)
+}
+
+// watchRequest is the body of the PUT /wikiDocs/{id}/watch endpoint.
+type watchRequest struct {
+	// Scope is app.WatchScopeWikiDoc, the default, or app.WatchScopeTree to also watch the
+	// descendants of the wikiDoc.
+	Scope app.WatchScope `json:"scope"`
+}
+
+// watchWikiDoc handles the PUT /wikiDocs/{id}/watch endpoint, subscribing the user to the changes of
+// the wikiDoc. The user must be able to read it.
+func (h *WatchHandler) watchWikiDoc(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	err := h.permissions.WikiDocView(userID, wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	}
+	if !h.PermissionsCheck(w, err) {
+		return
+	}
+
+	request := watchRequest{Scope: app.WatchScopeWikiDoc}
+	if r.ContentLength != 0 {
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode watch", err)
+			return
+		}
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	watch, err := h.watchService.Watch(userID, wikiDoc.ChannelID, wikiDoc.ID, request.Scope)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to watch wikiDoc", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, watch, http.StatusOK)
+}
+
+// unwatchWikiDoc handles the DELETE /wikiDocs/{id}/watch endpoint.
+func (h *WatchHandler) unwatchWikiDoc(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	} else if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err = h.watchService.Unwatch(userID, wikiDoc.ChannelID, wikiDoc.ID); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// watchChannel handles the PUT /channels/{channel_id}/watch endpoint, subscribing the user to the
+// changes of every wikiDoc of the channel. The user must be able to list them.
+func (h *WatchHandler) watchChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	watch, err := h.watchService.Watch(userID, channelID, "", app.WatchScopeChannel)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, watch, http.StatusOK)
+}
+
+// unwatchChannel handles the DELETE /channels/{channel_id}/watch endpoint.
+func (h *WatchHandler) unwatchChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if err := h.watchService.Unwatch(userID, channelID, ""); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
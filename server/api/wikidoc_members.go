@@ -0,0 +1,349 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+)
+
+// WikiDocMemberHandler is the API handler for the members of wikiDocs.
+type WikiDocMemberHandler struct {
+	*ErrorHandler
+	memberService  app.WikiDocMemberService
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+	pluginAPI      *pluginapi.Client
+	log            bot.Logger
+}
+
+// NewWikiDocMemberHandler Creates a new wikiDoc member API handler.
+func NewWikiDocMemberHandler(
+	router *mux.Router,
+	memberService app.WikiDocMemberService,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+	api *pluginapi.Client,
+	log bot.Logger,
+) *WikiDocMemberHandler {
+	handler := &WikiDocMemberHandler{
+		ErrorHandler:   &ErrorHandler{log: log},
+		memberService:  memberService,
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+		pluginAPI:      api,
+		log:            log,
+	}
+
+	wikiDocRouter := router.PathPrefix("/wikiDocs/{id:[A-Za-z0-9]+}").Subrouter()
+	wikiDocRouter.HandleFunc("/members", handler.getMembers).Methods(http.MethodGet)
+	wikiDocRouter.HandleFunc("/members/{user_id:[A-Za-z0-9]+}", handler.grant).Methods(http.MethodPut)
+	wikiDocRouter.HandleFunc("/members/{user_id:[A-Za-z0-9]+}", handler.revoke).Methods(http.MethodDelete)
+	wikiDocRouter.HandleFunc("/restricted", handler.setRestricted).Methods(http.MethodPut)
+	wikiDocRouter.HandleFunc("/groups", handler.getWikiDocGroups).Methods(http.MethodGet)
+	wikiDocRouter.HandleFunc("/groups/{group_id:[A-Za-z0-9]+}", handler.grantWikiDocGroup).Methods(http.MethodPut)
+	wikiDocRouter.HandleFunc("/groups/{group_id:[A-Za-z0-9]+}", handler.revokeWikiDocGroup).Methods(http.MethodDelete)
+
+	channelRouter := router.PathPrefix("/channels/{channel_id:[A-Za-z0-9]+}/groups").Subrouter()
+	channelRouter.HandleFunc("", handler.getChannelGroups).Methods(http.MethodGet)
+	channelRouter.HandleFunc("/{group_id:[A-Za-z0-9]+}", handler.grantChannelGroup).Methods(http.MethodPut)
+	channelRouter.HandleFunc("/{group_id:[A-Za-z0-9]+}", handler.revokeChannelGroup).Methods(http.MethodDelete)
+
+	return handler
+}
+
+// getMembers handles the GET /wikiDocs/{id}/members endpoint, user has view permissions.
+func (h *WikiDocMemberHandler) getMembers(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	err := h.permissions.WikiDocView(userID, wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	}
+	if !h.PermissionsCheck(w, err) {
+		return
+	}
+
+	members, err := h.memberService.GetMembers(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, members, http.StatusOK)
+}
+
+// grantRequest is the body of the PUT /wikiDocs/{id}/members/{user_id} endpoint.
+type grantRequest struct {
+	Role app.WikiDocRole `json:"role"`
+}
+
+// grant handles the PUT /wikiDocs/{id}/members/{user_id} endpoint, giving a role on the wikiDoc to
+// the user. The requester must be able to manage the members of the wikiDoc.
+func (h *WikiDocMemberHandler) grant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, ok := h.getManagedWikiDoc(w, vars["id"], userID)
+	if !ok {
+		return
+	}
+
+	var request grantRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode role", err)
+		return
+	}
+
+	if _, err := h.pluginAPI.User.Get(vars["user_id"]); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, fmt.Sprintf("user %s does not exist", vars["user_id"]), err)
+		return
+	}
+
+	member, err := h.memberService.Grant(wikiDoc.ID, vars["user_id"], request.Role)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to grant role", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, member, http.StatusOK)
+}
+
+// revoke handles the DELETE /wikiDocs/{id}/members/{user_id} endpoint, removing the role of the user
+// on the wikiDoc. The requester must be able to manage the members of the wikiDoc.
+func (h *WikiDocMemberHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, ok := h.getManagedWikiDoc(w, vars["id"], userID)
+	if !ok {
+		return
+	}
+
+	if err := h.memberService.Revoke(wikiDoc.ID, vars["user_id"]); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setRestrictedRequest is the body of the PUT /wikiDocs/{id}/restricted endpoint.
+type setRestrictedRequest struct {
+	Restricted bool `json:"restricted"`
+}
+
+// setRestricted handles the PUT /wikiDocs/{id}/restricted endpoint, restricting the wikiDoc to its
+// members or opening it to its channel again. The requester must be able to manage the members of
+// the wikiDoc, and becomes an owner of it when restricting it so that they keep access.
+func (h *WikiDocMemberHandler) setRestricted(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, ok := h.getManagedWikiDoc(w, mux.Vars(r)["id"], userID)
+	if !ok {
+		return
+	}
+
+	var request setRestrictedRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode restriction", err)
+		return
+	}
+
+	if request.Restricted && !app.IsSystemAdmin(userID, h.pluginAPI) {
+		role, err := h.memberService.GetRole(wikiDoc.ID, userID)
+		if err != nil {
+			h.HandleError(w, err)
+			return
+		}
+		if !role.Allows(app.RoleOwner) {
+			if _, err = h.memberService.Grant(wikiDoc.ID, userID, app.RoleOwner); err != nil {
+				h.HandleError(w, err)
+				return
+			}
+		}
+	}
+
+	updatedWikiDoc, err := h.wikiDocService.SetRestricted(wikiDoc, request.Restricted, userID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	setETag(w, updatedWikiDoc)
+	ReturnJSON(w, updatedWikiDoc, http.StatusOK)
+}
+
+// getWikiDocGroups handles the GET /wikiDocs/{id}/groups endpoint, user has view permissions.
+func (h *WikiDocMemberHandler) getWikiDocGroups(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	err := h.permissions.WikiDocView(userID, wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return
+	}
+	if !h.PermissionsCheck(w, err) {
+		return
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	grants, err := h.memberService.GetGroupGrants(wikiDoc.ChannelID, wikiDoc.ID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, grants, http.StatusOK)
+}
+
+// grantWikiDocGroup handles the PUT /wikiDocs/{id}/groups/{group_id} endpoint, giving a role on the
+// wikiDoc to the members of the group. The requester must be able to manage the members of the
+// wikiDoc.
+func (h *WikiDocMemberHandler) grantWikiDocGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, ok := h.getManagedWikiDoc(w, vars["id"], userID)
+	if !ok {
+		return
+	}
+
+	h.grantGroup(w, r, wikiDoc.ChannelID, wikiDoc.ID, vars["group_id"])
+}
+
+// revokeWikiDocGroup handles the DELETE /wikiDocs/{id}/groups/{group_id} endpoint, removing the role
+// of the group on the wikiDoc. The requester must be able to manage the members of the wikiDoc.
+func (h *WikiDocMemberHandler) revokeWikiDocGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, ok := h.getManagedWikiDoc(w, vars["id"], userID)
+	if !ok {
+		return
+	}
+
+	if err := h.memberService.RevokeGroup(wikiDoc.ChannelID, wikiDoc.ID, vars["group_id"]); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getChannelGroups handles the GET /channels/{channel_id}/groups endpoint, listing the roles granted
+// to groups on the channel wiki. The user must be able to list the wikiDocs of the channel.
+func (h *WikiDocMemberHandler) getChannelGroups(w http.ResponseWriter, r *http.Request) {
+	channelID := mux.Vars(r)["channel_id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	grants, err := h.memberService.GetGroupGrants(channelID, "")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, grants, http.StatusOK)
+}
+
+// grantChannelGroup handles the PUT /channels/{channel_id}/groups/{group_id} endpoint, giving a role
+// on every wikiDoc of the channel that is not restricted to the members of the group. The requester
+// must be able to manage the members of the channel wiki.
+func (h *WikiDocMemberHandler) grantChannelGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocManageMembers(userID, app.WikiDoc{ChannelID: vars["channel_id"]})) {
+		return
+	}
+
+	h.grantGroup(w, r, vars["channel_id"], "", vars["group_id"])
+}
+
+// revokeChannelGroup handles the DELETE /channels/{channel_id}/groups/{group_id} endpoint, removing
+// the role of the group on the channel wiki. The requester must be able to manage the members of the
+// channel wiki.
+func (h *WikiDocMemberHandler) revokeChannelGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocManageMembers(userID, app.WikiDoc{ChannelID: vars["channel_id"]})) {
+		return
+	}
+
+	if err := h.memberService.RevokeGroup(vars["channel_id"], "", vars["group_id"]); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// grantGroup decodes the role of a grant request and gives it to the members of the group, on the
+// wikiDoc or on the channel wiki when wikiDocID is empty.
+func (h *WikiDocMemberHandler) grantGroup(w http.ResponseWriter, r *http.Request, channelID, wikiDocID, groupID string) {
+	var request grantRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode role", err)
+		return
+	}
+
+	if _, err := h.pluginAPI.Group.Get(groupID); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, fmt.Sprintf("group %s does not exist", groupID), err)
+		return
+	}
+
+	grant, err := h.memberService.GrantGroup(channelID, wikiDocID, groupID, request.Role)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to grant role", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, grant, http.StatusOK)
+}
+
+// getManagedWikiDoc returns the wikiDoc if the user can manage its members. Otherwise, it writes the
+// error response and returns false.
+func (h *WikiDocMemberHandler) getManagedWikiDoc(w http.ResponseWriter, wikiDocID, userID string) (app.WikiDoc, bool) {
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if errors.Is(err, app.ErrNotFound) {
+		h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+		return app.WikiDoc{}, false
+	} else if err != nil {
+		h.HandleError(w, err)
+		return app.WikiDoc{}, false
+	}
+
+	if !h.PermissionsCheck(w, h.permissions.WikiDocManageMembers(userID, wikiDoc)) {
+		return app.WikiDoc{}, false
+	}
+
+	return wikiDoc, true
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/pkg/errors"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -17,6 +18,9 @@ import (
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
 )
 
+// approvalsRequiredMessage is the error shown when publishing a wikiDoc without enough approvals.
+const approvalsRequiredMessage = "The wikiDoc must be approved by more reviewers before it can be published."
+
 // WikiDocHandler is the API handler.
 type WikiDocHandler struct {
 	*ErrorHandler
@@ -44,11 +48,24 @@ func NewWikiDocHandler(
 
 	wikiDocsRouter := router.PathPrefix("/wikiDocs").Subrouter()
 	wikiDocsRouter.HandleFunc("", handler.getWikiDocs).Methods(http.MethodGet)
+	wikiDocsRouter.HandleFunc("/tree", handler.getTree).Methods(http.MethodGet)
+	wikiDocsRouter.HandleFunc("/trash", handler.getTrash).Methods(http.MethodGet)
 
 	wikiDocsRouter.HandleFunc("/dialog", handler.createWikiDocFromDialog).Methods(http.MethodPost)
 
 	wikiDocRouter := wikiDocsRouter.PathPrefix("/{id:[A-Za-z0-9]+}").Subrouter()
-	wikiDocRouter.HandleFunc("", handler.getWikiDoc).Methods(http.MethodGet)
+
+	wikiDocRouterViewable := wikiDocRouter.PathPrefix("").Subrouter()
+	wikiDocRouterViewable.Use(checkViewPermissions(handler.ErrorHandler, permissions))
+	wikiDocRouterViewable.HandleFunc("", handler.getWikiDoc).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/revisions", handler.getRevisions).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/revisions/{rev:[0-9]+}", handler.getRevision).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/diff", handler.diff).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/status/history", handler.getStatusChanges).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/links", handler.getLinks).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/backlinks", handler.getBacklinks).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/render", handler.render).Methods(http.MethodGet)
+	wikiDocRouterViewable.HandleFunc("/duplicate", handler.duplicate).Methods(http.MethodPost)
 
 	wikiDocRouterAuthorized := wikiDocRouter.PathPrefix("").Subrouter()
 	wikiDocRouterAuthorized.Use(handler.checkEditPermissions)
@@ -56,6 +73,10 @@ func NewWikiDocHandler(
 	wikiDocRouterAuthorized.HandleFunc("/content", handler.content).Methods(http.MethodPost)
 	wikiDocRouterAuthorized.HandleFunc("/status", handler.status).Methods(http.MethodPost)
 	wikiDocRouterAuthorized.HandleFunc("", handler.deleteWikiDoc).Methods(http.MethodDelete)
+	wikiDocRouterAuthorized.HandleFunc("/revisions/{rev:[0-9]+}/restore", handler.restoreRevision).Methods(http.MethodPost)
+	wikiDocRouterAuthorized.HandleFunc("/move", handler.move).Methods(http.MethodPost)
+	wikiDocRouterAuthorized.HandleFunc("/restore", handler.restore).Methods(http.MethodPost)
+	wikiDocRouterAuthorized.HandleFunc("/purge", handler.purge).Methods(http.MethodPost)
 
 	//channelRouter := wikiDocsRouter.PathPrefix("/channel").Subrouter()
 	//channelRouter.HandleFunc("/{channel_id:[A-Za-z0-9]+}", handler.getWikiDocByChannel).Methods(http.MethodGet)
@@ -63,6 +84,29 @@ func NewWikiDocHandler(
 	return handler
 }
 
+// checkViewPermissions returns the middleware of the routes of a wikiDoc, given by the {id} of their
+// path, that the user must be able to read.
+func checkViewPermissions(h *ErrorHandler, permissions *app.PermissionsService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			userID := r.Header.Get("Mattermost-User-ID")
+
+			err := permissions.WikiDocView(userID, vars["id"])
+			if errors.Is(err, app.ErrNotFound) {
+				h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+				return
+			}
+
+			if !h.PermissionsCheck(w, err) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (h *WikiDocHandler) checkEditPermissions(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -105,6 +149,10 @@ func (h *WikiDocHandler) updateWikiDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.checkIfMatch(w, r, oldWikiDoc) {
+		return
+	}
+
 	if wikiDoc.Name != "" {
 		oldWikiDoc.Name = wikiDoc.Name
 	}
@@ -118,40 +166,164 @@ func (h *WikiDocHandler) updateWikiDoc(w http.ResponseWriter, r *http.Request) {
 		oldWikiDoc.Status = wikiDoc.Status
 	}
 
-	err = h.wikiDocService.Update(oldWikiDoc)
+	updatedWikiDoc, err := h.wikiDocService.Update(oldWikiDoc, userID)
 	if err != nil {
+		if errors.Is(err, app.ErrConflict) {
+			h.handleConflict(w, wikiDocID, err)
+			return
+		}
+		if errors.Is(err, app.ErrInvalidTransition) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "invalid status transition", err)
+			return
+		}
+		if errors.Is(err, app.ErrApprovalsRequired) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, approvalsRequiredMessage, err)
+			return
+		}
 		h.HandleError(w, err)
 		return
 	}
 
-	updatedWikiDoc := oldWikiDoc
-
+	setETag(w, updatedWikiDoc)
 	ReturnJSON(w, updatedWikiDoc, http.StatusOK)
 }
 
-// Note that this currently does nothing. This is temporary given the removal of stages. Will be used by status.
+// deleteWikiDoc handles the DELETE /wikiDocs/{id} endpoint. When the wikiDoc has children, the
+// children=cascade|reparent parameter says whether they are deleted too or moved to its parent.
 func (h *WikiDocHandler) deleteWikiDoc(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	wikiDocID := vars["id"]
 	userID := r.Header.Get("Mattermost-User-ID")
 
+	options := app.DeleteOptions{
+		Children: app.ChildrenPolicy(r.URL.Query().Get("children")),
+	}
+	if !app.ValidChildrenPolicy(options.Children) {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'children': must be cascade or reparent", nil)
+		return
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if !h.PermissionsCheck(w, h.permissions.DeleteWikiDocWithOptions(userID, wikiDoc, options)) {
+		return
+	}
+
+	if !h.checkIfMatch(w, r, wikiDoc) {
+		return
+	}
+
+	err = h.wikiDocService.Delete(wikiDocID, userID, options)
+	if err != nil {
+		if errors.Is(err, app.ErrWikiDocHasChildren) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "The wikiDoc has children: set children=cascade to delete them or children=reparent to keep them.", err)
+			return
+		}
+		if errors.Is(err, app.ErrWikiDocInTrash) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "The wikiDoc is already in the trash.", err)
+			return
+		}
+		if errors.Is(err, app.ErrConflict) {
+			h.HandleErrorWithCode(w, http.StatusConflict, "The wikiDoc or one of its children was modified by someone else. Try again.", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, "Moved the wikiDoc to the trash", http.StatusOK)
+}
+
+// getTrash handles the GET /wikiDocs/trash endpoint, listing the deleted wikiDocs of a channel.
+// The user must be able to edit the wikiDocs of the channel, and only sees the ones they can edit.
+func (h *WikiDocHandler) getTrash(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "missing parameter 'channel_id'", nil)
+		return
+	}
+
+	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, app.WikiDoc{ChannelID: channelID})) {
+		return
+	}
+
+	trash, err := h.wikiDocService.GetTrash(channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	trash, err = h.permissions.FilterTrash(userID, channelID, trash)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, trash, http.StatusOK)
+}
+
+// restore handles the POST /wikiDocs/{id}/restore endpoint, taking a wikiDoc out of the trash. The
+// response carries the ETag of the restored version.
+func (h *WikiDocHandler) restore(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
 	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
 	if err != nil {
 		h.HandleError(w, err)
 		return
 	}
 
-	if !h.PermissionsCheck(w, h.permissions.HasEditPermissionsToWikiDocs(userID, wikiDoc)) {
+	if !h.checkIfMatch(w, r, wikiDoc) {
 		return
 	}
 
-	err = h.wikiDocService.Delete(wikiDocID)
+	restoredWikiDoc, err := h.wikiDocService.Restore(wikiDocID, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrWikiDocNotInTrash) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "The wikiDoc is not in the trash.", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	setETag(w, restoredWikiDoc)
+	ReturnJSON(w, restoredWikiDoc, http.StatusOK)
+}
+
+// purge handles the POST /wikiDocs/{id}/purge endpoint, permanently deleting a wikiDoc in the trash.
+// The files of its attachments stay in the storage of the server, see app.WikiDocService.Purge.
+func (h *WikiDocHandler) purge(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
 	if err != nil {
 		h.HandleError(w, err)
 		return
 	}
 
-	ReturnJSON(w, "Deleted the wikiDoc", http.StatusOK)
+	if !h.checkIfMatch(w, r, wikiDoc) {
+		return
+	}
+
+	if err = h.wikiDocService.Purge(wikiDocID, userID); err != nil {
+		if errors.Is(err, app.ErrWikiDocNotInTrash) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "Only wikiDocs in the trash can be purged.", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, "Purged the wikiDoc", http.StatusOK)
 }
 
 // createWikiDocFromDialog handles the interactive dialog submission when a user presses confirm on
@@ -201,7 +373,7 @@ func (h *WikiDocHandler) createWikiDocFromDialog(w http.ResponseWriter, r *http.
 		request.UserId,
 	)
 	if err != nil {
-		if errors.Is(err, app.ErrMalformedWikiDoc) {
+		if errors.Is(err, app.ErrMalformedWikiDoc) || errors.Is(err, app.ErrInvalidTransition) || errors.Is(err, app.ErrApprovalsRequired) {
 			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to create wikiDoc", err)
 			return
 		}
@@ -299,7 +471,7 @@ func (h *WikiDocHandler) createWikiDoc(wikiDoc app.WikiDoc, userID string) (stri
 }
 
 func (h *WikiDocHandler) getRequesterInfo(userID string) (app.RequesterInfo, error) {
-	return app.GetRequesterInfo(userID, h.pluginAPI)
+	return h.permissions.GetRequesterInfo(userID)
 }
 
 // getWikiDocs handles the GET /runs endpoint.
@@ -338,6 +510,7 @@ func (h *WikiDocHandler) getWikiDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, wikiDocRunToGet)
 	ReturnJSON(w, wikiDocRunToGet, http.StatusOK)
 }
 
@@ -360,6 +533,10 @@ func (h *WikiDocHandler) content(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.checkIfMatch(w, r, wikiDocToModify) {
+		return
+	}
+
 	var options map[string]string
 
 	if err = json.NewDecoder(r.Body).Decode(&options); err != nil {
@@ -369,12 +546,17 @@ func (h *WikiDocHandler) content(w http.ResponseWriter, r *http.Request) {
 
 	wikiDocToModify.Content = options["content"]
 
-	err = h.wikiDocService.Update(wikiDocToModify)
+	updatedWikiDoc, err := h.wikiDocService.Update(wikiDocToModify, userID)
 	if err != nil {
+		if errors.Is(err, app.ErrConflict) {
+			h.handleConflict(w, wikiDocID, err)
+			return
+		}
 		h.HandleError(w, err)
 		return
 	}
 
+	setETag(w, updatedWikiDoc)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status":"OK"}`))
 }
@@ -398,6 +580,10 @@ func (h *WikiDocHandler) status(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.checkIfMatch(w, r, wikiDocToModify) {
+		return
+	}
+
 	var options map[string]string
 
 	if err = json.NewDecoder(r.Body).Decode(&options); err != nil {
@@ -405,22 +591,416 @@ func (h *WikiDocHandler) status(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !app.ValidStatus(options["status"]) {
-		h.HandleErrorWithCode(w, http.StatusBadRequest, "invalid status provided", err)
+	if options["status"] == "" || !app.ValidStatus(options["status"]) {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "invalid status provided", nil)
+		return
 	}
 
+	previousStatus := wikiDocToModify.Status
 	wikiDocToModify.Status = options["status"]
 
-	err = h.wikiDocService.Update(wikiDocToModify)
+	updatedWikiDoc, err := h.wikiDocService.Update(wikiDocToModify, userID)
 	if err != nil {
+		if errors.Is(err, app.ErrConflict) {
+			h.handleConflict(w, wikiDocID, err)
+			return
+		}
+		if errors.Is(err, app.ErrInvalidTransition) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, fmt.Sprintf("A %s wikiDoc can only move to: %s.",
+				app.NormalizeStatus(previousStatus), strings.Join(app.AllowedTransitions(previousStatus), ", ")), err)
+			return
+		}
+		if errors.Is(err, app.ErrApprovalsRequired) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, approvalsRequiredMessage, err)
+			return
+		}
 		h.HandleError(w, err)
 		return
 	}
 
+	setETag(w, updatedWikiDoc)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status":"OK"}`))
 }
 
+// getStatusChanges handles the GET /wikiDocs/{id}/status/history endpoint, listing the status
+// transitions of a wikiDoc, newest first.
+func (h *WikiDocHandler) getStatusChanges(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+
+	changes, err := h.wikiDocService.GetStatusChanges(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, changes, http.StatusOK)
+}
+
+// getLinks handles the GET /wikiDocs/{id}/links endpoint, listing the [[links]] of a wikiDoc. The
+// targets the user cannot read are reported as missing.
+func (h *WikiDocHandler) getLinks(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	links, err := h.wikiDocService.GetLinks(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	for i := range links {
+		if links[i].Missing {
+			continue
+		}
+		if err = h.permissions.WikiDocView(userID, links[i].TargetID); err != nil {
+			links[i].TargetID = ""
+			links[i].Missing = true
+		}
+	}
+
+	ReturnJSON(w, links, http.StatusOK)
+}
+
+// render handles the GET /wikiDocs/{id}/render endpoint, returning the content of a wikiDoc as
+// sanitized HTML, with its table of contents and outline. The [[links]] to wikiDocs the user cannot
+// read are rendered as missing.
+func (h *WikiDocHandler) render(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	rendered, err := h.wikiDocService.Render(wikiDoc, func(targetID string) bool {
+		return h.permissions.WikiDocView(userID, targetID) == nil
+	})
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, rendered, http.StatusOK)
+}
+
+// getBacklinks handles the GET /wikiDocs/{id}/backlinks endpoint, listing the wikiDocs the user can
+// read that link to a wikiDoc.
+func (h *WikiDocHandler) getBacklinks(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	sources, err := h.wikiDocService.GetBacklinks(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	visible := make([]app.WikiDoc, 0, len(sources))
+	for _, source := range sources {
+		if h.permissions.WikiDocView(userID, source.ID) == nil {
+			visible = append(visible, source)
+		}
+	}
+
+	ReturnJSON(w, visible, http.StatusOK)
+}
+
+// getTree handles the GET /wikiDocs/tree endpoint.
+func (h *WikiDocHandler) getTree(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channel_id")
+	if !model.IsValidId(channelID) {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'channel_id': must be 26 characters", nil)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if !h.PermissionsCheck(w, h.permissions.WikiDocList(userID, channelID)) {
+		return
+	}
+
+	tree, err := h.wikiDocService.GetTree(channelID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	tree, err = h.permissions.FilterWikiDocTree(userID, channelID, tree)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, tree, http.StatusOK)
+}
+
+// moveWikiDocRequest is the body of the POST /wikiDocs/{id}/move endpoint.
+type moveWikiDocRequest struct {
+	// ParentID is the new parent, empty to make the wikiDoc a top-level doc.
+	ParentID string `json:"parent_id"`
+
+	// Position is the index among the new siblings; the wikiDoc is appended when omitted.
+	Position *int `json:"position"`
+}
+
+// move handles the POST /wikiDocs/{id}/move endpoint, user has edit permissions
+func (h *WikiDocHandler) move(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request moveWikiDocRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode move request", err)
+		return
+	}
+
+	position := -1
+	if request.Position != nil {
+		position = *request.Position
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if !h.checkIfMatch(w, r, wikiDoc) {
+		return
+	}
+
+	movedWikiDoc, err := h.wikiDocService.Move(wikiDoc, request.ParentID, position, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrWikiDocCycle) || errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to move wikiDoc", err)
+			return
+		}
+		if errors.Is(err, app.ErrConflict) {
+			h.handleConflict(w, wikiDoc.ID, err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	setETag(w, movedWikiDoc)
+	ReturnJSON(w, movedWikiDoc, http.StatusOK)
+}
+
+// duplicateWikiDocRequest is the body of the POST /wikiDocs/{id}/duplicate endpoint.
+type duplicateWikiDocRequest struct {
+	// ChannelID is the channel to copy the wikiDoc to, defaulting to its own channel.
+	ChannelID string `json:"channel_id"`
+
+	// TeamID is the team of ChannelID, optional.
+	TeamID string `json:"team_id"`
+
+	// Name is the name of the copy, optional.
+	Name string `json:"name"`
+}
+
+// duplicate handles the POST /wikiDocs/{id}/duplicate endpoint, user has view permissions. The user
+// must also be able to create wikiDocs in the target channel.
+func (h *WikiDocHandler) duplicate(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request duplicateWikiDocRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to decode duplicate request", err)
+		return
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(wikiDocID)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	channelID := request.ChannelID
+	if channelID == "" {
+		channelID = wikiDoc.ChannelID
+	}
+	if err = h.permissions.WikiDocCreate(app.WikiDoc{OwnerUserID: userID, ChannelID: channelID}); err != nil {
+		h.HandleErrorWithCode(w, http.StatusForbidden, "Not authorized to create wikiDocs in the target channel", err)
+		return
+	}
+
+	newID, err := h.wikiDocService.Duplicate(wikiDoc, app.DuplicateOptions{
+		ChannelID: channelID,
+		TeamID:    request.TeamID,
+		Name:      request.Name,
+	}, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrMalformedWikiDoc) {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "unable to duplicate wikiDoc", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	w.Header().Add("Location", fmt.Sprintf("/api/v0/wikiDocs/%s", newID))
+	ReturnJSON(w, map[string]string{"id": newID}, http.StatusCreated)
+}
+
+// getRevisions handles the GET /wikiDocs/{id}/revisions endpoint.
+func (h *WikiDocHandler) getRevisions(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+
+	if _, err := h.wikiDocService.Get(wikiDocID); err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc not found", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	revisions, err := h.wikiDocService.GetRevisions(wikiDocID)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, revisions, http.StatusOK)
+}
+
+// getRevision handles the GET /wikiDocs/{id}/revisions/{rev} endpoint.
+func (h *WikiDocHandler) getRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	revision, err := strconv.ParseInt(vars["rev"], 10, 64)
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'rev'", err)
+		return
+	}
+
+	rev, err := h.wikiDocService.GetRevision(vars["id"], revision)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusNotFound, "revision not found", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, rev, http.StatusOK)
+}
+
+// restoreRevision handles the POST /wikiDocs/{id}/revisions/{rev}/restore endpoint, user has edit permissions
+func (h *WikiDocHandler) restoreRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	revision, err := strconv.ParseInt(vars["rev"], 10, 64)
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'rev'", err)
+		return
+	}
+
+	wikiDoc, err := h.wikiDocService.Get(vars["id"])
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if !h.checkIfMatch(w, r, wikiDoc) {
+		return
+	}
+
+	restoredWikiDoc, err := h.wikiDocService.RestoreRevision(wikiDoc, revision, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrConflict) {
+			h.handleConflict(w, wikiDoc.ID, err)
+			return
+		}
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusNotFound, "revision not found", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	setETag(w, restoredWikiDoc)
+	ReturnJSON(w, restoredWikiDoc, http.StatusOK)
+}
+
+// diff handles the GET /wikiDocs/{id}/diff endpoint.
+//
+// The from and to parameters are revision numbers or "current"; to defaults to the current doc.
+// Set words=true to include word-level changes inside modified lines.
+func (h *WikiDocHandler) diff(w http.ResponseWriter, r *http.Request) {
+	wikiDocID := mux.Vars(r)["id"]
+	query := r.URL.Query()
+
+	if query.Get("from") == "" {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "missing parameter 'from'", nil)
+		return
+	}
+
+	from, err := parseRevisionParam(query.Get("from"))
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'from'", err)
+		return
+	}
+
+	to, err := parseRevisionParam(query.Get("to"))
+	if err != nil {
+		h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'to'", err)
+		return
+	}
+
+	options := app.DiffOptions{
+		Context: -1,
+		Words:   query.Get("words") == "true",
+	}
+	if contextParam := query.Get("context"); contextParam != "" {
+		options.Context, err = strconv.Atoi(contextParam)
+		if err != nil || options.Context < 0 {
+			h.HandleErrorWithCode(w, http.StatusBadRequest, "bad parameter 'context'", err)
+			return
+		}
+	}
+
+	result, err := h.wikiDocService.Diff(wikiDocID, from, to, options)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			h.HandleErrorWithCode(w, http.StatusNotFound, "wikiDoc or revision not found", err)
+			return
+		}
+		h.HandleError(w, err)
+		return
+	}
+
+	ReturnJSON(w, result, http.StatusOK)
+}
+
+// parseRevisionParam parses a revision number, mapping "current" and blank to 0.
+func parseRevisionParam(param string) (int64, error) {
+	if param == "" || param == "current" {
+		return 0, nil
+	}
+
+	revision, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if revision <= 0 {
+		return 0, errors.New("revision must be positive")
+	}
+
+	return revision, nil
+}
+
 // parseWikiDocsFilterOptions is only for parsing. Put validation logic in app.validateOptions.
 func parseWikiDocsFilterOptions(u *url.URL, currentUserID string) (*app.WikiDocFilterOptions, error) {
 	teamId := u.Query().Get("team_id")
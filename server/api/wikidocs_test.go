@@ -0,0 +1,521 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+var (
+	channelID      = model.NewId()
+	otherChannelID = model.NewId()
+
+	memberID   = model.NewId()
+	outsiderID = model.NewId()
+	guestID    = model.NewId()
+	adminID    = model.NewId()
+
+	// managerID can manage the properties of channelID, without being a member of it.
+	managerID = model.NewId()
+
+	// readerID can read channelID, a public channel of their team, but is not a member of it.
+	readerID = model.NewId()
+
+	// groupMemberID is not a member of channelID, but of groupID which is granted its wiki.
+	groupMemberID = model.NewId()
+	groupID       = model.NewId()
+
+	publishedDoc = app.WikiDoc{ID: model.NewId(), Name: "Published", Status: app.StatusPublished, ChannelID: channelID}
+	privateDoc   = app.WikiDoc{ID: model.NewId(), Name: "Private", Status: app.StatusDraft, ChannelID: channelID}
+	childDoc     = app.WikiDoc{ID: model.NewId(), Name: "Child", Status: app.StatusPublished, ChannelID: channelID, ParentID: privateDoc.ID}
+
+	// restrictedDoc is only readable by its members: outsiderID and guestID are viewers.
+	restrictedDoc = app.WikiDoc{ID: model.NewId(), Name: "Restricted", Status: app.StatusDraft, ChannelID: channelID, Restricted: true}
+
+	trashedDoc           = app.WikiDoc{ID: model.NewId(), Name: "Trashed", Status: app.StatusPublished, ChannelID: channelID, DeleteAt: 1}
+	trashedRestrictedDoc = app.WikiDoc{ID: model.NewId(), Name: "Trashed restricted", Status: app.StatusDraft, ChannelID: channelID, DeleteAt: 1, Restricted: true}
+)
+
+// fakeWikiDocService serves fixed wikiDocs, and records the requester of list queries.
+type fakeWikiDocService struct {
+	app.WikiDocService
+
+	wikiDocs      map[string]app.WikiDoc
+	requesterInfo *app.RequesterInfo
+
+	// channelMembers are the rows of ChannelMembers for channelID, that the store joins when listing.
+	channelMembers map[string]bool
+}
+
+func (s *fakeWikiDocService) Get(id string) (app.WikiDoc, error) {
+	wikiDoc, ok := s.wikiDocs[id]
+	if !ok {
+		return app.WikiDoc{}, app.ErrNotFound
+	}
+	return wikiDoc, nil
+}
+
+// GetWikiDocs lists the wikiDocs of a channel as the store does for the users without roles: admins
+// list them all, and members of the channel those not restricted, only the public ones for guests.
+func (s *fakeWikiDocService) GetWikiDocs(requesterInfo app.RequesterInfo, options app.WikiDocFilterOptions) (*app.GetWikiDocsResults, error) {
+	s.requesterInfo = &requesterInfo
+
+	items := []app.WikiDoc{}
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ChannelID != options.ChannelId || wikiDoc.DeleteAt != 0 {
+			continue
+		}
+		if requesterInfo.IsAdmin ||
+			(!wikiDoc.Restricted && s.channelMembers[requesterInfo.UserID] && (!requesterInfo.IsGuest || app.IsPublicStatus(wikiDoc.Status))) {
+			items = append(items, wikiDoc)
+		}
+	}
+	return &app.GetWikiDocsResults{Items: items}, nil
+}
+
+func (s *fakeWikiDocService) GetTree(channelID string) ([]*app.WikiDocNode, error) {
+	var wikiDocs []app.WikiDoc
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ChannelID == channelID && wikiDoc.DeleteAt == 0 {
+			wikiDocs = append(wikiDocs, wikiDoc)
+		}
+	}
+	return app.BuildWikiDocTree(wikiDocs), nil
+}
+
+func (s *fakeWikiDocService) GetRevisions(wikiDocID string) ([]app.WikiDocRevision, error) {
+	return []app.WikiDocRevision{{WikiDocID: wikiDocID, Revision: 1}}, nil
+}
+
+func (s *fakeWikiDocService) GetRevision(wikiDocID string, revision int64) (app.WikiDocRevision, error) {
+	return app.WikiDocRevision{WikiDocID: wikiDocID, Revision: revision}, nil
+}
+
+func (s *fakeWikiDocService) Diff(wikiDocID string, from, to int64, options app.DiffOptions) (*app.WikiDocDiff, error) {
+	return &app.WikiDocDiff{}, nil
+}
+
+func (s *fakeWikiDocService) Delete(id string, userID string, options app.DeleteOptions) error {
+	return nil
+}
+
+func (s *fakeWikiDocService) GetTrash(channelID string) ([]app.WikiDoc, error) {
+	trash := []app.WikiDoc{}
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ChannelID == channelID && wikiDoc.DeleteAt != 0 {
+			trash = append(trash, wikiDoc)
+		}
+	}
+	return trash, nil
+}
+
+// GetLinks links a wikiDoc to every other wikiDoc not in the trash.
+func (s *fakeWikiDocService) GetLinks(wikiDocID string) ([]app.WikiDocLink, error) {
+	var links []app.WikiDocLink
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ID != wikiDocID && wikiDoc.DeleteAt == 0 {
+			links = append(links, app.WikiDocLink{SourceID: wikiDocID, Target: wikiDoc.Name, TargetID: wikiDoc.ID})
+		}
+	}
+	return links, nil
+}
+
+// GetBacklinks links every other wikiDoc not in the trash to a wikiDoc.
+func (s *fakeWikiDocService) GetBacklinks(wikiDocID string) ([]app.WikiDoc, error) {
+	var sources []app.WikiDoc
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ID != wikiDocID && wikiDoc.DeleteAt == 0 {
+			sources = append(sources, wikiDoc)
+		}
+	}
+	return sources, nil
+}
+
+// fakeMemberService serves fixed roles, by wikiDoc ID then user ID, and fixed channel wiki roles and
+// groups, by user ID.
+type fakeMemberService struct {
+	app.WikiDocMemberService
+
+	roles        map[string]map[string]app.WikiDocRole
+	channelRoles map[string]app.WikiDocRole
+	groups       map[string][]string
+}
+
+func (s *fakeMemberService) GetRole(wikiDocID, userID string) (app.WikiDocRole, error) {
+	return s.roles[wikiDocID][userID], nil
+}
+
+func (s *fakeMemberService) GetChannelRoles(userID, channelID string) (app.ChannelRoles, error) {
+	roles := app.ChannelRoles{Channel: s.channelRoles[userID], WikiDocs: map[string]app.WikiDocRole{}}
+	for wikiDocID, members := range s.roles {
+		if role, ok := members[userID]; ok {
+			roles.WikiDocs[wikiDocID] = role
+		}
+	}
+	return roles, nil
+}
+
+func (s *fakeMemberService) GetUserGroupIDs(userID string) ([]string, error) {
+	return s.groups[userID], nil
+}
+
+// setupRouter returns the wikiDoc routes, backed by a mocked plugin API where memberID and guestID
+// are members of channelID, readerID can read it without being a member, managerID can manage it,
+// and adminID is a system admin. Roles are granted on restrictedDoc and privateDoc, and on the wiki of channelID to
+// groupMemberID through their group.
+func setupRouter(t *testing.T) (*mux.Router, *fakeWikiDocService) {
+	t.Helper()
+
+	api := &plugintest.API{}
+	t.Cleanup(func() { api.AssertExpectations(t) })
+
+	users := map[string]string{
+		memberID:   model.SystemUserRoleId,
+		outsiderID: model.SystemUserRoleId,
+		guestID:    model.SystemGuestRoleId,
+		adminID:    model.SystemUserRoleId + " " + model.SystemAdminRoleId,
+
+		groupMemberID: model.SystemUserRoleId,
+		readerID:      model.SystemUserRoleId,
+		managerID:     model.SystemUserRoleId,
+	}
+	channelMembers := map[string]bool{memberID: true, guestID: true}
+	for userID, roles := range users {
+		api.On("GetUser", userID).Return(&model.User{Id: userID, Roles: roles}, nil).Maybe()
+		api.On("HasPermissionTo", userID, model.PermissionManageSystem).Return(userID == adminID).Maybe()
+
+		if channelMembers[userID] {
+			api.On("GetChannelMember", channelID, userID).Return(&model.ChannelMember{ChannelId: channelID, UserId: userID}, nil).Maybe()
+		} else {
+			api.On("GetChannelMember", channelID, userID).Return(nil, model.NewAppError("GetChannelMember", "not_found", nil, "", http.StatusNotFound)).Maybe()
+		}
+		api.On("GetChannelMember", otherChannelID, userID).Return(nil, model.NewAppError("GetChannelMember", "not_found", nil, "", http.StatusNotFound)).Maybe()
+		api.On("HasPermissionToChannel", userID, channelID, model.PermissionReadChannel).Return(channelMembers[userID] || userID == readerID).Maybe()
+		api.On("HasPermissionToChannel", userID, channelID, model.PermissionManagePublicChannelProperties).Return(userID == managerID).Maybe()
+	}
+	api.On("GetChannel", channelID).Return(&model.Channel{Id: channelID, Type: model.ChannelTypeOpen}, nil).Maybe()
+
+	service := &fakeWikiDocService{
+		wikiDocs: map[string]app.WikiDoc{
+			publishedDoc.ID:  publishedDoc,
+			privateDoc.ID:    privateDoc,
+			childDoc.ID:      childDoc,
+			restrictedDoc.ID: restrictedDoc,
+			trashedDoc.ID:    trashedDoc,
+
+			trashedRestrictedDoc.ID: trashedRestrictedDoc,
+		},
+		channelMembers: channelMembers,
+	}
+
+	members := &fakeMemberService{
+		roles: map[string]map[string]app.WikiDocRole{
+			restrictedDoc.ID: {outsiderID: app.RoleViewer, guestID: app.RoleViewer},
+			privateDoc.ID:    {memberID: app.RoleOwner},
+		},
+		channelRoles: map[string]app.WikiDocRole{groupMemberID: app.RoleViewer},
+		groups:       map[string][]string{groupMemberID: {groupID}},
+	}
+
+	pluginAPI := pluginapi.NewClient(api, nil)
+	router := mux.NewRouter()
+	NewWikiDocHandler(router, service, app.NewPermissionsService(service, members, pluginAPI), pluginAPI, &bot.NilLogger{})
+
+	return router, service
+}
+
+func serve(router *mux.Router, method, url, userID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	r.Header.Set("Mattermost-User-ID", userID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestGetWikiDocPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		wikiDoc  app.WikiDoc
+		expected int
+	}{
+		{"member reads published doc", memberID, publishedDoc, http.StatusOK},
+		{"member reads private doc", memberID, privateDoc, http.StatusOK},
+		{"outsider cannot read published doc", outsiderID, publishedDoc, http.StatusForbidden},
+		{"outsider cannot read private doc", outsiderID, privateDoc, http.StatusForbidden},
+		{"guest member reads published doc", guestID, publishedDoc, http.StatusOK},
+		{"guest member cannot read private doc", guestID, privateDoc, http.StatusForbidden},
+		{"admin reads doc of channel they are not in", adminID, privateDoc, http.StatusOK},
+		{"member cannot read restricted doc", memberID, restrictedDoc, http.StatusForbidden},
+		{"viewer reads restricted doc of channel they are not in", outsiderID, restrictedDoc, http.StatusOK},
+		{"guest viewer reads restricted draft", guestID, restrictedDoc, http.StatusOK},
+		{"admin reads restricted doc", adminID, restrictedDoc, http.StatusOK},
+		{"group member reads private doc of channel they are not in", groupMemberID, privateDoc, http.StatusOK},
+		{"group member cannot read restricted doc", groupMemberID, restrictedDoc, http.StatusForbidden},
+		{"reader of the channel cannot read published doc without being a member", readerID, publishedDoc, http.StatusForbidden},
+		{"member cannot read trashed doc", memberID, trashedDoc, http.StatusNotFound},
+		{"admin reads trashed doc", adminID, trashedDoc, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, _ := setupRouter(t)
+
+			for _, path := range []string{"", "/revisions", "/revisions/1", "/diff?from=1"} {
+				w := serve(router, http.MethodGet, "/wikiDocs/"+tc.wikiDoc.ID+path, tc.userID)
+				assert.Equal(t, tc.expected, w.Code, "GET /wikiDocs/{id}%s", path)
+			}
+		})
+	}
+
+	t.Run("missing doc", func(t *testing.T) {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs/"+model.NewId(), memberID)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("member without edit permissions cannot update", func(t *testing.T) {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodPatch, "/wikiDocs/"+publishedDoc.ID, memberID)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestDeleteWikiDocPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		children app.ChildrenPolicy
+		expected int
+	}{
+		{"owner deletes doc, reparenting its children", memberID, app.ChildrenReparent, http.StatusOK},
+		{"owner cannot delete the children they do not own", memberID, app.ChildrenCascade, http.StatusForbidden},
+		{"admin deletes doc and its children", adminID, app.ChildrenCascade, http.StatusOK},
+		{"guest cannot delete doc", guestID, app.ChildrenReparent, http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, _ := setupRouter(t)
+
+			w := serve(router, http.MethodDelete, "/wikiDocs/"+privateDoc.ID+"?children="+string(tc.children), tc.userID)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
+
+func TestGetTrashPermissions(t *testing.T) {
+	getTrash := func(t *testing.T, userID string) (int, []string) {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs/trash?channel_id="+channelID, userID)
+		var trash []app.WikiDoc
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&trash))
+		}
+		var names []string
+		for _, wikiDoc := range trash {
+			names = append(names, wikiDoc.Name)
+		}
+		return w.Code, names
+	}
+
+	t.Run("channel manager does not see restricted docs", func(t *testing.T) {
+		code, names := getTrash(t, managerID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Trashed"}, names)
+	})
+
+	t.Run("admin sees every doc", func(t *testing.T) {
+		code, names := getTrash(t, adminID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Trashed", "Trashed restricted"}, names)
+	})
+
+	t.Run("member cannot list the trash", func(t *testing.T) {
+		code, _ := getTrash(t, memberID)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+}
+
+func TestGetTreePermissions(t *testing.T) {
+	names := func(nodes []*app.WikiDocNode) []string {
+		var result []string
+		var walk func(nodes []*app.WikiDocNode)
+		walk = func(nodes []*app.WikiDocNode) {
+			for _, node := range nodes {
+				result = append(result, node.Name)
+				walk(node.Children)
+			}
+		}
+		walk(nodes)
+		return result
+	}
+
+	getTree := func(t *testing.T, userID, channelID string) (int, []*app.WikiDocNode) {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs/tree?channel_id="+channelID, userID)
+		var tree []*app.WikiDocNode
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&tree))
+		}
+		return w.Code, tree
+	}
+
+	t.Run("member sees every doc not restricted", func(t *testing.T) {
+		code, tree := getTree(t, memberID, channelID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Published", "Private", "Child"}, names(tree))
+	})
+
+	t.Run("guest sees published docs and docs they are a member of, children of hidden docs moving up", func(t *testing.T) {
+		code, tree := getTree(t, guestID, channelID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Published", "Child", "Restricted"}, names(tree))
+		assert.Len(t, tree, 3)
+	})
+
+	t.Run("group member sees every doc not restricted", func(t *testing.T) {
+		code, tree := getTree(t, groupMemberID, channelID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Published", "Private", "Child"}, names(tree))
+	})
+
+	t.Run("outsider cannot list", func(t *testing.T) {
+		code, _ := getTree(t, outsiderID, channelID)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("admin lists any channel", func(t *testing.T) {
+		code, _ := getTree(t, adminID, otherChannelID)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("admin sees restricted docs", func(t *testing.T) {
+		code, tree := getTree(t, adminID, channelID)
+		require.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, []string{"Published", "Private", "Child", "Restricted"}, names(tree))
+	})
+}
+
+func TestGetLinksPermissions(t *testing.T) {
+	getLinks := func(t *testing.T, userID string) (found, missing []string) {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs/"+publishedDoc.ID+"/links", userID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var links []app.WikiDocLink
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&links))
+		for _, link := range links {
+			if link.Missing {
+				assert.Empty(t, link.TargetID)
+				missing = append(missing, link.Target)
+			} else {
+				found = append(found, link.Target)
+			}
+		}
+		return found, missing
+	}
+
+	getBacklinks := func(t *testing.T, userID string) []string {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs/"+publishedDoc.ID+"/backlinks", userID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var sources []app.WikiDoc
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&sources))
+		var names []string
+		for _, source := range sources {
+			names = append(names, source.Name)
+		}
+		return names
+	}
+
+	t.Run("targets the member cannot read are missing", func(t *testing.T) {
+		found, missing := getLinks(t, memberID)
+		assert.ElementsMatch(t, []string{"Private", "Child"}, found)
+		assert.ElementsMatch(t, []string{"Restricted"}, missing)
+	})
+
+	t.Run("targets the guest cannot read are missing", func(t *testing.T) {
+		found, missing := getLinks(t, guestID)
+		assert.ElementsMatch(t, []string{"Child", "Restricted"}, found)
+		assert.ElementsMatch(t, []string{"Private"}, missing)
+	})
+
+	t.Run("backlinks only list readable docs", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"Private", "Child"}, getBacklinks(t, memberID))
+		assert.ElementsMatch(t, []string{"Child", "Restricted"}, getBacklinks(t, guestID))
+	})
+
+	t.Run("outsider cannot list links", func(t *testing.T) {
+		router, _ := setupRouter(t)
+
+		for _, path := range []string{"/links", "/backlinks"} {
+			w := serve(router, http.MethodGet, "/wikiDocs/"+publishedDoc.ID+path, outsiderID)
+			assert.Equal(t, http.StatusForbidden, w.Code, "GET /wikiDocs/{id}%s", path)
+		}
+	})
+}
+
+func TestGetWikiDocsRequesterInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		expected app.RequesterInfo
+	}{
+		{"member", memberID, app.RequesterInfo{UserID: memberID}},
+		{"guest", guestID, app.RequesterInfo{UserID: guestID, IsGuest: true}},
+		{"admin", adminID, app.RequesterInfo{UserID: adminID, IsAdmin: true}},
+		{"group member", groupMemberID, app.RequesterInfo{UserID: groupMemberID, GroupIDs: []string{groupID}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, service := setupRouter(t)
+
+			w := serve(router, http.MethodGet, "/wikiDocs?channel_id="+channelID, tc.userID)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.NotNil(t, service.requesterInfo)
+			assert.Equal(t, tc.expected, *service.requesterInfo)
+		})
+	}
+}
+
+func TestListAndGetAgree(t *testing.T) {
+	for _, userID := range []string{memberID, outsiderID, guestID, adminID, readerID} {
+		router, _ := setupRouter(t)
+
+		w := serve(router, http.MethodGet, "/wikiDocs?channel_id="+channelID, userID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var results app.GetWikiDocsResults
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&results))
+		listed := map[string]bool{}
+		for _, wikiDoc := range results.Items {
+			listed[wikiDoc.ID] = true
+		}
+
+		for _, wikiDoc := range []app.WikiDoc{publishedDoc, privateDoc, childDoc} {
+			w = serve(router, http.MethodGet, "/wikiDocs/"+wikiDoc.ID, userID)
+			assert.Equal(t, listed[wikiDoc.ID], w.Code == http.StatusOK, "user %s, wikiDoc %s", userID, wikiDoc.Name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+
+	root "github.com/CyberPeace-Institute/mattermost-plugin-wiki"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+// announcementExcerptLength is the maximum number of characters of the excerpt of an announcement.
+const announcementExcerptLength = 300
+
+// announce posts in the channel of a wikiDoc that it was created or published, unless the channel
+// disabled announcements. Restricted wikiDocs are not announced, as not every member of the channel
+// can read them. Failures are logged: the change itself already happened.
+func (s *wikiDocsService) announce(wikiDoc WikiDoc, published bool) {
+	if wikiDoc.Restricted {
+		return
+	}
+
+	settings, err := s.settingsStore.GetChannelSettings(wikiDoc.ChannelID)
+	if err != nil {
+		s.logger.Warnf("failed to get the settings of channel %s to announce wikiDoc %s: %v", wikiDoc.ChannelID, wikiDoc.ID, err)
+		return
+	}
+	if settings.DisableAnnouncements {
+		return
+	}
+
+	author, err := s.api.User.Get(wikiDoc.OwnerUserID)
+	if err != nil {
+		s.logger.Warnf("failed to get the author of wikiDoc %s to announce it: %v", wikiDoc.ID, err)
+		return
+	}
+
+	post := announcementPost(wikiDoc, author, published, wikiDocLink(siteURL(s.api), wikiDoc))
+	if err = s.poster.Post(wikiDoc.ChannelID, post); err != nil {
+		s.logger.Warnf("failed to announce wikiDoc %s: %v", wikiDoc.ID, err)
+	}
+}
+
+// siteURL returns the configured URL of the server, empty if there is none.
+func siteURL(api *pluginapi.Client) string {
+	config := api.Configuration.GetConfig()
+	if config == nil || config.ServiceSettings.SiteURL == nil {
+		return ""
+	}
+
+	return strings.TrimRight(*config.ServiceSettings.SiteURL, "/")
+}
+
+// wikiDocLink returns the URL of a wikiDoc on the plugin API, relative to the server when the site
+// URL is not configured.
+func wikiDocLink(siteURL string, wikiDoc WikiDoc) string {
+	return fmt.Sprintf("%s/plugins/%s/api/v0/wikiDocs/%s", siteURL, root.Manifest.Id, wikiDoc.ID)
+}
+
+// announcementPost is the post announcing that a wikiDoc was created or published.
+func announcementPost(wikiDoc WikiDoc, author *model.User, published bool, link string) *model.Post {
+	verb := "created"
+	if published {
+		verb = "published"
+	}
+
+	post := &model.Post{
+		Message: fmt.Sprintf("@%s %s the wiki doc **%s**.", author.Username, verb, wikiDoc.Name),
+	}
+	attachment := &model.SlackAttachment{
+		AuthorName: author.GetDisplayName(model.ShowUsername),
+		Title:      wikiDoc.Name,
+		TitleLink:  link,
+		Footer:     wikiDoc.Status,
+	}
+
+	// Guests of the channel only read public wikiDocs: the others are not quoted.
+	if IsPublicStatus(wikiDoc.Status) {
+		attachment.Text = excerpt(wikiDoc)
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+
+	return post
+}
+
+// excerpt returns the description of a wikiDoc, or the beginning of its content when it has none,
+// shortened to announcementExcerptLength characters.
+func excerpt(wikiDoc WikiDoc) string {
+	text := strings.TrimSpace(wikiDoc.Description)
+	if text == "" {
+		text = strings.TrimSpace(wikiDoc.Content)
+	}
+
+	if utf8.RuneCountInString(text) <= announcementExcerptLength {
+		return text
+	}
+
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:announcementExcerptLength])) + "…"
+}
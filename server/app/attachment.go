@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+
+	root "github.com/CyberPeace-Institute/mattermost-plugin-wiki"
+)
+
+// Attachment is a file attached to a wikiDoc, stored through the Mattermost file API.
+type Attachment struct {
+	ID        string `json:"id"`
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// FileID is the Mattermost file holding the content of the attachment.
+	FileID string `json:"-"`
+
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+
+	// UserID is the user who uploaded the attachment.
+	UserID   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+
+	// URL is the path of the attachment on the plugin API, to be referenced in the content.
+	URL string `json:"url"`
+}
+
+// AttachmentPath returns the path of an attachment on the plugin API, relative to the server.
+func AttachmentPath(wikiDocID, attachmentID string) string {
+	return fmt.Sprintf("/plugins/%s/api/v0/wikiDocs/%s/attachments/%s", root.Manifest.Id, wikiDocID, attachmentID)
+}
+
+// AttachmentStore is an interface for storing the attachments of wikiDocs
+type AttachmentStore interface {
+	// GetAttachments retrieves the attachments of a wikiDoc, oldest first
+	GetAttachments(wikiDocID string) ([]Attachment, error)
+
+	// GetAttachment retrieves an attachment. Returns ErrNotFound if not found.
+	GetAttachment(id string) (Attachment, error)
+
+	// CreateAttachment stores a new attachment
+	CreateAttachment(attachment Attachment) error
+
+	// DeleteAttachment deletes an attachment
+	DeleteAttachment(id string) error
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+// AttachmentService manages the attachments of wikiDocs
+type AttachmentService interface {
+	// GetAttachments retrieves the attachments of a wikiDoc, oldest first
+	GetAttachments(wikiDocID string) ([]Attachment, error)
+
+	// Get retrieves an attachment. Returns ErrNotFound if not found.
+	Get(id string) (Attachment, error)
+
+	// Upload stores a file in the channel of a wikiDoc and attaches it to the wikiDoc on behalf of
+	// userID. Returns ErrMalformedAttachment if the name is empty, and ErrWikiDocInTrash if the
+	// wikiDoc is in the trash.
+	Upload(wikiDoc WikiDoc, userID, name string, content io.Reader) (Attachment, error)
+
+	// Open returns the content of an attachment
+	Open(attachment Attachment) (io.Reader, error)
+
+	// Delete detaches an attachment from its wikiDoc. The Mattermost file API cannot delete files:
+	// the file stays in the storage of the server.
+	Delete(id string) error
+}
+
+type attachmentService struct {
+	store AttachmentStore
+	api   *pluginapi.Client
+}
+
+func NewAttachmentService(store AttachmentStore, api *pluginapi.Client) AttachmentService {
+	return &attachmentService{
+		store: store,
+		api:   api,
+	}
+}
+
+func (s *attachmentService) GetAttachments(wikiDocID string) ([]Attachment, error) {
+	attachments, err := s.store.GetAttachments(wikiDocID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		attachments[i].URL = AttachmentPath(attachments[i].WikiDocID, attachments[i].ID)
+	}
+
+	return attachments, nil
+}
+
+func (s *attachmentService) Get(id string) (Attachment, error) {
+	attachment, err := s.store.GetAttachment(id)
+	if err != nil {
+		return Attachment{}, err
+	}
+	attachment.URL = AttachmentPath(attachment.WikiDocID, attachment.ID)
+
+	return attachment, nil
+}
+
+func (s *attachmentService) Upload(wikiDoc WikiDoc, userID, name string, content io.Reader) (Attachment, error) {
+	if wikiDoc.DeleteAt != 0 {
+		return Attachment{}, errors.Wrapf(ErrWikiDocInTrash, "cannot attach a file to wikiDoc '%s'", wikiDoc.ID)
+	}
+
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return Attachment{}, errors.Wrap(ErrMalformedAttachment, "the file must have a name")
+	}
+
+	info, err := s.api.File.Upload(content, name, wikiDoc.ChannelID)
+	if err != nil {
+		return Attachment{}, errors.Wrapf(err, "failed to upload '%s'", name)
+	}
+
+	attachment := Attachment{
+		ID:        model.NewId(),
+		WikiDocID: wikiDoc.ID,
+		FileID:    info.Id,
+		Name:      info.Name,
+		MimeType:  info.MimeType,
+		Size:      info.Size,
+		UserID:    userID,
+		CreateAt:  model.GetMillis(),
+	}
+	if err = s.store.CreateAttachment(attachment); err != nil {
+		return Attachment{}, err
+	}
+	attachment.URL = AttachmentPath(attachment.WikiDocID, attachment.ID)
+
+	return attachment, nil
+}
+
+func (s *attachmentService) Open(attachment Attachment) (io.Reader, error) {
+	content, err := s.api.File.Get(attachment.FileID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read attachment '%s'", attachment.ID)
+	}
+
+	return content, nil
+}
+
+func (s *attachmentService) Delete(id string) error {
+	return s.store.DeleteAttachment(id)
+}
+
+// copyAttachments copies the attachments of a wikiDoc for its copy, owned by userID, and returns
+// them along with the content of the copy, whose references to the attachments of the original
+// now point to their copies. The copies are not stored.
+func (s *wikiDocsService) copyAttachments(original WikiDoc, copyID, userID string) ([]Attachment, string, error) {
+	attachments, err := s.attachmentStore.GetAttachments(original.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(attachments) == 0 {
+		return nil, original.Content, nil
+	}
+
+	fileIDs := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		fileIDs = append(fileIDs, attachment.FileID)
+	}
+	copiedFileIDs, err := s.api.File.CopyInfos(fileIDs, userID)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to copy the files of wikiDoc '%s'", original.ID)
+	}
+
+	content := original.Content
+	now := model.GetMillis()
+	copies := make([]Attachment, 0, len(attachments))
+	for i, attachment := range attachments {
+		copied := attachment
+		copied.ID = model.NewId()
+		copied.WikiDocID = copyID
+		copied.FileID = copiedFileIDs[i]
+		copied.UserID = userID
+		copied.CreateAt = now
+		copies = append(copies, copied)
+
+		content = strings.ReplaceAll(content, AttachmentPath(original.ID, attachment.ID), AttachmentPath(copyID, copied.ID))
+	}
+
+	return copies, content, nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// ChannelSettings configures the wiki of a channel. Channels without settings use the zero value.
+type ChannelSettings struct {
+	ChannelID string `json:"channel_id"`
+
+	// RequiredApprovals is the number of reviewers who must approve a wikiDoc before it can be
+	// published. Zero lets wikiDocs be published without review.
+	RequiredApprovals int `json:"required_approvals"`
+
+	// DisableAnnouncements stops the bot from posting in the channel when a wikiDoc is created or
+	// published.
+	DisableAnnouncements bool `json:"disable_announcements"`
+
+	UpdateAt int64 `json:"update_at"`
+}
+
+// ChannelSettingsStore is an interface for storing the wiki settings of channels
+type ChannelSettingsStore interface {
+	// GetChannelSettings retrieves the settings of a channel, defaults if none were saved
+	GetChannelSettings(channelID string) (ChannelSettings, error)
+
+	// SaveChannelSettings creates or replaces the settings of a channel
+	SaveChannelSettings(settings ChannelSettings) error
+}
+
+// ChannelSettingsService manages the wiki settings of channels
+type ChannelSettingsService interface {
+	// Get retrieves the settings of a channel
+	Get(channelID string) (ChannelSettings, error)
+
+	// Update replaces the settings of a channel and returns them. Returns ErrMalformedWikiDoc if
+	// they are not valid.
+	Update(settings ChannelSettings) (ChannelSettings, error)
+}
+
+type channelSettingsService struct {
+	store ChannelSettingsStore
+}
+
+func NewChannelSettingsService(store ChannelSettingsStore) ChannelSettingsService {
+	return &channelSettingsService{
+		store: store,
+	}
+}
+
+func (s *channelSettingsService) Get(channelID string) (ChannelSettings, error) {
+	return s.store.GetChannelSettings(channelID)
+}
+
+func (s *channelSettingsService) Update(settings ChannelSettings) (ChannelSettings, error) {
+	if !model.IsValidId(settings.ChannelID) {
+		return ChannelSettings{}, errors.Wrap(ErrMalformedWikiDoc, "channel id must be 26 characters")
+	}
+
+	if settings.RequiredApprovals < 0 {
+		return ChannelSettings{}, errors.Wrap(ErrMalformedWikiDoc, "required approvals cannot be negative")
+	}
+
+	settings.UpdateAt = model.GetMillis()
+	if err := s.store.SaveChannelSettings(settings); err != nil {
+		return ChannelSettings{}, err
+	}
+
+	return settings, nil
+}
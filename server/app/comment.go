@@ -0,0 +1,185 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CommentAnchor locates the text of a wikiDoc a comment thread is about. The heading and the quote
+// are taken from the revision the thread was started on; the offset follows the quote in the current
+// content of the wikiDoc, see ReanchorComment.
+type CommentAnchor struct {
+	// Revision is the revision of the wikiDoc the thread was started on.
+	Revision int64 `json:"revision"`
+
+	// Heading is the text of the heading of the section the quote is in, empty before the first heading.
+	Heading string `json:"heading"`
+
+	// Quote is the commented text.
+	Quote string `json:"quote"`
+
+	// Offset is the byte offset of the quote in the current content of the wikiDoc.
+	Offset int `json:"offset"`
+
+	// Detached is true when the quote is no longer in the current content. Offset is then where it
+	// was last found. The thread is anchored again if the quote comes back.
+	Detached bool `json:"detached"`
+}
+
+// Comment is a comment on a wikiDoc. The first comment of a thread is anchored to the text it is
+// about; the replies point to it.
+type Comment struct {
+	ID        string `json:"id"`
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// ParentID is the ID of the first comment of the thread for replies, empty otherwise.
+	ParentID string `json:"parent_id"`
+
+	// UserID is the author of the comment.
+	UserID  string `json:"user_id"`
+	Message string `json:"message"`
+
+	// Anchor is only set on the first comment of a thread.
+	Anchor *CommentAnchor `json:"anchor,omitempty"`
+
+	// ResolvedAt is when the thread was resolved, 0 while it is open. Only set on the first comment
+	// of a thread.
+	ResolvedAt int64 `json:"resolved_at"`
+
+	// ResolvedBy is the user who resolved the thread.
+	ResolvedBy string `json:"resolved_by"`
+
+	CreateAt int64 `json:"create_at"`
+	UpdateAt int64 `json:"update_at"`
+}
+
+// IsThread returns true if the comment starts a thread.
+func (c Comment) IsThread() bool {
+	return c.ParentID == ""
+}
+
+// CommentStore is an interface for storing the comments of wikiDocs
+type CommentStore interface {
+	// GetComments retrieves the comments of a wikiDoc, oldest first
+	GetComments(wikiDocID string) ([]Comment, error)
+
+	// GetComment retrieves a comment. Returns ErrNotFound if not found.
+	GetComment(id string) (Comment, error)
+
+	// CreateComment stores a new comment
+	CreateComment(comment Comment) error
+
+	// UpdateComment stores the message and the resolution of a comment
+	UpdateComment(comment Comment) error
+
+	// UpdateAnchors stores the anchors of the given threads
+	UpdateAnchors(comments []Comment) error
+
+	// DeleteComment deletes a comment along with its replies
+	DeleteComment(id string) error
+}
+
+// headingPattern matches the ATX headings of markdown content.
+var headingPattern = regexp.MustCompile(`(?m)^ {0,3}#{1,6}[ \t]+(.*?)[ \t#]*$`)
+
+// contentSection is the part of markdown content under a heading.
+type contentSection struct {
+	heading    string
+	start, end int
+}
+
+// contentSections splits markdown content into the sections of its headings. Content before the
+// first heading is a section without heading.
+func contentSections(content string) []contentSection {
+	sections := []contentSection{{start: 0}}
+	for _, match := range headingPattern.FindAllStringSubmatchIndex(content, -1) {
+		sections[len(sections)-1].end = match[0]
+		sections = append(sections, contentSection{
+			heading: content[match[2]:match[3]],
+			start:   match[0],
+		})
+	}
+	sections[len(sections)-1].end = len(content)
+
+	return sections
+}
+
+// headingAt returns the heading of the section containing the offset.
+func headingAt(content string, offset int) string {
+	for _, section := range contentSections(content) {
+		if offset >= section.start && offset < section.end {
+			return section.heading
+		}
+	}
+	return ""
+}
+
+// locateQuote returns the offset of the occurrence of the quote closest to near, preferring the
+// occurrences in the sections of the heading. Returns -1 if the quote is not in the content.
+func locateQuote(content, heading, quote string, near int) int {
+	if quote == "" {
+		return -1
+	}
+
+	var occurrences, inSection []int
+	sections := contentSections(content)
+	for i := strings.Index(content, quote); i >= 0; {
+		occurrences = append(occurrences, i)
+		for _, section := range sections {
+			if i >= section.start && i < section.end && section.heading == heading {
+				inSection = append(inSection, i)
+			}
+		}
+
+		next := strings.Index(content[i+1:], quote)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	// The heading may have been renamed: fall back to the whole content.
+	if len(inSection) > 0 {
+		occurrences = inSection
+	}
+
+	best := -1
+	for _, offset := range occurrences {
+		if best < 0 || distance(offset, near) < distance(best, near) {
+			best = offset
+		}
+	}
+
+	return best
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// ReanchorComment moves the anchor of a thread to the position of its quote in new content, and
+// returns whether it changed. The thread is detached when the quote is no longer in the content.
+func ReanchorComment(comment *Comment, content string) bool {
+	if comment.Anchor == nil {
+		return false
+	}
+
+	anchor := *comment.Anchor
+	if offset := locateQuote(content, anchor.Heading, anchor.Quote, anchor.Offset); offset >= 0 {
+		anchor.Offset = offset
+		anchor.Heading = headingAt(content, offset)
+		anchor.Detached = false
+	} else {
+		anchor.Detached = true
+	}
+
+	if anchor == *comment.Anchor {
+		return false
+	}
+
+	comment.Anchor = &anchor
+	return true
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// CommentService manages the comment threads of wikiDocs
+type CommentService interface {
+	// GetComments retrieves the comments of a wikiDoc, oldest first. Replies follow the thread they
+	// belong to through their ParentID.
+	GetComments(wikiDocID string) ([]Comment, error)
+
+	// Get retrieves a comment. Returns ErrNotFound if not found.
+	Get(id string) (Comment, error)
+
+	// Create adds a comment to a wikiDoc. A comment without ParentID starts a thread anchored to the
+	// quote of its anchor in the given revision, or in the current content for revision 0. Replies
+	// are added to the thread of their parent. Returns ErrMalformedComment if the message is empty,
+	// the quote is not in the revision, or the parent is not a thread of the wikiDoc.
+	Create(comment Comment) (Comment, error)
+
+	// Edit replaces the message of a comment. Returns ErrMalformedComment if the message is empty.
+	Edit(comment Comment, message string) (Comment, error)
+
+	// Resolve marks a thread as resolved by userID. Returns ErrMalformedComment for replies.
+	Resolve(comment Comment, userID string) (Comment, error)
+
+	// Reopen marks a resolved thread as open again. Returns ErrMalformedComment for replies.
+	Reopen(comment Comment) (Comment, error)
+
+	// Delete deletes a comment, along with its replies when it starts a thread
+	Delete(id string) error
+}
+
+type commentService struct {
+	store           CommentStore
+	wikiDocsService WikiDocService
+}
+
+func NewCommentService(store CommentStore, wikiDocsService WikiDocService) CommentService {
+	return &commentService{
+		store:           store,
+		wikiDocsService: wikiDocsService,
+	}
+}
+
+func (s *commentService) GetComments(wikiDocID string) ([]Comment, error) {
+	return s.store.GetComments(wikiDocID)
+}
+
+func (s *commentService) Get(id string) (Comment, error) {
+	return s.store.GetComment(id)
+}
+
+func (s *commentService) Create(comment Comment) (Comment, error) {
+	comment.Message = strings.TrimSpace(comment.Message)
+	if comment.Message == "" {
+		return Comment{}, errors.Wrap(ErrMalformedComment, "message cannot be empty")
+	}
+
+	wikiDoc, err := s.wikiDocsService.Get(comment.WikiDocID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if wikiDoc.DeleteAt != 0 {
+		return Comment{}, errors.New("cannot comment a wikiDoc that is archived")
+	}
+
+	if comment.ParentID != "" {
+		parent, err := s.store.GetComment(comment.ParentID)
+		if errors.Is(err, ErrNotFound) || (err == nil && parent.WikiDocID != wikiDoc.ID) {
+			return Comment{}, errors.Wrapf(ErrMalformedComment, "comment '%s' is not a thread of the wikiDoc", comment.ParentID)
+		} else if err != nil {
+			return Comment{}, err
+		}
+
+		// Replies to replies go to the thread.
+		if !parent.IsThread() {
+			comment.ParentID = parent.ParentID
+		}
+		comment.Anchor = nil
+	} else if comment.Anchor, err = s.anchor(wikiDoc, comment.Anchor); err != nil {
+		return Comment{}, err
+	}
+
+	now := model.GetMillis()
+	comment.ID = model.NewId()
+	comment.ResolvedAt = 0
+	comment.ResolvedBy = ""
+	comment.CreateAt = now
+	comment.UpdateAt = now
+
+	if err = s.store.CreateComment(comment); err != nil {
+		return Comment{}, err
+	}
+
+	return comment, nil
+}
+
+// anchor locates the quote of a new thread in its revision, and follows it to the current content of
+// the wikiDoc. The offset of the given anchor, if any, designates the occurrence of the quote.
+func (s *commentService) anchor(wikiDoc WikiDoc, anchor *CommentAnchor) (*CommentAnchor, error) {
+	if anchor == nil || strings.TrimSpace(anchor.Quote) == "" {
+		return nil, errors.Wrap(ErrMalformedComment, "a thread must quote the text it is about")
+	}
+
+	content := wikiDoc.Content
+	if anchor.Revision != 0 {
+		revision, err := s.wikiDocsService.GetRevision(wikiDoc.ID, anchor.Revision)
+		if errors.Is(err, ErrNotFound) {
+			return nil, errors.Wrapf(ErrMalformedComment, "revision %d does not exist", anchor.Revision)
+		} else if err != nil {
+			return nil, err
+		}
+		content = revision.Content
+	} else {
+		revisions, err := s.wikiDocsService.GetRevisions(wikiDoc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) > 0 {
+			anchor.Revision = revisions[0].Revision
+		}
+	}
+
+	offset := locateQuote(content, anchor.Heading, anchor.Quote, anchor.Offset)
+	if offset < 0 {
+		return nil, errors.Wrapf(ErrMalformedComment, "quote is not in revision %d", anchor.Revision)
+	}
+
+	located := &Comment{Anchor: &CommentAnchor{
+		Revision: anchor.Revision,
+		Heading:  headingAt(content, offset),
+		Quote:    anchor.Quote,
+		Offset:   offset,
+	}}
+	if content != wikiDoc.Content {
+		ReanchorComment(located, wikiDoc.Content)
+	}
+
+	return located.Anchor, nil
+}
+
+func (s *commentService) Edit(comment Comment, message string) (Comment, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return Comment{}, errors.Wrap(ErrMalformedComment, "message cannot be empty")
+	}
+
+	comment.Message = message
+	comment.UpdateAt = model.GetMillis()
+	if err := s.store.UpdateComment(comment); err != nil {
+		return Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentService) Resolve(comment Comment, userID string) (Comment, error) {
+	if !comment.IsThread() {
+		return Comment{}, errors.Wrap(ErrMalformedComment, "only threads can be resolved")
+	}
+	if comment.ResolvedAt != 0 {
+		return comment, nil
+	}
+
+	now := model.GetMillis()
+	comment.ResolvedAt = now
+	comment.ResolvedBy = userID
+	comment.UpdateAt = now
+	if err := s.store.UpdateComment(comment); err != nil {
+		return Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentService) Reopen(comment Comment) (Comment, error) {
+	if !comment.IsThread() {
+		return Comment{}, errors.Wrap(ErrMalformedComment, "only threads can be reopened")
+	}
+	if comment.ResolvedAt == 0 {
+		return comment, nil
+	}
+
+	comment.ResolvedAt = 0
+	comment.ResolvedBy = ""
+	comment.UpdateAt = model.GetMillis()
+	if err := s.store.UpdateComment(comment); err != nil {
+		return Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentService) Delete(id string) error {
+	return s.store.DeleteComment(id)
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReanchorComment(t *testing.T) {
+	content := "Intro mentions the plan.\n\n# Goals\n\nShip the plan.\n\n# Risks\n\nThe plan slips.\n"
+
+	anchored := func(heading, quote string) *Comment {
+		offset := locateQuote(content, heading, quote, 0)
+		assert.GreaterOrEqual(t, offset, 0)
+		return &Comment{Anchor: &CommentAnchor{Revision: 1, Heading: heading, Quote: quote, Offset: offset}}
+	}
+
+	t.Run("quote is found in the section of its heading", func(t *testing.T) {
+		comment := anchored("Risks", "plan")
+		assert.Equal(t, strings.Index(content, "plan slips"), comment.Anchor.Offset)
+		assert.Equal(t, "Risks", headingAt(content, comment.Anchor.Offset))
+	})
+
+	t.Run("unchanged content keeps the anchor", func(t *testing.T) {
+		comment := anchored("Goals", "Ship the plan")
+		assert.False(t, ReanchorComment(comment, content))
+	})
+
+	t.Run("anchor follows the quote when text is inserted before it", func(t *testing.T) {
+		comment := anchored("Goals", "Ship the plan")
+		edited := strings.Replace(content, "Intro", "A longer intro", 1)
+
+		assert.True(t, ReanchorComment(comment, edited))
+		assert.Equal(t, strings.Index(edited, "Ship the plan"), comment.Anchor.Offset)
+		assert.False(t, comment.Anchor.Detached)
+	})
+
+	t.Run("anchor follows the quote when its heading is renamed", func(t *testing.T) {
+		comment := anchored("Goals", "Ship the plan")
+		edited := strings.Replace(content, "# Goals", "# Objectives", 1)
+
+		assert.True(t, ReanchorComment(comment, edited))
+		assert.Equal(t, "Objectives", comment.Anchor.Heading)
+		assert.Equal(t, strings.Index(edited, "Ship the plan"), comment.Anchor.Offset)
+	})
+
+	t.Run("thread is detached when the quote is removed, and anchored again when it comes back", func(t *testing.T) {
+		comment := anchored("Goals", "Ship the plan")
+		edited := strings.Replace(content, "Ship the plan.", "Drop it.", 1)
+
+		assert.True(t, ReanchorComment(comment, edited))
+		assert.True(t, comment.Anchor.Detached)
+		assert.Equal(t, int64(1), comment.Anchor.Revision)
+
+		assert.True(t, ReanchorComment(comment, content))
+		assert.False(t, comment.Anchor.Detached)
+	})
+
+	t.Run("replies have no anchor", func(t *testing.T) {
+		assert.False(t, ReanchorComment(&Comment{ParentID: "thread"}, content))
+	})
+}
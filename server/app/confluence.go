@@ -0,0 +1,383 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConfluenceEntitiesName is the name of the file describing the content of a Confluence space
+	// export.
+	ConfluenceEntitiesName = "entities.xml"
+
+	// confluenceDateLayout is the layout of the dates of a Confluence space export.
+	confluenceDateLayout = "2006-01-02 15:04:05.000"
+)
+
+// confluenceObject is an object of the entities of a Confluence space export.
+type confluenceObject struct {
+	Class       string                 `xml:"class,attr"`
+	ID          string                 `xml:"id"`
+	Properties  []confluenceProperty   `xml:"property"`
+	Collections []confluenceCollection `xml:"collection"`
+}
+
+// confluenceProperty is a property of an object: a value, or a reference to another object.
+type confluenceProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+	ID    string `xml:"id"`
+}
+
+// confluenceCollection is a collection of references to other objects.
+type confluenceCollection struct {
+	Name     string `xml:"name,attr"`
+	Elements []struct {
+		ID string `xml:"id"`
+	} `xml:"element"`
+}
+
+func (o confluenceObject) property(name string) confluenceProperty {
+	for _, property := range o.Properties {
+		if property.Name == name {
+			return property
+		}
+	}
+	return confluenceProperty{}
+}
+
+func (o confluenceObject) value(name string) string {
+	return strings.TrimSpace(o.property(name).Value)
+}
+
+func (o confluenceObject) reference(name string) string {
+	return strings.TrimSpace(o.property(name).ID)
+}
+
+func (o confluenceObject) number(name string) int {
+	number, _ := strconv.Atoi(o.value(name))
+	return number
+}
+
+// bodyIDs returns the IDs of the body contents of a page, in order.
+func (o confluenceObject) bodyIDs() []string {
+	var ids []string
+	for _, collection := range o.Collections {
+		if collection.Name != "bodyContents" {
+			continue
+		}
+		for _, element := range collection.Elements {
+			ids = append(ids, strings.TrimSpace(element.ID))
+		}
+	}
+	return ids
+}
+
+// current returns true for the objects that are neither old versions, drafts nor deleted.
+func (o confluenceObject) current() bool {
+	status := o.value("contentStatus")
+	return o.reference("originalVersion") == "" && (status == "" || status == "current")
+}
+
+// confluencePage is a page of a Confluence space export, with its older versions.
+type confluencePage struct {
+	confluenceObject
+	versions []confluenceObject
+}
+
+// ReadConfluenceExport returns the documents of a Confluence space export, parents first: a
+// document per current page of the space, with its older versions and its attachments. The pages
+// keep their hierarchy, and their storage format is converted to Markdown. The entities, and the
+// attachments altogether, must not be larger than maxSize bytes once uncompressed.
+func ReadConfluenceExport(archive *zip.Reader, maxSize int64) ([]ImportDoc, error) {
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[path.Clean(strings.ReplaceAll(file.Name, "\\", "/"))] = file
+	}
+
+	entities, ok := files[ConfluenceEntitiesName]
+	if !ok {
+		return nil, errors.Wrapf(ErrMalformedImport, "not a Confluence space export: %s is missing", ConfluenceEntitiesName)
+	}
+	if entities.UncompressedSize64 > uint64(maxSize) {
+		return nil, newImportBudget(maxSize).err()
+	}
+
+	pages := map[string]*confluencePage{}
+	var oldVersions []confluenceObject
+	attachments := map[string][]confluenceObject{}
+	attachmentCount := 0
+
+	err := readConfluenceEntities(entities, maxSize, func(object confluenceObject) error {
+		switch object.Class {
+		case "Page":
+			if object.current() {
+				if len(pages) == maxImportFiles {
+					return errors.Wrapf(ErrMalformedImport, "the export has more than %d pages", maxImportFiles)
+				}
+				pages[object.ID] = &confluencePage{confluenceObject: object}
+			} else if object.reference("originalVersion") != "" {
+				oldVersions = append(oldVersions, object)
+			}
+		case "Attachment":
+			if object.current() {
+				if attachmentCount == maxImportFiles {
+					return errors.Wrapf(ErrMalformedImport, "the export has more than %d attachments", maxImportFiles)
+				}
+				attachmentCount++
+				container := firstNonEmpty(object.reference("containerContent"), object.reference("content"))
+				attachments[container] = append(attachments[container], object)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range oldVersions {
+		if page, ok := pages[version.reference("originalVersion")]; ok {
+			page.versions = append(page.versions, version)
+		}
+	}
+
+	// The bodies are read on a second pass, only those of the versions that are imported.
+	wanted := map[string]bool{}
+	for _, page := range pages {
+		sort.Slice(page.versions, func(i, j int) bool {
+			return page.versions[i].number("version") < page.versions[j].number("version")
+		})
+		if len(page.versions) > maxImportRevisions-1 {
+			page.versions = page.versions[len(page.versions)-maxImportRevisions+1:]
+		}
+		for _, id := range page.bodyIDs() {
+			wanted[id] = true
+		}
+		for _, version := range page.versions {
+			for _, id := range version.bodyIDs() {
+				wanted[id] = true
+			}
+		}
+	}
+
+	bodies := map[string]string{}
+	err = readConfluenceEntities(entities, maxSize, func(object confluenceObject) error {
+		if object.Class == "BodyContent" && wanted[object.ID] {
+			bodies[object.ID] = object.property("body").Value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The attachments are opened when they are imported, from a budget shared by all of them.
+	attachmentBudget := newImportBudget(maxSize)
+
+	docs := make([]ImportDoc, 0, len(pages))
+	depths := map[string]int{}
+	positions := map[string]int{}
+	for id, page := range pages {
+		doc := ImportDoc{
+			Path:        id,
+			WikiDoc:     confluenceVersion(page.confluenceObject, bodies),
+			Attachments: confluenceAttachments(id, attachments[id], files, attachmentBudget),
+		}
+		doc.WikiDoc.CreateAt = parseConfluenceDate(page.value("creationDate"))
+		if parent := page.reference("parent"); pages[parent] != nil {
+			doc.ParentPath = parent
+		}
+
+		for _, version := range page.versions {
+			doc.Revisions = append(doc.Revisions, confluenceVersion(version, bodies))
+		}
+
+		depths[id] = confluenceDepth(page, pages)
+		positions[id] = page.number("position")
+		docs = append(docs, doc)
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if depths[a.Path] != depths[b.Path] {
+			return depths[a.Path] < depths[b.Path]
+		}
+		if positions[a.Path] != positions[b.Path] {
+			return positions[a.Path] < positions[b.Path]
+		}
+		if a.WikiDoc.Name != b.WikiDoc.Name {
+			return a.WikiDoc.Name < b.WikiDoc.Name
+		}
+		return a.Path < b.Path
+	})
+
+	return docs, nil
+}
+
+// readConfluenceEntities decodes the objects of the entities of an export one at a time, failing
+// once more than maxSize bytes are read.
+func readConfluenceEntities(entities *zip.File, maxSize int64, handle func(object confluenceObject) error) error {
+	reader, err := entities.Open()
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", ConfluenceEntitiesName)
+	}
+	defer reader.Close()
+
+	budget := newImportBudget(maxSize)
+	err = readConfluenceObjects(budget.reader(reader), handle)
+	if budget.exhausted() {
+		return budget.err()
+	}
+	return err
+}
+
+// readConfluenceObjects decodes the objects of the entities of an export one at a time.
+func readConfluenceObjects(r io.Reader, handle func(object confluenceObject) error) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrapf(ErrMalformedImport, "invalid %s: %v", ConfluenceEntitiesName, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			if start.Name.Local != "hibernate-generic" {
+				return errors.Wrapf(ErrMalformedImport, "not a Confluence space export: unexpected %s", ConfluenceEntitiesName)
+			}
+			root = false
+			continue
+		}
+		if start.Name.Local != "object" {
+			continue
+		}
+
+		var object confluenceObject
+		if err = decoder.DecodeElement(&object, &start); err != nil {
+			return errors.Wrapf(ErrMalformedImport, "invalid object: %v", err)
+		}
+		object.ID = strings.TrimSpace(object.ID)
+		if err = handle(object); err != nil {
+			return err
+		}
+	}
+	if root {
+		return errors.Wrapf(ErrMalformedImport, "not a Confluence space export: empty %s", ConfluenceEntitiesName)
+	}
+
+	return nil
+}
+
+// confluenceVersion returns the name, content and date of a version of a page.
+func confluenceVersion(page confluenceObject, bodies map[string]string) WikiDoc {
+	var body string
+	for _, id := range page.bodyIDs() {
+		body += bodies[id]
+	}
+
+	return WikiDoc{
+		Name:     page.value("title"),
+		Content:  StorageToMarkdown(body),
+		UpdateAt: parseConfluenceDate(page.value("lastModificationDate")),
+	}
+}
+
+// confluenceAttachments returns the attachments of a page whose file is in the export, stored
+// as attachments/{page}/{attachment}/{version}. The files fail to open once the budget is spent.
+func confluenceAttachments(pageID string, objects []confluenceObject, files map[string]*zip.File, budget *importBudget) []ImportAttachment {
+	var attachments []ImportAttachment
+	for _, object := range objects {
+		name := firstNonEmpty(object.value("title"), object.value("fileName"))
+		if name == "" {
+			continue
+		}
+
+		folder := path.Join("attachments", pageID, object.ID)
+		file := files[path.Join(folder, strconv.Itoa(object.number("version")))]
+		if file == nil {
+			// Some exports only keep the latest version of the file, whatever its number.
+			file = latestConfluenceFile(files, folder)
+		}
+		if file == nil {
+			continue
+		}
+
+		attachments = append(attachments, ImportAttachment{
+			Name: name,
+			Open: func() (io.ReadCloser, error) { return openConfluenceAttachment(file, budget) },
+		})
+	}
+
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+
+	return attachments
+}
+
+// latestConfluenceFile returns the file of the highest version in the folder of an attachment, or
+// nil if there is none.
+func latestConfluenceFile(files map[string]*zip.File, folder string) *zip.File {
+	var file *zip.File
+	latest := -1
+	for filePath, candidate := range files {
+		if path.Dir(filePath) != folder {
+			continue
+		}
+		if version, err := strconv.Atoi(path.Base(filePath)); err == nil && version > latest {
+			file, latest = candidate, version
+		}
+	}
+	return file
+}
+
+// openConfluenceAttachment opens the file of an attachment, whose reads fail once the budget is spent.
+func openConfluenceAttachment(file *zip.File, budget *importBudget) (io.ReadCloser, error) {
+	if budget.exhausted() || file.UncompressedSize64 > uint64(budget.remaining) {
+		return nil, budget.err()
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{budget.reader(reader), reader}, nil
+}
+
+// confluenceDepth returns the number of ancestors of a page in the export.
+func confluenceDepth(page *confluencePage, pages map[string]*confluencePage) int {
+	depth := 0
+	seen := map[string]bool{page.ID: true}
+	for parent := pages[page.reference("parent")]; parent != nil && !seen[parent.ID]; parent = pages[parent.reference("parent")] {
+		seen[parent.ID] = true
+		depth++
+	}
+	return depth
+}
+
+// parseConfluenceDate returns the time of a date of a Confluence export, in milliseconds, or 0 if
+// it is not valid.
+func parseConfluenceDate(date string) int64 {
+	t, err := time.Parse(confluenceDateLayout, date)
+	if err != nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,479 @@
+package app
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+)
+
+// storageWhitespacePattern matches the runs of whitespace that HTML renders as a single space.
+var storageWhitespacePattern = regexp.MustCompile(`\s+`)
+
+// storageAutoClose are the HTML elements without a closing tag. It leaves out the "link" of
+// xml.HTMLAutoClose, since the decoder ignores the prefix of the names and would close "ac:link".
+var storageAutoClose = func() []string {
+	var names []string
+	for _, name := range xml.HTMLAutoClose {
+		if name != "link" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// storageBlocks are the elements of the Confluence storage format rendered as blocks.
+var storageBlocks = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "table": true, "pre": true, "blockquote": true, "hr": true,
+	"ac:task-list": true, "ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true,
+	"ac:rich-text-body": true,
+}
+
+// storageInlineMacros are the macros of the Confluence storage format rendered within text.
+var storageInlineMacros = map[string]bool{"status": true, "anchor": true}
+
+// storageCalloutMacros are the macros of the Confluence storage format rendered as quotes, with
+// their label.
+var storageCalloutMacros = map[string]string{"info": "Info", "note": "Note", "warning": "Warning", "tip": "Tip", "panel": ""}
+
+// storageNode is an element or a text of a document in the Confluence storage format.
+type storageNode struct {
+	// name is the name of the element, with its namespace prefix, or empty for a text.
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*storageNode
+}
+
+// StorageToMarkdown converts a page in the Confluence storage format, XHTML with Confluence
+// elements, to Markdown. Links to pages become [[links]] to their title, and the attachments of
+// the page are referred to with ImportAttachmentURL. The macros that depend on Confluence, such as
+// the table of contents, are left out.
+func StorageToMarkdown(body string) string {
+	root := parseStorage(body)
+	markdown := strings.Join(storageConverter{}.blocks(root.children), "\n\n")
+
+	markdown = strings.TrimSpace(blankLinesPattern.ReplaceAllString(markdown, "\n\n"))
+	if markdown == "" {
+		return ""
+	}
+	return markdown + "\n"
+}
+
+// parseStorage parses a document in the storage format. Documents that are not well-formed are
+// parsed up to their first error.
+func parseStorage(body string) *storageNode {
+	decoder := xml.NewDecoder(strings.NewReader("<root>" + body + "</root>"))
+	decoder.Strict = false
+	decoder.AutoClose = storageAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	document := &storageNode{}
+	stack := []*storageNode{document}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		parent := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &storageNode{name: storageName(token.Name), attrs: map[string]string{}}
+			for _, attr := range token.Attr {
+				node.attrs[storageName(attr.Name)] = attr.Value
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &storageNode{text: string(token)})
+		}
+	}
+
+	if len(document.children) == 0 {
+		return document
+	}
+	return document.children[0]
+}
+
+// storageName returns a name with its namespace prefix, as in "ac:link".
+func storageName(name xml.Name) string {
+	if name.Space == "" {
+		return strings.ToLower(name.Local)
+	}
+	return strings.ToLower(name.Space + ":" + name.Local)
+}
+
+// child returns the first child element with the given name.
+func (n *storageNode) child(name string) *storageNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// textContent returns the text of a node and its descendants.
+func (n *storageNode) textContent() string {
+	if n == nil {
+		return ""
+	}
+	if n.name == "" {
+		return n.text
+	}
+	var text strings.Builder
+	for _, child := range n.children {
+		text.WriteString(child.textContent())
+	}
+	return text.String()
+}
+
+// storageConverter converts the storage format to Markdown.
+type storageConverter struct{}
+
+func (c storageConverter) isBlock(n *storageNode) bool {
+	if n.name == "ac:structured-macro" || n.name == "ac:macro" {
+		return !storageInlineMacros[n.attrs["ac:name"]]
+	}
+	return storageBlocks[n.name]
+}
+
+// blocks converts nodes to Markdown blocks, grouping the texts and inline elements between blocks
+// in paragraphs.
+func (c storageConverter) blocks(nodes []*storageNode) []string {
+	var out []string
+	var paragraph strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(paragraph.String()); text != "" {
+			out = append(out, text)
+		}
+		paragraph.Reset()
+	}
+
+	for _, node := range nodes {
+		if !c.isBlock(node) {
+			paragraph.WriteString(c.inline(node))
+			continue
+		}
+		flush()
+		if block := c.block(node); strings.TrimSpace(block) != "" {
+			out = append(out, block)
+		}
+	}
+	flush()
+
+	return out
+}
+
+func (c storageConverter) block(n *storageNode) string {
+	switch n.name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return strings.Repeat("#", int(n.name[1]-'0')) + " " + c.inlineText(n.children)
+	case "ul", "ol":
+		return c.list(n)
+	case "table":
+		return c.table(n)
+	case "pre":
+		return fence(n.textContent(), "")
+	case "blockquote":
+		return quote(strings.Join(c.blocks(n.children), "\n\n"))
+	case "hr":
+		return "---"
+	case "ac:structured-macro", "ac:macro":
+		return c.macro(n)
+	case "ac:task-list":
+		var tasks []string
+		for _, task := range n.children {
+			if task.name != "ac:task" {
+				continue
+			}
+			box := "[ ]"
+			if strings.TrimSpace(task.child("ac:task-status").textContent()) == "complete" {
+				box = "[x]"
+			}
+			body := ""
+			if taskBody := task.child("ac:task-body"); taskBody != nil {
+				body = c.inlineText(taskBody.children)
+			}
+			tasks = append(tasks, "- "+box+" "+body)
+		}
+		return strings.Join(tasks, "\n")
+	default:
+		return strings.Join(c.blocks(n.children), "\n\n")
+	}
+}
+
+// inlineText converts nodes to a single line of text.
+func (c storageConverter) inlineText(nodes []*storageNode) string {
+	var text strings.Builder
+	for _, node := range nodes {
+		if c.isBlock(node) {
+			text.WriteString(" " + strings.Join(c.blocks([]*storageNode{node}), " ") + " ")
+			continue
+		}
+		text.WriteString(c.inline(node))
+	}
+	return strings.TrimSpace(strings.ReplaceAll(text.String(), "  \n", " "))
+}
+
+func (c storageConverter) inline(n *storageNode) string {
+	if n.name == "" {
+		return storageWhitespacePattern.ReplaceAllString(n.text, " ")
+	}
+
+	switch n.name {
+	case "strong", "b":
+		return emphasis(c.inlineText(n.children), "**")
+	case "em", "i":
+		return emphasis(c.inlineText(n.children), "*")
+	case "del", "s":
+		return emphasis(c.inlineText(n.children), "~~")
+	case "code":
+		return inlineCode(n.textContent())
+	case "br":
+		return "  \n"
+	case "a":
+		text := c.inlineText(n.children)
+		href := n.attrs["href"]
+		switch {
+		case href == "":
+			return text
+		case text == "" || text == href:
+			return "<" + href + ">"
+		default:
+			return "[" + text + "](" + href + ")"
+		}
+	case "img":
+		return "![" + n.attrs["alt"] + "](" + n.attrs["src"] + ")"
+	case "ac:link":
+		return c.link(n)
+	case "ac:image":
+		return c.image(n)
+	case "ac:structured-macro", "ac:macro":
+		if n.attrs["ac:name"] == "status" {
+			return "[" + c.parameters(n)["title"] + "]"
+		}
+		return ""
+	case "ac:emoticon", "ac:placeholder", "ac:parameter":
+		return ""
+	default:
+		var text strings.Builder
+		for _, child := range n.children {
+			text.WriteString(c.inline(child))
+		}
+		return text.String()
+	}
+}
+
+// link converts a link to a page, an attachment, a URL or a user.
+func (c storageConverter) link(n *storageNode) string {
+	label := strings.TrimSpace(n.child("ac:plain-text-link-body").textContent())
+	if body := n.child("ac:link-body"); body != nil {
+		label = c.inlineText(body.children)
+	}
+
+	if page := n.child("ri:page"); page != nil {
+		title := strings.TrimSpace(page.attrs["ri:content-title"])
+		switch {
+		case title == "":
+			return label
+		case label == "" || label == title:
+			return "[[" + title + "]]"
+		default:
+			return "[[" + title + "|" + label + "]]"
+		}
+	}
+	if attachment := n.child("ri:attachment"); attachment != nil {
+		name := attachment.attrs["ri:filename"]
+		if attachment.child("ri:page") != nil {
+			return firstNonEmpty(label, name)
+		}
+		return "[" + firstNonEmpty(label, name) + "](" + ImportAttachmentURL(name) + ")"
+	}
+	if url := n.child("ri:url"); url != nil {
+		return "[" + firstNonEmpty(label, url.attrs["ri:value"]) + "](" + url.attrs["ri:value"] + ")"
+	}
+	if user := n.child("ri:user"); user != nil && user.attrs["ri:username"] != "" {
+		return "@" + user.attrs["ri:username"]
+	}
+
+	return label
+}
+
+// image converts an image, an attachment of the page or a URL.
+func (c storageConverter) image(n *storageNode) string {
+	alt := n.attrs["ac:alt"]
+	if attachment := n.child("ri:attachment"); attachment != nil {
+		name := attachment.attrs["ri:filename"]
+		if attachment.child("ri:page") != nil {
+			return firstNonEmpty(alt, name)
+		}
+		return "![" + firstNonEmpty(alt, name) + "](" + ImportAttachmentURL(name) + ")"
+	}
+	if url := n.child("ri:url"); url != nil {
+		return "![" + alt + "](" + url.attrs["ri:value"] + ")"
+	}
+	return ""
+}
+
+// macro converts the macros that make sense outside of Confluence: code, callouts and the macros
+// that only lay out their body.
+func (c storageConverter) macro(n *storageNode) string {
+	name := n.attrs["ac:name"]
+	parameters := c.parameters(n)
+
+	body := ""
+	if richText := n.child("ac:rich-text-body"); richText != nil {
+		body = strings.Join(c.blocks(richText.children), "\n\n")
+	}
+
+	switch name {
+	case "code", "noformat":
+		return fence(n.child("ac:plain-text-body").textContent(), parameters["language"])
+	case "toc", "children", "pagetree", "recently-updated", "jira", "attachments", "include", "excerpt-include", "anchor":
+		return ""
+	}
+
+	if label, ok := storageCalloutMacros[name]; ok {
+		title := firstNonEmpty(parameters["title"], label)
+		if title != "" {
+			body = "**" + title + "**\n\n" + body
+		}
+		return quote(body)
+	}
+	if title := parameters["title"]; title != "" && name == "expand" {
+		return "**" + title + "**\n\n" + body
+	}
+
+	return body
+}
+
+// parameters returns the parameters of a macro, by name.
+func (c storageConverter) parameters(n *storageNode) map[string]string {
+	parameters := map[string]string{}
+	for _, child := range n.children {
+		if child.name == "ac:parameter" {
+			parameters[child.attrs["ac:name"]] = strings.TrimSpace(child.textContent())
+		}
+	}
+	return parameters
+}
+
+// list converts a list, its nested lists indented under their item.
+func (c storageConverter) list(n *storageNode) string {
+	marker := "- "
+	if n.name == "ol" {
+		marker = "1. "
+	}
+	indent := strings.Repeat(" ", len(marker))
+
+	var items []string
+	for _, item := range n.children {
+		var content string
+		switch item.name {
+		case "li":
+			content = strings.Join(c.blocks(item.children), "\n")
+		case "ul", "ol":
+			items = append(items, indentLines(c.list(item), indent))
+			continue
+		default:
+			continue
+		}
+		lines := strings.SplitN(content, "\n", 2)
+		item := marker + lines[0]
+		if len(lines) > 1 {
+			item += "\n" + indentLines(lines[1], indent)
+		}
+		items = append(items, item)
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// table converts a table to a Markdown table, its first row being the header.
+func (c storageConverter) table(n *storageNode) string {
+	var rows [][]string
+	var collect func(node *storageNode)
+	collect = func(node *storageNode) {
+		for _, child := range node.children {
+			switch child.name {
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			case "tr":
+				var row []string
+				for _, cell := range child.children {
+					if cell.name == "th" || cell.name == "td" {
+						text := strings.Join(c.blocks(cell.children), " ")
+						text = strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", "\\|")
+						row = append(row, strings.TrimSpace(text))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	collect(n)
+
+	columns := 0
+	for _, row := range rows {
+		columns = maxInt(columns, len(row))
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var out []string
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		out = append(out, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			out = append(out, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// emphasis wraps a text in a delimiter, outside of the spaces around it.
+func emphasis(text, delimiter string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	return delimiter + text + delimiter
+}
+
+// fence returns the fenced code block of a code, delimited by enough backticks.
+func fence(code, lang string) string {
+	delimiter := "```"
+	for strings.Contains(code, delimiter) {
+		delimiter += "`"
+	}
+	return delimiter + lang + "\n" + strings.Trim(code, "\n") + "\n" + delimiter
+}
+
+// quote returns a text as a Markdown quote.
+func quote(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines indents the lines of a text that are not blank.
+func indentLines(text, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
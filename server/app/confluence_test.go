@@ -0,0 +1,193 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageToMarkdown(t *testing.T) {
+	for name, test := range map[string]struct {
+		storage  string
+		expected string
+	}{
+		"text": {
+			`<h2>Setup</h2><p>This is <strong>bold</strong>, <em>italic</em> and <code>code</code>.<br/>Next <a href="https://example.com">site</a>.</p><hr/>`,
+			"## Setup\n\nThis is **bold**, *italic* and `code`.  \nNext [site](https://example.com).\n\n---",
+		},
+		"links and images": {
+			`<p><ac:link><ri:page ri:content-title="Other page" /><ac:plain-text-link-body><![CDATA[the other]]></ac:plain-text-link-body></ac:link> ` +
+				`<ac:link><ri:page ri:content-title="Home" /></ac:link> ` +
+				`<ac:link><ri:attachment ri:filename="spec sheet.pdf" /></ac:link></p>` +
+				`<ac:image ac:alt="Logo"><ri:attachment ri:filename="logo.png" /></ac:image>`,
+			"[[Other page|the other]] [[Home]] [spec sheet.pdf](attachment:spec%20sheet.pdf)\n\n![Logo](attachment:logo.png)",
+		},
+		"lists and tasks": {
+			`<ul><li>One<ul><li>Nested</li></ul></li><li>Two</li></ul><ol><li>First</li></ol>` +
+				`<ac:task-list><ac:task><ac:task-status>complete</ac:task-status><ac:task-body>Done</ac:task-body></ac:task>` +
+				`<ac:task><ac:task-status>incomplete</ac:task-status><ac:task-body>Todo</ac:task-body></ac:task></ac:task-list>`,
+			"- One\n  - Nested\n- Two\n\n1. First\n\n- [x] Done\n- [ ] Todo",
+		},
+		"macros": {
+			`<ac:structured-macro ac:name="toc" />` +
+				`<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter>` +
+				`<ac:plain-text-body><![CDATA[if a < b {}]]></ac:plain-text-body></ac:structured-macro>` +
+				`<ac:structured-macro ac:name="warning"><ac:rich-text-body><p>Careful</p></ac:rich-text-body></ac:structured-macro>` +
+				`<p>State <ac:structured-macro ac:name="status"><ac:parameter ac:name="title">DONE</ac:parameter></ac:structured-macro></p>`,
+			"```go\nif a < b {}\n```\n\n> **Warning**\n>\n> Careful\n\nState [DONE]",
+		},
+		"table": {
+			`<table><tbody><tr><th>Name</th><th>Value</th></tr><tr><td><p>a|b</p></td><td>c</td></tr></tbody></table>`,
+			"| Name | Value |\n| --- | --- |\n| a\\|b | c |",
+		},
+		"empty": {"", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expected := test.expected
+			if expected != "" {
+				expected += "\n"
+			}
+			assert.Equal(t, expected, StorageToMarkdown(test.storage))
+		})
+	}
+}
+
+func TestReadConfluenceExport(t *testing.T) {
+	entities := `<?xml version="1.0" encoding="UTF-8"?>
+<hibernate-generic datetime="2021-03-01 10:00:00">
+<object class="Page" package="com.atlassian.confluence.pages">
+<id name="id">2</id>
+<property name="title"><![CDATA[Child]]></property>
+<property name="parent" class="Page" package="com.atlassian.confluence.pages"><id name="id">1</id></property>
+<collection name="bodyContents" class="java.util.Collection"><element class="BodyContent" package="com.atlassian.confluence.core"><id name="id">20</id></element></collection>
+<property name="version">1</property>
+<property name="creationDate">2021-01-05 00:00:00.000</property>
+<property name="lastModificationDate">2021-01-05 00:00:00.000</property>
+<property name="contentStatus"><![CDATA[current]]></property>
+</object>
+<object class="Page" package="com.atlassian.confluence.pages">
+<id name="id">1</id>
+<property name="title"><![CDATA[Home]]></property>
+<collection name="bodyContents" class="java.util.Collection"><element class="BodyContent" package="com.atlassian.confluence.core"><id name="id">10</id></element></collection>
+<property name="version">2</property>
+<property name="creationDate">2021-01-01 00:00:00.000</property>
+<property name="lastModificationDate">2021-01-03 00:00:00.000</property>
+<property name="contentStatus"><![CDATA[current]]></property>
+</object>
+<object class="Page" package="com.atlassian.confluence.pages">
+<id name="id">3</id>
+<property name="title"><![CDATA[Home]]></property>
+<property name="originalVersion" class="Page" package="com.atlassian.confluence.pages"><id name="id">1</id></property>
+<collection name="bodyContents" class="java.util.Collection"><element class="BodyContent" package="com.atlassian.confluence.core"><id name="id">30</id></element></collection>
+<property name="version">1</property>
+<property name="lastModificationDate">2021-01-01 00:00:00.000</property>
+<property name="contentStatus"><![CDATA[current]]></property>
+</object>
+<object class="Page" package="com.atlassian.confluence.pages">
+<id name="id">4</id>
+<property name="title"><![CDATA[Draft]]></property>
+<property name="contentStatus"><![CDATA[draft]]></property>
+</object>
+<object class="BodyContent" package="com.atlassian.confluence.core">
+<id name="id">10</id>
+<property name="body"><![CDATA[<p>Welcome <ac:image><ri:attachment ri:filename="logo.png" /></ac:image></p>]]></property>
+</object>
+<object class="BodyContent" package="com.atlassian.confluence.core">
+<id name="id">20</id>
+<property name="body"><![CDATA[<p>Child page</p>]]></property>
+</object>
+<object class="BodyContent" package="com.atlassian.confluence.core">
+<id name="id">30</id>
+<property name="body"><![CDATA[<p>Hello</p>]]></property>
+</object>
+<object class="Attachment" package="com.atlassian.confluence.pages">
+<id name="id">100</id>
+<property name="title"><![CDATA[logo.png]]></property>
+<property name="containerContent" class="Page" package="com.atlassian.confluence.pages"><id name="id">1</id></property>
+<property name="version">1</property>
+</object>
+</hibernate-generic>`
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range map[string]string{
+		ConfluenceEntitiesName:        entities,
+		"attachments/1/100/1":         "PNG",
+		"exportDescriptor.properties": "",
+	} {
+		file, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = file.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+
+	docs, err := ReadConfluenceExport(archive, 1<<20)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	home := docs[0]
+	assert.Equal(t, "Home", home.WikiDoc.Name)
+	assert.Equal(t, "Welcome ![logo.png](attachment:logo.png)\n", home.WikiDoc.Content)
+	assert.Equal(t, int64(1609459200000), home.WikiDoc.CreateAt)
+	assert.Equal(t, int64(1609632000000), home.WikiDoc.UpdateAt)
+	require.Len(t, home.Revisions, 1)
+	assert.Equal(t, "Hello\n", home.Revisions[0].Content)
+
+	require.Len(t, home.Attachments, 1)
+	assert.Equal(t, "logo.png", home.Attachments[0].Name)
+	file, err := home.Attachments[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "PNG", string(content))
+
+	child := docs[1]
+	assert.Equal(t, "Child", child.WikiDoc.Name)
+	assert.Equal(t, home.Path, child.ParentPath)
+
+	t.Run("entities larger than the budget", func(t *testing.T) {
+		_, err := ReadConfluenceExport(archive, 100)
+		assert.ErrorIs(t, err, ErrMalformedImport)
+	})
+
+	t.Run("attachments larger than the budget they share", func(t *testing.T) {
+		for _, file := range archive.File {
+			if file.Name == "attachments/1/100/1" {
+				budget := newImportBudget(5)
+				reader, err := openConfluenceAttachment(file, budget)
+				require.NoError(t, err)
+				_, err = io.ReadAll(reader)
+				require.NoError(t, err)
+
+				_, err = openConfluenceAttachment(file, budget)
+				assert.ErrorIs(t, err, ErrMalformedImport)
+			}
+		}
+	})
+
+	t.Run("latest version of an attachment by number", func(t *testing.T) {
+		files := map[string]*zip.File{}
+		for _, version := range []string{"2", "9", "10", "thumbnail"} {
+			files["attachments/1/100/"+version] = &zip.File{FileHeader: zip.FileHeader{Name: version}}
+		}
+		files["attachments/1/200/11"] = &zip.File{FileHeader: zip.FileHeader{Name: "other"}}
+
+		require.NotNil(t, latestConfluenceFile(files, "attachments/1/100"))
+		assert.Equal(t, "10", latestConfluenceFile(files, "attachments/1/100").Name)
+		assert.Nil(t, latestConfluenceFile(files, "attachments/1/300"))
+	})
+
+	_, err = ReadConfluenceExport(&zip.Reader{}, 1<<20)
+	assert.ErrorIs(t, err, ErrMalformedImport)
+	err = readConfluenceObjects(strings.NewReader("<html/>"), func(confluenceObject) error { return nil })
+	assert.ErrorIs(t, err, ErrMalformedImport)
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffContextDefault is the number of unchanged lines shown around each change.
+const DiffContextDefault = 3
+
+// DiffLineType enumerates the kinds of lines and words found in a diff.
+type DiffLineType string
+
+const (
+	// DiffContext is an unchanged line or word.
+	DiffContext DiffLineType = "context"
+
+	// DiffAdded is a line or word only present in the newer version.
+	DiffAdded DiffLineType = "added"
+
+	// DiffRemoved is a line or word only present in the older version.
+	DiffRemoved DiffLineType = "removed"
+)
+
+// DiffOptions specifies how a diff is computed.
+type DiffOptions struct {
+	// Context is the number of unchanged lines around each change. Negative values use DiffContextDefault.
+	Context int
+
+	// Words enables word-level changes inside modified lines.
+	Words bool
+}
+
+// DiffWord is a run of words within a modified line.
+type DiffWord struct {
+	Type DiffLineType `json:"type"`
+	Text string       `json:"text"`
+}
+
+// DiffLine is a single line of a hunk.
+type DiffLine struct {
+	Type DiffLineType `json:"type"`
+	Text string       `json:"text"`
+
+	// OldLine and NewLine are the 1-based line numbers in each version, 0 when the line is absent.
+	OldLine int `json:"old_line,omitempty"`
+	NewLine int `json:"new_line,omitempty"`
+
+	// Words holds the word-level changes of a modified line, when requested.
+	Words []DiffWord `json:"words,omitempty"`
+}
+
+// DiffHunk is a group of changed lines with their surrounding context, as in a unified diff.
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Header   string     `json:"header"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// TextDiff is the diff of a single text field.
+type TextDiff struct {
+	Changed bool       `json:"changed"`
+	Hunks   []DiffHunk `json:"hunks"`
+}
+
+// WikiDocVersion identifies one side of a WikiDocDiff.
+type WikiDocVersion struct {
+	// Revision is the revision number, or 0 for the current state of the doc.
+	Revision int64 `json:"revision"`
+
+	// Current is true when this side is the current state of the doc.
+	Current bool `json:"current"`
+
+	// UpdateAt is when this version was written.
+	UpdateAt int64 `json:"update_at"`
+}
+
+// WikiDocDiff is the structured diff between two versions of a wikiDoc.
+type WikiDocDiff struct {
+	WikiDocID   string         `json:"wiki_doc_id"`
+	From        WikiDocVersion `json:"from"`
+	To          WikiDocVersion `json:"to"`
+	Name        TextDiff       `json:"name"`
+	Description TextDiff       `json:"description"`
+	Content     TextDiff       `json:"content"`
+}
+
+// wordPattern splits a line into words, whitespace runs and single punctuation characters.
+var wordPattern = regexp.MustCompile(`\w+|\s+|[^\w\s]`)
+
+// DiffText computes the line diff between two texts.
+func DiffText(from, to string, options DiffOptions) TextDiff {
+	if from == to {
+		return TextDiff{Hunks: []DiffHunk{}}
+	}
+
+	context := options.Context
+	if context < 0 {
+		context = DiffContextDefault
+	}
+
+	a := splitDiffLines(from)
+	b := splitDiffLines(to)
+
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+
+	hunks := []DiffHunk{}
+	for _, group := range matcher.GetGroupedOpCodes(context) {
+		first, last := group[0], group[len(group)-1]
+		hunk := DiffHunk{
+			OldStart: hunkStart(first.I1, last.I2),
+			OldLines: last.I2 - first.I1,
+			NewStart: hunkStart(first.J1, last.J2),
+			NewLines: last.J2 - first.J1,
+			Lines:    []DiffLine{},
+		}
+		hunk.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+
+		for _, op := range group {
+			switch op.Tag {
+			case 'e':
+				for i := op.I1; i < op.I2; i++ {
+					hunk.Lines = append(hunk.Lines, DiffLine{
+						Type:    DiffContext,
+						Text:    a[i],
+						OldLine: i + 1,
+						NewLine: op.J1 + i - op.I1 + 1,
+					})
+				}
+			case 'd':
+				hunk.Lines = append(hunk.Lines, removedLines(a, op.I1, op.I2)...)
+			case 'i':
+				hunk.Lines = append(hunk.Lines, addedLines(b, op.J1, op.J2)...)
+			case 'r':
+				removed := removedLines(a, op.I1, op.I2)
+				added := addedLines(b, op.J1, op.J2)
+				if options.Words {
+					for i := 0; i < len(removed) && i < len(added); i++ {
+						removed[i].Words, added[i].Words = DiffWords(removed[i].Text, added[i].Text)
+					}
+				}
+				hunk.Lines = append(hunk.Lines, removed...)
+				hunk.Lines = append(hunk.Lines, added...)
+			}
+		}
+
+		hunks = append(hunks, hunk)
+	}
+
+	return TextDiff{
+		Changed: true,
+		Hunks:   hunks,
+	}
+}
+
+// DiffWords computes the word-level changes between two versions of a line. It returns the
+// words of the old line (context and removed) and of the new line (context and added).
+func DiffWords(from, to string) (oldWords, newWords []DiffWord) {
+	a := wordPattern.FindAllString(from, -1)
+	b := wordPattern.FindAllString(to, -1)
+
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+	for _, op := range matcher.GetOpCodes() {
+		switch op.Tag {
+		case 'e':
+			text := strings.Join(a[op.I1:op.I2], "")
+			oldWords = appendDiffWord(oldWords, DiffContext, text)
+			newWords = appendDiffWord(newWords, DiffContext, text)
+		case 'd':
+			oldWords = appendDiffWord(oldWords, DiffRemoved, strings.Join(a[op.I1:op.I2], ""))
+		case 'i':
+			newWords = appendDiffWord(newWords, DiffAdded, strings.Join(b[op.J1:op.J2], ""))
+		case 'r':
+			oldWords = appendDiffWord(oldWords, DiffRemoved, strings.Join(a[op.I1:op.I2], ""))
+			newWords = appendDiffWord(newWords, DiffAdded, strings.Join(b[op.J1:op.J2], ""))
+		}
+	}
+
+	return oldWords, newWords
+}
+
+func appendDiffWord(words []DiffWord, wordType DiffLineType, text string) []DiffWord {
+	if text == "" {
+		return words
+	}
+
+	if len(words) > 0 && words[len(words)-1].Type == wordType {
+		words[len(words)-1].Text += text
+		return words
+	}
+
+	return append(words, DiffWord{Type: wordType, Text: text})
+}
+
+func removedLines(lines []string, start, end int) []DiffLine {
+	result := make([]DiffLine, 0, end-start)
+	for i := start; i < end; i++ {
+		result = append(result, DiffLine{Type: DiffRemoved, Text: lines[i], OldLine: i + 1})
+	}
+	return result
+}
+
+func addedLines(lines []string, start, end int) []DiffLine {
+	result := make([]DiffLine, 0, end-start)
+	for i := start; i < end; i++ {
+		result = append(result, DiffLine{Type: DiffAdded, Text: lines[i], NewLine: i + 1})
+	}
+	return result
+}
+
+// hunkStart returns the 1-based start line of a hunk range, following the unified diff
+// convention of pointing at the preceding line for empty ranges.
+func hunkStart(start, end int) int {
+	if start == end {
+		return start
+	}
+	return start + 1
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
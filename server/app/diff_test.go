@@ -0,0 +1,90 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffText(t *testing.T) {
+	for name, test := range map[string]struct {
+		from     string
+		to       string
+		options  DiffOptions
+		expected TextDiff
+	}{
+		"both empty": {
+			"", "", DiffOptions{}, TextDiff{Hunks: []DiffHunk{}},
+		},
+		"identical": {
+			"a\nb\n", "a\nb\n", DiffOptions{Context: -1, Words: true}, TextDiff{Hunks: []DiffHunk{}},
+		},
+		"from empty": {
+			"", "a\nb", DiffOptions{},
+			TextDiff{Changed: true, Hunks: []DiffHunk{{
+				OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2, Header: "@@ -0,0 +1,2 @@",
+				Lines: []DiffLine{
+					{Type: DiffAdded, Text: "a", NewLine: 1},
+					{Type: DiffAdded, Text: "b", NewLine: 2},
+				},
+			}}},
+		},
+		"to empty": {
+			"a\n", "", DiffOptions{},
+			TextDiff{Changed: true, Hunks: []DiffHunk{{
+				OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Header: "@@ -1,1 +0,0 @@",
+				Lines: []DiffLine{{Type: DiffRemoved, Text: "a", OldLine: 1}},
+			}}},
+		},
+		"modified line with context and words": {
+			"a\nold text\nc", "a\nnew text\nc", DiffOptions{Context: 1, Words: true},
+			TextDiff{Changed: true, Hunks: []DiffHunk{{
+				OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Header: "@@ -1,3 +1,3 @@",
+				Lines: []DiffLine{
+					{Type: DiffContext, Text: "a", OldLine: 1, NewLine: 1},
+					{Type: DiffRemoved, Text: "old text", OldLine: 2, Words: []DiffWord{
+						{Type: DiffRemoved, Text: "old"}, {Type: DiffContext, Text: " text"},
+					}},
+					{Type: DiffAdded, Text: "new text", NewLine: 2, Words: []DiffWord{
+						{Type: DiffAdded, Text: "new"}, {Type: DiffContext, Text: " text"},
+					}},
+					{Type: DiffContext, Text: "c", OldLine: 3, NewLine: 3},
+				},
+			}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, DiffText(test.from, test.to, test.options))
+		})
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	for name, test := range map[string]struct {
+		from     string
+		to       string
+		oldWords []DiffWord
+		newWords []DiffWord
+	}{
+		"both empty": {"", "", nil, nil},
+		"identical": {
+			"same, words", "same, words",
+			[]DiffWord{{Type: DiffContext, Text: "same, words"}},
+			[]DiffWord{{Type: DiffContext, Text: "same, words"}},
+		},
+		"from empty": {
+			"", "new words", nil, []DiffWord{{Type: DiffAdded, Text: "new words"}},
+		},
+		"replaced word": {
+			"the red fox", "the blue fox",
+			[]DiffWord{{Type: DiffContext, Text: "the "}, {Type: DiffRemoved, Text: "red"}, {Type: DiffContext, Text: " fox"}},
+			[]DiffWord{{Type: DiffContext, Text: "the "}, {Type: DiffAdded, Text: "blue"}, {Type: DiffContext, Text: " fox"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			oldWords, newWords := DiffWords(test.from, test.to)
+			assert.Equal(t, test.oldWords, oldWords)
+			assert.Equal(t, test.newWords, newWords)
+		})
+	}
+}
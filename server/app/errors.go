@@ -19,3 +19,36 @@ var ErrMalformedWikiDoc = errors.New("malformed")
 
 // ErrDuplicateEntry occurs when failing to insert because the entry already existed.
 var ErrDuplicateEntry = errors.New("duplicate entry")
+
+// ErrConflict occurs when a wikiDoc was modified after the version a change was based on.
+var ErrConflict = errors.New("conflict")
+
+// ErrWikiDocCycle occurs when moving a wikiDoc under itself or one of its descendants.
+var ErrWikiDocCycle = errors.New("wikiDoc cannot be moved under itself or its descendants")
+
+// ErrWikiDocHasChildren occurs when deleting a wikiDoc with children without saying what to do with them.
+var ErrWikiDocHasChildren = errors.New("wikiDoc has children")
+
+// ErrInvalidTransition occurs when moving a wikiDoc to a status its current status does not lead to.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrWikiDocInTrash occurs when deleting a wikiDoc that is already in the trash.
+var ErrWikiDocInTrash = errors.New("wikiDoc is in the trash")
+
+// ErrWikiDocNotInTrash occurs when restoring or purging a wikiDoc that is not in the trash.
+var ErrWikiDocNotInTrash = errors.New("wikiDoc is not in the trash")
+
+// ErrApprovalsRequired occurs when publishing a wikiDoc that was not approved by enough reviewers.
+var ErrApprovalsRequired = errors.New("not enough approvals")
+
+// ErrMalformedReview occurs when a review or a review request is not valid.
+var ErrMalformedReview = errors.New("malformed review")
+
+// ErrMalformedComment occurs when a comment is not valid.
+var ErrMalformedComment = errors.New("malformed comment")
+
+// ErrMalformedAttachment occurs when an attachment is not valid.
+var ErrMalformedAttachment = errors.New("malformed attachment")
+
+// ErrMalformedImport occurs when an import or one of its files is not valid.
+var ErrMalformedImport = errors.New("malformed import")
@@ -0,0 +1,234 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ExportManifestName is the name of the manifest in an export archive.
+	ExportManifestName = "manifest.json"
+
+	// ExportVersion is the version of the layout of export archives.
+	ExportVersion = 1
+
+	// exportContentTag is the export tag of the field written as the body of the Markdown files,
+	// the other export-tagged fields being written in their front matter.
+	exportContentTag = "content"
+
+	// maxExportSlugLength is the maximum number of characters of the name of an exported file.
+	maxExportSlugLength = 100
+)
+
+// ExportChannel is the wiki of a channel to export.
+type ExportChannel struct {
+	ChannelID string
+
+	// Folder is the folder of the channel in the archive, empty for its root.
+	Folder string
+
+	// Tree is the wikiDocs to export, nested under their parents. Their content is not needed.
+	Tree []*WikiDocNode
+}
+
+// ExportManifest describes the content of an export archive.
+type ExportManifest struct {
+	Version    int    `json:"version"`
+	ExportedAt int64  `json:"exported_at"`
+	TeamID     string `json:"team_id,omitempty"`
+	ChannelID  string `json:"channel_id,omitempty"`
+
+	WikiDocs []ExportManifestEntry `json:"wiki_docs"`
+}
+
+// ExportManifestEntry describes an exported wikiDoc.
+type ExportManifestEntry struct {
+	ID        string `json:"id"`
+	ParentID  string `json:"parent_id"`
+	ChannelID string `json:"channel_id"`
+
+	// Path is the path of the Markdown file of the wikiDoc in the archive. The file of a wikiDoc
+	// with children sits next to the folder of its children, of the same name.
+	Path string `json:"path"`
+
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	SortOrder int    `json:"sort_order"`
+	CreateAt  int64  `json:"create_at"`
+	UpdateAt  int64  `json:"update_at"`
+}
+
+func (s *wikiDocsService) Export(w io.Writer, manifest ExportManifest, channels []ExportChannel) error {
+	archive := zip.NewWriter(w)
+
+	manifest.Version = ExportVersion
+	manifest.ExportedAt = model.GetMillis()
+	manifest.WikiDocs = []ExportManifestEntry{}
+
+	var export func(folder string, nodes []*WikiDocNode) error
+	export = func(folder string, nodes []*WikiDocNode) error {
+		used := map[string]bool{}
+		for _, node := range nodes {
+			slug := uniqueSlug(exportSlug(node.Name), used)
+			path := folder + slug + ".md"
+
+			// The content of the wikiDocs is read one at a time, so that it is not all held in memory.
+			wikiDoc, err := s.store.Get(node.ID)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get wikiDoc '%s'", node.ID)
+			}
+			if err = writeExportFile(archive, path, wikiDoc); err != nil {
+				return err
+			}
+
+			manifest.WikiDocs = append(manifest.WikiDocs, ExportManifestEntry{
+				ID:        wikiDoc.ID,
+				ParentID:  wikiDoc.ParentID,
+				ChannelID: wikiDoc.ChannelID,
+				Path:      path,
+				Name:      wikiDoc.Name,
+				Status:    wikiDoc.Status,
+				SortOrder: wikiDoc.SortOrder,
+				CreateAt:  wikiDoc.CreateAt,
+				UpdateAt:  wikiDoc.UpdateAt,
+			})
+
+			if len(node.Children) > 0 {
+				if err = export(folder+slug+"/", node.Children); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	for _, channel := range channels {
+		folder := ""
+		if channel.Folder != "" {
+			folder = channel.Folder + "/"
+		}
+		if err := export(folder, channel.Tree); err != nil {
+			return err
+		}
+	}
+
+	file, err := archive.Create(ExportManifestName)
+	if err != nil {
+		return errors.Wrap(err, "failed to add the manifest")
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return errors.Wrap(err, "failed to write the manifest")
+	}
+
+	return errors.Wrap(archive.Close(), "failed to complete the archive")
+}
+
+// writeExportFile adds the Markdown file of a wikiDoc to the archive.
+func writeExportFile(archive *zip.Writer, path string, wikiDoc WikiDoc) error {
+	frontMatter, body, err := ExportMarkdown(wikiDoc)
+	if err != nil {
+		return err
+	}
+
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     path,
+		Method:   zip.Deflate,
+		Modified: time.UnixMilli(wikiDoc.UpdateAt),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to add '%s'", path)
+	}
+
+	if _, err = fmt.Fprintf(file, "---\n%s---\n\n%s", frontMatter, body); err != nil {
+		return errors.Wrapf(err, "failed to write '%s'", path)
+	}
+
+	return nil
+}
+
+// ExportMarkdown returns the YAML front matter of a wikiDoc, made of the fields with an export tag
+// but its content, and the body of its Markdown file, its content.
+func ExportMarkdown(wikiDoc WikiDoc) ([]byte, string, error) {
+	frontMatter := &yaml.Node{Kind: yaml.MappingNode}
+	body := ""
+
+	value := reflect.ValueOf(wikiDoc)
+	for i := 0; i < value.NumField(); i++ {
+		tag := value.Type().Field(i).Tag.Get("export")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		text := fmt.Sprint(value.Field(i).Interface())
+		if tag == exportContentTag {
+			body = text
+			continue
+		}
+
+		frontMatter.Content = append(frontMatter.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: tag},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: text},
+		)
+	}
+
+	out, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to write the front matter of wikiDoc '%s'", wikiDoc.ID)
+	}
+
+	return out, body, nil
+}
+
+// exportSlug returns the name of the file of a wikiDoc: its name, lowercased, with dashes instead
+// of the characters that are not letters or digits.
+func exportSlug(name string) string {
+	var slug strings.Builder
+	dash := false
+	count := 0
+	for _, r := range strings.ToLower(name) {
+		if count == maxExportSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteRune('-')
+			dash = true
+		} else {
+			continue
+		}
+		count++
+	}
+
+	result := strings.TrimRight(slug.String(), "-")
+	if result == "" {
+		return "untitled"
+	}
+
+	return result
+}
+
+// uniqueSlug returns the slug, suffixed with a number if it is already used, and marks it as used.
+func uniqueSlug(slug string, used map[string]bool) string {
+	unique := slug
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", slug, i)
+	}
+	used[unique] = true
+
+	return unique
+}
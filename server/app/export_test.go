@@ -0,0 +1,99 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	wikiDoc := WikiDoc{
+		ID:          "doc",
+		Name:        "Release: 2.0",
+		Description: "yes",
+		Status:      "published",
+		Content:     "# Notes\n\n---\n\nDone.",
+		OwnerUserID: "owner",
+	}
+
+	frontMatter, body, err := ExportMarkdown(wikiDoc)
+	require.NoError(t, err)
+	assert.Equal(t, wikiDoc.Content, body)
+
+	fields := map[string]string{}
+	require.NoError(t, yaml.Unmarshal(frontMatter, &fields))
+	assert.Equal(t, map[string]string{
+		"name":        "Release: 2.0",
+		"description": "yes",
+		"status":      "published",
+	}, fields)
+}
+
+func TestExportSlug(t *testing.T) {
+	assert.Equal(t, "release-notes-2-0", exportSlug("  Release notes: 2.0!"))
+	assert.Equal(t, "café", exportSlug("Café"))
+	assert.Equal(t, "untitled", exportSlug("?!"))
+	assert.Len(t, exportSlug(strings.Repeat("a", 150)), maxExportSlugLength)
+
+	used := map[string]bool{}
+	assert.Equal(t, "faq", uniqueSlug("faq", used))
+	assert.Equal(t, "faq-2", uniqueSlug("faq", used))
+	assert.Equal(t, "faq-3", uniqueSlug("faq", used))
+}
+
+func TestExport(t *testing.T) {
+	store := newFakeWikiDocStore(
+		WikiDoc{ID: "home", Name: "Home", ChannelID: "c1", Content: "Welcome"},
+		WikiDoc{ID: "guide", Name: "Guide", ChannelID: "c1", ParentID: "home", Content: "Steps"},
+		WikiDoc{ID: "home2", Name: "home", ChannelID: "c1", Content: "Again"},
+		WikiDoc{ID: "faq", Name: "FAQ", ChannelID: "c2", Content: "Answers"},
+	)
+	s := &wikiDocsService{store: store}
+
+	var out bytes.Buffer
+	err := s.Export(&out, ExportManifest{TeamID: "team"}, []ExportChannel{
+		{ChannelID: "c1", Folder: "town-square", Tree: []*WikiDocNode{
+			{WikiDoc: WikiDoc{ID: "home", Name: "Home"}, Children: []*WikiDocNode{
+				{WikiDoc: WikiDoc{ID: "guide", Name: "Guide"}},
+			}},
+			{WikiDoc: WikiDoc{ID: "home2", Name: "home"}},
+		}},
+		{ChannelID: "c2", Folder: "support", Tree: []*WikiDocNode{
+			{WikiDoc: WikiDoc{ID: "faq", Name: "FAQ"}},
+		}},
+	})
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+
+	require.Len(t, files, 5)
+	assert.Equal(t, "---\nname: Guide\ndescription: \"\"\nstatus: \"\"\n---\n\nSteps", files["town-square/home/guide.md"])
+	assert.Contains(t, files["town-square/home.md"], "Welcome")
+	assert.Contains(t, files["town-square/home-2.md"], "Again")
+	assert.Contains(t, files["support/faq.md"], "Answers")
+
+	var manifest ExportManifest
+	require.NoError(t, json.Unmarshal([]byte(files[ExportManifestName]), &manifest))
+	assert.Equal(t, ExportVersion, manifest.Version)
+	assert.Equal(t, "team", manifest.TeamID)
+	require.Len(t, manifest.WikiDocs, 4)
+	assert.Equal(t, "town-square/home/guide.md", manifest.WikiDocs[1].Path)
+	assert.Equal(t, "home", manifest.WikiDocs[1].ParentID)
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// GroupCacheTTL is how long the groups of a user are cached. Plugins are not told when the members
+// of a group change, so entries expire; the hooks called when group sync adds or removes a user
+// from a team or channel invalidate them sooner, see InvalidateUserGroups.
+const GroupCacheTTL = 5 * time.Minute
+
+// groupCache caches the IDs of the groups users are members of.
+type groupCache struct {
+	mu      sync.Mutex
+	entries map[string]groupCacheEntry
+	ttl     time.Duration
+
+	// load retrieves the IDs of the groups of a user on a cache miss.
+	load func(userID string) ([]string, error)
+
+	now func() time.Time
+}
+
+type groupCacheEntry struct {
+	groupIDs []string
+	expireAt time.Time
+}
+
+func newGroupCache(ttl time.Duration, load func(userID string) ([]string, error)) *groupCache {
+	return &groupCache{
+		entries: map[string]groupCacheEntry{},
+		ttl:     ttl,
+		load:    load,
+		now:     time.Now,
+	}
+}
+
+// get returns the IDs of the groups of a user, loading them if they are not cached or expired.
+func (c *groupCache) get(userID string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expireAt) {
+		return entry.groupIDs, nil
+	}
+
+	groupIDs, err := c.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[userID] = groupCacheEntry{groupIDs: groupIDs, expireAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return groupIDs, nil
+}
+
+// invalidate forgets the groups of a user, or of every user when userID is empty.
+func (c *groupCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if userID == "" {
+		c.entries = map[string]groupCacheEntry{}
+		return
+	}
+
+	delete(c.entries, userID)
+}
@@ -0,0 +1,46 @@
+package app
+
+// GroupGrant is a role granted to the members of a Mattermost user group, either on a single wikiDoc
+// or on every wikiDoc of a channel wiki that is not restricted to its members.
+type GroupGrant struct {
+	GroupID   string `json:"group_id"`
+	ChannelID string `json:"channel_id"`
+
+	// WikiDocID is empty when the role is granted on the whole channel wiki.
+	WikiDocID string      `json:"wiki_doc_id"`
+	Role      WikiDocRole `json:"role"`
+	CreateAt  int64       `json:"create_at"`
+	UpdateAt  int64       `json:"update_at"`
+}
+
+// ChannelRoles are the roles a user has on the wikiDocs of a channel, either directly or through the
+// groups they are members of.
+type ChannelRoles struct {
+	// Channel is the role granted on every wikiDoc of the channel that is not restricted.
+	Channel WikiDocRole
+
+	// WikiDocs are the roles granted on single wikiDocs, by wikiDoc ID.
+	WikiDocs map[string]WikiDocRole
+}
+
+// RoleOn returns the highest role the user has on a wikiDoc of the channel, or on the channel wiki
+// when the wikiDoc has no ID.
+func (r ChannelRoles) RoleOn(wikiDoc WikiDoc) WikiDocRole {
+	var role WikiDocRole
+	if wikiDoc.ID != "" {
+		role = r.WikiDocs[wikiDoc.ID]
+	}
+	if !wikiDoc.Restricted {
+		role = maxRole(role, r.Channel)
+	}
+
+	return role
+}
+
+// maxRole returns the role granting the most rights.
+func maxRole(a, b WikiDocRole) WikiDocRole {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
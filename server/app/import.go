@@ -0,0 +1,638 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ConflictPolicy enumerates what happens to an imported document named like an existing wikiDoc.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves the existing wikiDoc as it is and does not import the document.
+	ConflictSkip ConflictPolicy = "skip"
+
+	// ConflictOverwrite replaces the description and content of the existing wikiDoc, and its
+	// status if the document sets one.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+
+	// ConflictRename imports the document as a new wikiDoc, with a number appended to its name.
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// ImportActionCreated and the other actions are the outcomes of the import of a document.
+
+const (
+	ImportActionCreated     = "created"
+	ImportActionOverwritten = "overwritten"
+	ImportActionRenamed     = "renamed"
+	ImportActionSkipped     = "skipped"
+	ImportActionFailed      = "failed"
+)
+
+const (
+	// maxImportFiles is the maximum number of files of an imported archive.
+	maxImportFiles = 5000
+
+	// maxImportFileSize is the maximum size of an imported Markdown file, once uncompressed.
+	maxImportFileSize = 10 * 1024 * 1024
+)
+
+// importBudget is the number of bytes an import may still decompress. Archives declare the size of
+// their files, but the bytes read are counted too, since the declared sizes can lie.
+type importBudget struct {
+	size      int64
+	remaining int64
+}
+
+func newImportBudget(size int64) *importBudget {
+	return &importBudget{size: size, remaining: size}
+}
+
+// err is the error of the imports larger than the budget.
+func (b *importBudget) err() error {
+	return errors.Wrapf(ErrMalformedImport, "the import is larger than %d bytes once uncompressed", b.size)
+}
+
+// exhausted returns true once more bytes were read than the budget allows.
+func (b *importBudget) exhausted() bool {
+	return b.remaining < 0
+}
+
+// reader returns a reader of r that fails once the budget is exhausted.
+func (b *importBudget) reader(r io.Reader) io.Reader {
+	return &budgetReader{reader: r, budget: b}
+}
+
+type budgetReader struct {
+	reader io.Reader
+	budget *importBudget
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if r.budget.exhausted() {
+		return 0, r.budget.err()
+	}
+	// Reading one byte past the budget tells a file that fits from one that does not.
+	if int64(len(p)) > r.budget.remaining+1 {
+		p = p[:r.budget.remaining+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.budget.remaining -= int64(n)
+	if r.budget.exhausted() {
+		return n, r.budget.err()
+	}
+	return n, err
+}
+
+// ImportDoc is a document to import as a wikiDoc.
+type ImportDoc struct {
+	// Path identifies the document in the import, as the path of its file in an archive.
+	Path string
+
+	// ParentPath is the Path of the document to import it under, empty for a top-level wikiDoc.
+	ParentPath string
+
+	// WikiDoc holds the name, description, status and content of the document. The name is
+	// required, the status defaults to StatusDraft. Its CreateAt and UpdateAt, if set, are the
+	// original dates of the document.
+	WikiDoc WikiDoc
+
+	// Revisions are the earlier versions of the document, oldest first: their name, description,
+	// content and UpdateAt. They become the first revisions of the wikiDoc created, its last one
+	// being WikiDoc. They are ignored when overwriting a wikiDoc.
+	Revisions []WikiDoc
+
+	// Attachments are the files to attach to the wikiDoc. The content refers to them with
+	// ImportAttachmentURL, replaced by their URL once attached.
+	Attachments []ImportAttachment
+
+	// Err is the reason the document cannot be imported, if any. It is reported as failed.
+	Err error
+}
+
+// ImportAttachment is a file to attach to an imported wikiDoc.
+type ImportAttachment struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// ImportAttachmentURL returns the reference to an attachment of an imported document in its
+// content.
+func ImportAttachmentURL(name string) string {
+	return "attachment:" + url.PathEscape(name)
+}
+
+// ImportOptions controls how documents are imported.
+type ImportOptions struct {
+	ChannelID string
+	TeamID    string
+
+	// UserID is the user importing the documents, the owner of the wikiDocs created.
+	UserID string
+
+	// DryRun reports what would be imported, without changing anything.
+	DryRun bool
+
+	// Conflict is what to do with a document whose name is the name of a wikiDoc of the channel:
+	// ConflictSkip, the default, ConflictOverwrite or ConflictRename.
+	Conflict ConflictPolicy
+
+	// CanEdit checks that the user can overwrite an existing wikiDoc. All wikiDocs can be
+	// overwritten if it is nil.
+	CanEdit func(wikiDoc WikiDoc) error
+}
+
+// ImportResult is the outcome of the import of a document.
+type ImportResult struct {
+	Path      string `json:"path"`
+	Name      string `json:"name,omitempty"`
+	Action    string `json:"action"`
+	WikiDocID string `json:"wiki_doc_id,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// ImportReport is the outcome of an import, with a result per document.
+type ImportReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Conflict ConflictPolicy `json:"conflict"`
+	Counts   map[string]int `json:"counts"`
+	Results  []ImportResult `json:"results"`
+}
+
+// ValidConflictPolicy returns true if the policy is known, or blank.
+func ValidConflictPolicy(policy ConflictPolicy) bool {
+	return policy == "" || policy == ConflictSkip || policy == ConflictOverwrite || policy == ConflictRename
+}
+
+func (s *wikiDocsService) Import(docs []ImportDoc, options ImportOptions) (*ImportReport, error) {
+	if !ValidConflictPolicy(options.Conflict) {
+		return nil, errors.Wrapf(ErrMalformedImport, "unknown conflict policy '%s'", options.Conflict)
+	}
+	if options.Conflict == "" {
+		options.Conflict = ConflictSkip
+	}
+
+	existing, err := s.store.GetWikiDocsForChannel(options.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]WikiDoc, len(existing))
+	for _, wikiDoc := range existing {
+		byName[importNameKey(wikiDoc.Name)] = wikiDoc
+	}
+
+	report := &ImportReport{
+		DryRun:   options.DryRun,
+		Conflict: options.Conflict,
+		Counts:   map[string]int{},
+		Results:  make([]ImportResult, 0, len(docs)),
+	}
+
+	// The IDs of the wikiDocs the documents were imported as, or would be on a dry run, by path.
+	imported := map[string]string{}
+	for _, doc := range docs {
+		result := s.importDoc(doc, options, byName, imported)
+		report.Counts[result.Action]++
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// importDoc imports a document, or checks that it could be on a dry run, and records the wikiDoc
+// it was imported as.
+func (s *wikiDocsService) importDoc(doc ImportDoc, options ImportOptions, byName map[string]WikiDoc, imported map[string]string) ImportResult {
+	result := ImportResult{Path: doc.Path, Name: strings.TrimSpace(doc.WikiDoc.Name)}
+	fail := func(err error) ImportResult {
+		result.Action = ImportActionFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	if doc.Err != nil {
+		return fail(doc.Err)
+	}
+	if result.Name == "" {
+		return fail(errors.Wrap(ErrMalformedWikiDoc, "missing name"))
+	}
+	if !ValidStatus(doc.WikiDoc.Status) {
+		return fail(errors.Wrapf(ErrMalformedWikiDoc, "invalid status '%s'", doc.WikiDoc.Status))
+	}
+
+	wikiDoc := WikiDoc{
+		Name:        result.Name,
+		Description: doc.WikiDoc.Description,
+		Content:     doc.WikiDoc.Content,
+		Status:      doc.WikiDoc.Status,
+		OwnerUserID: options.UserID,
+		TeamID:      options.TeamID,
+		ChannelID:   options.ChannelID,
+		ParentID:    imported[doc.ParentPath],
+		CreateAt:    doc.WikiDoc.CreateAt,
+		UpdateAt:    doc.WikiDoc.UpdateAt,
+	}
+
+	if conflicting, ok := byName[importNameKey(wikiDoc.Name)]; ok {
+		switch options.Conflict {
+		case ConflictSkip:
+			imported[doc.Path] = conflicting.ID
+			result.Action = ImportActionSkipped
+			result.WikiDocID = conflicting.ID
+			result.Message = "a wikiDoc with this name already exists"
+			return result
+
+		case ConflictOverwrite:
+			updated, err := s.overwrite(conflicting.ID, wikiDoc, doc.Attachments, options)
+			if err != nil {
+				return fail(err)
+			}
+			imported[doc.Path] = updated.ID
+			byName[importNameKey(updated.Name)] = updated
+			result.Action = ImportActionOverwritten
+			result.WikiDocID = updated.ID
+			return result
+
+		case ConflictRename:
+			wikiDoc.Name = uniqueImportName(wikiDoc.Name, byName)
+			result.Name = wikiDoc.Name
+			result.Action = ImportActionRenamed
+		}
+	}
+	if result.Action == "" {
+		result.Action = ImportActionCreated
+	}
+
+	if err := checkCreatableStatus(wikiDoc.Status); err != nil {
+		return fail(err)
+	}
+
+	if doc.ParentPath != "" && wikiDoc.ParentID == "" {
+		result.Message = "its parent was not imported: imported at the top level"
+	}
+
+	if options.DryRun {
+		if NormalizeStatus(wikiDoc.Status) == StatusPublished {
+			if err := s.checkApprovals(wikiDoc, false); err != nil {
+				return fail(err)
+			}
+		}
+		wikiDoc.ID = "dry-run:" + doc.Path
+	} else {
+		newID, err := s.createImported(wikiDoc, doc, options.UserID)
+		if err != nil {
+			return fail(err)
+		}
+		wikiDoc.ID = newID
+		result.WikiDocID = newID
+	}
+
+	imported[doc.Path] = wikiDoc.ID
+	byName[importNameKey(wikiDoc.Name)] = wikiDoc
+
+	return result
+}
+
+// createImported creates the wikiDoc of a document, with its revisions and attachments, in a single
+// transaction. It is neither announced nor notified to the users it mentions.
+func (s *wikiDocsService) createImported(wikiDoc WikiDoc, doc ImportDoc, userID string) (string, error) {
+	// The ID is chosen beforehand for the URLs of the attachments.
+	wikiDoc.ID = model.NewId()
+	attachments, references, err := s.uploadImportAttachments(wikiDoc, doc.Attachments, userID)
+	if err != nil {
+		return "", err
+	}
+
+	versions := make([]WikiDoc, 0, len(doc.Revisions)+1)
+	for _, revision := range doc.Revisions {
+		version := wikiDoc
+		version.Name = strings.TrimSpace(revision.Name)
+		if version.Name == "" {
+			version.Name = wikiDoc.Name
+		}
+		version.Description = revision.Description
+		version.Content = revision.Content
+		version.UpdateAt = revision.UpdateAt
+		versions = append(versions, version)
+	}
+	versions = append(versions, wikiDoc)
+	for i := range versions {
+		versions[i].Content = references.Replace(versions[i].Content)
+	}
+
+	first := versions[0]
+	if first.CreateAt == 0 || (first.UpdateAt != 0 && first.UpdateAt < first.CreateAt) {
+		first.CreateAt = first.UpdateAt
+	}
+	if first.CreateAt == 0 {
+		first.CreateAt = model.GetMillis()
+	}
+	if first.UpdateAt < first.CreateAt {
+		first.UpdateAt = first.CreateAt
+	}
+	if first, err = s.checkCreatable(first); err != nil {
+		return "", err
+	}
+
+	// The later versions are recorded as revisions, at their original dates when known.
+	revisions := make([]WikiDoc, 0, len(versions)-1)
+	previous := first
+	for _, version := range versions[1:] {
+		updateAt := version.UpdateAt
+		if updateAt == 0 {
+			updateAt = model.GetMillis()
+		}
+		version.CreateAt = previous.CreateAt
+		version.UpdateAt = nextUpdateAt(previous.UpdateAt, updateAt)
+		revisions = append(revisions, version)
+		previous = version
+	}
+
+	return s.storeCreated(first, revisions, attachments, true)
+}
+
+// uploadImportAttachments stores the files of the attachments of an imported document in the
+// channel of its wikiDoc, and returns the attachments, not stored yet, along with the replacer of
+// their references in the content by their URL.
+func (s *wikiDocsService) uploadImportAttachments(wikiDoc WikiDoc, files []ImportAttachment, userID string) ([]Attachment, *strings.Replacer, error) {
+	attachments := make([]Attachment, 0, len(files))
+	references := make([]string, 0, 2*len(files))
+	for _, file := range files {
+		attachment, err := s.uploadImportAttachment(wikiDoc, file, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		attachments = append(attachments, attachment)
+		references = append(references, ImportAttachmentURL(file.Name), AttachmentPath(wikiDoc.ID, attachment.ID))
+	}
+
+	return attachments, strings.NewReplacer(references...), nil
+}
+
+func (s *wikiDocsService) uploadImportAttachment(wikiDoc WikiDoc, file ImportAttachment, userID string) (Attachment, error) {
+	content, err := file.Open()
+	if err != nil {
+		return Attachment{}, errors.Wrapf(err, "failed to read attachment '%s'", file.Name)
+	}
+	defer content.Close()
+
+	info, err := s.api.File.Upload(content, file.Name, wikiDoc.ChannelID)
+	if err != nil {
+		return Attachment{}, errors.Wrapf(err, "failed to upload attachment '%s'", file.Name)
+	}
+
+	return Attachment{
+		ID:        model.NewId(),
+		WikiDocID: wikiDoc.ID,
+		FileID:    info.Id,
+		Name:      info.Name,
+		MimeType:  info.MimeType,
+		Size:      info.Size,
+		UserID:    userID,
+		CreateAt:  model.GetMillis(),
+	}, nil
+}
+
+// storeImportAttachments attaches the uploaded files to their wikiDoc.
+func (s *wikiDocsService) storeImportAttachments(wikiDocID string, attachments []Attachment) error {
+	for _, attachment := range attachments {
+		if err := s.attachmentStore.CreateAttachment(attachment); err != nil {
+			return errors.Wrapf(err, "failed to store the attachments of wikiDoc '%s'", wikiDocID)
+		}
+	}
+
+	return nil
+}
+
+// overwrite replaces the description and content of an existing wikiDoc with those of an imported
+// one, and its status if the imported one has a status, and attaches the files of the imported one.
+// On a dry run, it only checks that it could.
+func (s *wikiDocsService) overwrite(id string, imported WikiDoc, files []ImportAttachment, options ImportOptions) (WikiDoc, error) {
+	wikiDoc, err := s.store.Get(id)
+	if err != nil {
+		return WikiDoc{}, err
+	}
+	if options.CanEdit != nil {
+		if err = options.CanEdit(wikiDoc); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	previous := wikiDoc
+	wikiDoc.Name = imported.Name
+	wikiDoc.Description = imported.Description
+	wikiDoc.Content = imported.Content
+	if imported.Status != "" {
+		wikiDoc.Status = NormalizeStatus(imported.Status)
+	}
+
+	if !options.DryRun {
+		attachments, references, err := s.uploadImportAttachments(wikiDoc, files, options.UserID)
+		if err != nil {
+			return WikiDoc{}, err
+		}
+		wikiDoc.Content = references.Replace(wikiDoc.Content)
+
+		if wikiDoc, err = s.Update(wikiDoc, options.UserID); err != nil {
+			return WikiDoc{}, err
+		}
+
+		return wikiDoc, s.storeImportAttachments(wikiDoc.ID, attachments)
+	}
+
+	if err = checkTransition(previous.Status, wikiDoc.Status); err != nil {
+		return WikiDoc{}, err
+	}
+	if wikiDoc.Status == StatusPublished && NormalizeStatus(previous.Status) != StatusPublished {
+		if err = s.checkApprovals(wikiDoc, reviewedFieldsChanged(previous, wikiDoc)); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	return wikiDoc, nil
+}
+
+// importNameKey is the key of a name when looking for conflicts: names differing only by case
+// conflict.
+func importNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// uniqueImportName returns the name, with the first number from 2 that makes it unique appended.
+func uniqueImportName(name string, byName map[string]WikiDoc) string {
+	for i := 2; ; i++ {
+		unique := fmt.Sprintf("%s (%d)", name, i)
+		if _, ok := byName[importNameKey(unique)]; !ok {
+			return unique
+		}
+	}
+}
+
+// ReadMarkdownArchive returns the documents of a zip archive of Markdown files, parents first. The
+// file of a document with children sits next to the folder of its children, of the same name, as
+// in the archives of Export. The other files are reported as failed, but for the manifest of
+// exports. Returns ErrMalformedImport if the Markdown files add up to more than maxSize bytes once
+// uncompressed.
+func ReadMarkdownArchive(archive *zip.Reader, maxSize int64) ([]ImportDoc, error) {
+	if len(archive.File) > maxImportFiles {
+		return nil, errors.Wrapf(ErrMalformedImport, "the archive has more than %d files", maxImportFiles)
+	}
+
+	budget := newImportBudget(maxSize)
+	var declared uint64
+	for _, file := range archive.File {
+		if strings.EqualFold(path.Ext(file.Name), ".md") {
+			declared += file.UncompressedSize64
+		}
+	}
+	if declared > uint64(maxSize) {
+		return nil, budget.err()
+	}
+
+	docs := []ImportDoc{}
+	for _, file := range archive.File {
+		name := strings.ReplaceAll(file.Name, "\\", "/")
+		if file.FileInfo().IsDir() || name == ExportManifestName || isIgnoredArchivePath(name) {
+			continue
+		}
+
+		doc := ImportDoc{Path: path.Clean(name)}
+		switch {
+		case path.IsAbs(name) || doc.Path == ".." || strings.HasPrefix(doc.Path, "../"):
+			doc.Err = errors.Wrap(ErrMalformedImport, "invalid path")
+		case !strings.EqualFold(path.Ext(doc.Path), ".md"):
+			doc.Err = errors.Wrap(ErrMalformedImport, "not a Markdown file")
+		case file.UncompressedSize64 > maxImportFileSize:
+			doc.Err = errors.Wrapf(ErrMalformedImport, "larger than %d bytes", maxImportFileSize)
+		default:
+			doc.WikiDoc, doc.Err = readMarkdownFile(file, budget)
+			if budget.exhausted() {
+				return nil, budget.err()
+			}
+		}
+
+		if dir := path.Dir(doc.Path); dir != "." {
+			doc.ParentPath = dir + ".md"
+		}
+		if doc.WikiDoc.Name == "" && doc.Err == nil {
+			doc.WikiDoc.Name = strings.TrimSuffix(path.Base(doc.Path), path.Ext(doc.Path))
+		}
+
+		docs = append(docs, doc)
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		depthI, depthJ := strings.Count(docs[i].Path, "/"), strings.Count(docs[j].Path, "/")
+		if depthI != depthJ {
+			return depthI < depthJ
+		}
+		return docs[i].Path < docs[j].Path
+	})
+
+	return docs, nil
+}
+
+// isIgnoredArchivePath returns true for the files added to archives by operating systems.
+func isIgnoredArchivePath(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store"
+}
+
+// readMarkdownFile reads a Markdown file of an archive, within the budget of the import.
+func readMarkdownFile(file *zip.File, budget *importBudget) (WikiDoc, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return WikiDoc{}, errors.Wrap(err, "failed to open the file")
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(budget.reader(io.LimitReader(reader, maxImportFileSize+1)))
+	if err != nil {
+		return WikiDoc{}, errors.Wrap(err, "failed to read the file")
+	}
+	if len(data) > maxImportFileSize {
+		return WikiDoc{}, errors.Wrapf(ErrMalformedImport, "larger than %d bytes", maxImportFileSize)
+	}
+
+	return ImportMarkdown(data)
+}
+
+// ImportMarkdown returns the wikiDoc of a Markdown file: the fields with an export tag are set from
+// its YAML front matter, if any, and its content is the body of the file. This is the reverse of
+// ExportMarkdown. Unknown fields of the front matter are ignored.
+func ImportMarkdown(data []byte) (WikiDoc, error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\ufeff"))), "\r\n", "\n")
+
+	var wikiDoc WikiDoc
+	frontMatter, body, found := splitFrontMatter(text)
+	if !found {
+		wikiDoc.Content = text
+		return wikiDoc, nil
+	}
+
+	fields := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(frontMatter), &fields); err != nil {
+		return WikiDoc{}, errors.Wrapf(ErrMalformedImport, "invalid front matter: %v", err)
+	}
+
+	value := reflect.ValueOf(&wikiDoc).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		tag := value.Type().Field(i).Tag.Get("export")
+		if tag == "" || tag == "-" || tag == exportContentTag || value.Field(i).Kind() != reflect.String {
+			continue
+		}
+
+		field, ok := fields[tag]
+		if !ok || field == nil {
+			continue
+		}
+		switch field.(type) {
+		case map[string]interface{}, []interface{}:
+			return WikiDoc{}, errors.Wrapf(ErrMalformedImport, "front matter field '%s' must be text", tag)
+		}
+		value.Field(i).SetString(fmt.Sprint(field))
+	}
+	wikiDoc.Content = body
+
+	return wikiDoc, nil
+}
+
+// splitFrontMatter returns the YAML front matter of a Markdown file, between its "---" lines, and
+// the body that follows, without the blank line that separates them.
+func splitFrontMatter(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, false
+	}
+	rest := text[len("---\n"):]
+
+	var frontMatter, body string
+	if strings.HasPrefix(rest, "---\n") || rest == "---" {
+		body = strings.TrimPrefix(rest, "---")
+	} else {
+		end := strings.Index(rest, "\n---\n")
+		if end < 0 {
+			if !strings.HasSuffix(rest, "\n---") {
+				return "", text, false
+			}
+			end = len(rest) - len("\n---")
+		}
+		frontMatter = rest[:end+1]
+		body = rest[end+len("\n---"):]
+	}
+
+	body = strings.TrimPrefix(body, "\n")
+	body = strings.TrimPrefix(body, "\n")
+
+	return frontMatter, body, true
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportMarkdown(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		wikiDoc := WikiDoc{Name: "Release: 2.0", Description: "true", Status: StatusInReview, Content: "# Notes\n\n---\n\nDone."}
+		frontMatter, body, err := ExportMarkdown(wikiDoc)
+		require.NoError(t, err)
+
+		imported, err := ImportMarkdown([]byte("---\n" + string(frontMatter) + "---\n\n" + body))
+		require.NoError(t, err)
+		assert.Equal(t, wikiDoc, imported)
+	})
+
+	t.Run("without front matter", func(t *testing.T) {
+		imported, err := ImportMarkdown([]byte("# Title\r\n\r\nText"))
+		require.NoError(t, err)
+		assert.Equal(t, WikiDoc{Content: "# Title\n\nText"}, imported)
+	})
+
+	t.Run("unknown and typed fields", func(t *testing.T) {
+		imported, err := ImportMarkdown([]byte("\ufeff---\ntitle: Ignored\nname: 42\n---\nText"))
+		require.NoError(t, err)
+		assert.Equal(t, WikiDoc{Name: "42", Content: "Text"}, imported)
+	})
+
+	t.Run("invalid front matter", func(t *testing.T) {
+		_, err := ImportMarkdown([]byte("---\nname: [a, b]\n---\nText"))
+		assert.ErrorIs(t, err, ErrMalformedImport)
+
+		_, err = ImportMarkdown([]byte("---\nname: 'open\n---\nText"))
+		assert.ErrorIs(t, err, ErrMalformedImport)
+	})
+}
+
+func TestReadMarkdownArchive(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range map[string]string{
+		"guide/install.md":    "---\nname: Installation\n---\n\nSteps",
+		"guide.md":            "Guide",
+		"notes/loose.md":      "Loose",
+		"logo.png":            "PNG",
+		ExportManifestName:    "{}",
+		"__MACOSX/._guide.md": "",
+	} {
+		file, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = file.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+
+	docs, err := ReadMarkdownArchive(archive, 1024)
+	require.NoError(t, err)
+	require.Len(t, docs, 4)
+
+	assert.Equal(t, "guide.md", docs[0].Path)
+	assert.Equal(t, "guide", docs[0].WikiDoc.Name)
+	assert.Equal(t, "Guide", docs[0].WikiDoc.Content)
+
+	assert.Equal(t, "logo.png", docs[1].Path)
+	assert.ErrorIs(t, docs[1].Err, ErrMalformedImport)
+
+	assert.Equal(t, "guide/install.md", docs[2].Path)
+	assert.Equal(t, "guide.md", docs[2].ParentPath)
+	assert.Equal(t, "Installation", docs[2].WikiDoc.Name)
+
+	assert.Equal(t, "notes/loose.md", docs[3].Path)
+	assert.Equal(t, "notes.md", docs[3].ParentPath)
+
+	t.Run("larger than the budget", func(t *testing.T) {
+		_, err := ReadMarkdownArchive(archive, 20)
+		assert.ErrorIs(t, err, ErrMalformedImport)
+	})
+}
+
+func TestImportBudget(t *testing.T) {
+	budget := newImportBudget(10)
+
+	data, err := io.ReadAll(budget.reader(strings.NewReader("12345")))
+	require.NoError(t, err)
+	assert.Equal(t, "12345", string(data))
+
+	data, err = io.ReadAll(budget.reader(strings.NewReader("67890")))
+	require.NoError(t, err, "a read up to the budget")
+	assert.Equal(t, "67890", string(data))
+	assert.False(t, budget.exhausted())
+
+	_, err = io.ReadAll(budget.reader(strings.NewReader("x")))
+	assert.ErrorIs(t, err, ErrMalformedImport)
+	assert.True(t, budget.exhausted())
+}
+
+func TestImportDryRun(t *testing.T) {
+	existing := WikiDoc{ID: "existing", Name: "Guide", ChannelID: "channel", Status: StatusPublished}
+	docs := []ImportDoc{
+		{Path: "guide.md", WikiDoc: WikiDoc{Name: "guide", Content: "New"}},
+		{Path: "guide/install.md", ParentPath: "guide.md", WikiDoc: WikiDoc{Name: "Install"}},
+		{Path: "orphan/page.md", ParentPath: "orphan.md", WikiDoc: WikiDoc{Name: "Page"}},
+		{Path: "bad.md", WikiDoc: WikiDoc{Name: "Bad", Status: "Unknown"}},
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		s := &wikiDocsService{store: newFakeWikiDocStore(existing)}
+		report, err := s.Import(docs, ImportOptions{ChannelID: "channel", DryRun: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, ConflictSkip, report.Conflict)
+		assert.Equal(t, map[string]int{ImportActionSkipped: 1, ImportActionCreated: 2, ImportActionFailed: 1}, report.Counts)
+		require.Len(t, report.Results, 4)
+		assert.Equal(t, "existing", report.Results[0].WikiDocID)
+		assert.Empty(t, report.Results[1].Message)
+		assert.NotEmpty(t, report.Results[2].Message)
+		assert.Equal(t, ImportActionFailed, report.Results[3].Action)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		s := &wikiDocsService{store: newFakeWikiDocStore(existing)}
+		report, err := s.Import(docs[:2], ImportOptions{ChannelID: "channel", DryRun: true, Conflict: ConflictRename})
+		require.NoError(t, err)
+
+		assert.Equal(t, ImportActionRenamed, report.Results[0].Action)
+		assert.Equal(t, "guide (2)", report.Results[0].Name)
+		assert.Equal(t, ImportActionCreated, report.Results[1].Action)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		s := &wikiDocsService{store: newFakeWikiDocStore(existing)}
+		denied := WikiDoc{}
+		report, err := s.Import(docs[:1], ImportOptions{
+			ChannelID: "channel",
+			DryRun:    true,
+			Conflict:  ConflictOverwrite,
+			CanEdit: func(wikiDoc WikiDoc) error {
+				denied = wikiDoc
+				return ErrNoPermissions
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "existing", denied.ID)
+		assert.Equal(t, ImportActionFailed, report.Results[0].Action)
+
+		report, err = s.Import(docs[:1], ImportOptions{ChannelID: "channel", DryRun: true, Conflict: ConflictOverwrite})
+		require.NoError(t, err)
+		assert.Equal(t, ImportActionOverwritten, report.Results[0].Action)
+		assert.Equal(t, "existing", report.Results[0].WikiDocID)
+	})
+
+	t.Run("unknown conflict policy", func(t *testing.T) {
+		s := &wikiDocsService{store: newFakeWikiDocStore()}
+		_, err := s.Import(docs, ImportOptions{ChannelID: "channel", Conflict: "merge"})
+		assert.ErrorIs(t, err, ErrMalformedImport)
+	})
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"github.com/pkg/errors"
+)
+
+// statusTransitions lists the statuses each status can move to.
+var statusTransitions = map[string][]string{
+	StatusDraft:      {StatusInReview, StatusPublished, StatusArchived},
+	StatusInReview:   {StatusDraft, StatusPublished},
+	StatusPublished:  {StatusDraft, StatusDeprecated, StatusArchived},
+	StatusDeprecated: {StatusPublished, StatusArchived},
+	StatusArchived:   {StatusDraft},
+}
+
+// creatableStatuses are the statuses a wikiDoc can be created with.
+var creatableStatuses = []string{StatusDraft, StatusPublished}
+
+// PublicStatuses returns the statuses of the wikiDocs guests can read.
+func PublicStatuses() []string {
+	return []string{StatusPublished, StatusDeprecated}
+}
+
+// IsPublicStatus returns true if guests can read the wikiDocs in the given status.
+func IsPublicStatus(status string) bool {
+	for _, public := range PublicStatuses() {
+		if status == public {
+			return true
+		}
+	}
+	return false
+}
+
+// WikiDocStatusChange records a transition of a wikiDoc from one status to another.
+type WikiDocStatusChange struct {
+	ID        string `json:"id"`
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// FromStatus is empty for the status a wikiDoc was created with.
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+
+	// UserID is the user who changed the status.
+	UserID   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+}
+
+// NormalizeStatus returns the status a wikiDoc is stored with for the given input: blank defaults
+// to StatusDraft, and the legacy StatusPrivate is StatusDraft.
+func NormalizeStatus(status string) string {
+	if status == "" || status == StatusPrivate {
+		return StatusDraft
+	}
+	return status
+}
+
+// AllowedTransitions returns the statuses a wikiDoc in the given status can move to.
+func AllowedTransitions(status string) []string {
+	return append([]string{}, statusTransitions[NormalizeStatus(status)]...)
+}
+
+// CanTransition returns true if a wikiDoc can move from one status to the other. Keeping the same
+// status is always allowed.
+func CanTransition(from, to string) bool {
+	from, to = NormalizeStatus(from), NormalizeStatus(to)
+	if from == to {
+		return true
+	}
+
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkTransition returns ErrInvalidTransition if a wikiDoc cannot move from one status to the other.
+func checkTransition(from, to string) error {
+	if !CanTransition(from, to) {
+		return errors.Wrapf(ErrInvalidTransition, "cannot move from %s to %s", NormalizeStatus(from), NormalizeStatus(to))
+	}
+	return nil
+}
+
+// checkCreatableStatus returns ErrInvalidTransition if a wikiDoc cannot be created with the status.
+func checkCreatableStatus(status string) error {
+	for _, creatable := range creatableStatuses {
+		if NormalizeStatus(status) == creatable {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrInvalidTransition, "cannot create a wikiDoc with status %s", status)
+}
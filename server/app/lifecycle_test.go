@@ -0,0 +1,89 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeStatus(t *testing.T) {
+	for status, expected := range map[string]string{
+		"":               StatusDraft,
+		StatusPrivate:    StatusDraft,
+		StatusDraft:      StatusDraft,
+		StatusInReview:   StatusInReview,
+		StatusPublished:  StatusPublished,
+		StatusDeprecated: StatusDeprecated,
+		StatusArchived:   StatusArchived,
+	} {
+		t.Run(status, func(t *testing.T) {
+			assert.Equal(t, expected, NormalizeStatus(status))
+		})
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	statuses := []string{StatusDraft, StatusInReview, StatusPublished, StatusDeprecated, StatusArchived}
+
+	// allowed lists every transition that is allowed, keeping the same status included.
+	allowed := map[[2]string]bool{
+		{StatusDraft, StatusDraft}:           true,
+		{StatusDraft, StatusInReview}:        true,
+		{StatusDraft, StatusPublished}:       true,
+		{StatusDraft, StatusArchived}:        true,
+		{StatusInReview, StatusInReview}:     true,
+		{StatusInReview, StatusDraft}:        true,
+		{StatusInReview, StatusPublished}:    true,
+		{StatusPublished, StatusPublished}:   true,
+		{StatusPublished, StatusDraft}:       true,
+		{StatusPublished, StatusDeprecated}:  true,
+		{StatusPublished, StatusArchived}:    true,
+		{StatusDeprecated, StatusDeprecated}: true,
+		{StatusDeprecated, StatusPublished}:  true,
+		{StatusDeprecated, StatusArchived}:   true,
+		{StatusArchived, StatusArchived}:     true,
+		{StatusArchived, StatusDraft}:        true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(from+" to "+to, func(t *testing.T) {
+				err := checkTransition(from, to)
+				if allowed[[2]string{from, to}] {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, ErrInvalidTransition)
+				}
+			})
+		}
+	}
+
+	t.Run("legacy and blank statuses are drafts", func(t *testing.T) {
+		assert.NoError(t, checkTransition(StatusPrivate, StatusInReview))
+		assert.NoError(t, checkTransition("", StatusPrivate))
+		assert.ErrorIs(t, checkTransition(StatusPrivate, StatusDeprecated), ErrInvalidTransition)
+		assert.NoError(t, checkTransition(StatusInReview, ""))
+	})
+}
+
+func TestCheckCreatableStatus(t *testing.T) {
+	for status, creatable := range map[string]bool{
+		"":               true,
+		StatusPrivate:    true,
+		StatusDraft:      true,
+		StatusPublished:  true,
+		StatusInReview:   false,
+		StatusDeprecated: false,
+		StatusArchived:   false,
+		"unknown":        false,
+	} {
+		t.Run(status, func(t *testing.T) {
+			err := checkCreatableStatus(status)
+			if creatable {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidTransition)
+			}
+		})
+	}
+}
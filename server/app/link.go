@@ -0,0 +1,215 @@
+package app
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// maxLinkTargetLength is the maximum number of characters of the target of a link.
+const maxLinkTargetLength = 255
+
+// linkPattern matches the [[Page Name]] and [[target|label]] links of a content.
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// WikiDocLink is a [[link]] from the content of a wikiDoc to another wikiDoc of its team.
+type WikiDocLink struct {
+	// SourceID is the wikiDoc whose content contains the link.
+	SourceID string `json:"source_id"`
+
+	// Target is the text the link points to: the ID or the name of a wikiDoc.
+	Target string `json:"target"`
+
+	// Label is the text the link is displayed with, empty to display the target.
+	Label string `json:"label"`
+
+	// TargetID is the wikiDoc the target resolves to, empty when no wikiDoc matches it.
+	TargetID string `json:"target_id"`
+
+	// TeamID is the team of the source, where the target is resolved.
+	TeamID string `json:"-"`
+
+	// SourceChannelID is the channel of the source, whose wikiDocs the target resolves to first.
+	// It is only set by GetLinksByTarget.
+	SourceChannelID string `json:"-"`
+
+	// Missing is set when the target does not resolve to a wikiDoc the requester can read.
+	Missing bool `json:"missing"`
+
+	CreateAt int64 `json:"create_at"`
+}
+
+// LinkStore is an interface for storing the links between wikiDocs
+type LinkStore interface {
+	// GetLinks retrieves the links of a wikiDoc, in order of their targets
+	GetLinks(sourceID string) ([]WikiDocLink, error)
+
+	// GetBacklinks retrieves the links resolving to a wikiDoc
+	GetBacklinks(targetID string) ([]WikiDocLink, error)
+
+	// SaveLinks replaces the links of a wikiDoc
+	SaveLinks(sourceID string, links []WikiDocLink) error
+
+	// GetLinksByTarget retrieves the links of a team whose target is the given name, ignoring case,
+	// with the channel of their source
+	GetLinksByTarget(teamID, name string) ([]WikiDocLink, error)
+
+	// SetLinkTargets points the given links, found by their source and target, to their TargetID
+	SetLinkTargets(links []WikiDocLink) error
+
+	// GetWikiDocsByName retrieves the wikiDocs of a team that are not in the trash and have the
+	// given name, ignoring case, oldest first. The content of the wikiDocs is not retrieved.
+	GetWikiDocsByName(teamID, name string) ([]WikiDoc, error)
+}
+
+// parseLinks returns the links of a content, by target, with the label of their first occurrence.
+// Links in code are ignored.
+func parseLinks(content string) []WikiDocLink {
+	content = stripCode(content)
+
+	var links []WikiDocLink
+	seen := map[string]bool{}
+	for _, match := range linkPattern.FindAllStringSubmatch(content, -1) {
+		target := strings.TrimSpace(match[1])
+		if target == "" || utf8.RuneCountInString(target) > maxLinkTargetLength || seen[target] {
+			continue
+		}
+		seen[target] = true
+
+		label := strings.TrimSpace(match[2])
+		if utf8.RuneCountInString(label) > maxLinkTargetLength {
+			label = string([]rune(label)[:maxLinkTargetLength])
+		}
+		links = append(links, WikiDocLink{Target: target, Label: label})
+	}
+
+	return links
+}
+
+// updateLinks stores the links of the content of a wikiDoc, resolving their targets: the ID of a
+// wikiDoc of the same team, or the name of a wikiDoc of the same channel, or else of the team.
+func (s *wikiDocsService) updateLinks(wikiDoc WikiDoc) error {
+	links := parseLinks(wikiDoc.Content)
+	now := model.GetMillis()
+	for i := range links {
+		targetID, err := s.resolveLink(wikiDoc, links[i].Target)
+		if err != nil {
+			return err
+		}
+		links[i].SourceID = wikiDoc.ID
+		links[i].TargetID = targetID
+		links[i].TeamID = wikiDoc.TeamID
+		links[i].CreateAt = now
+	}
+
+	return s.linkStore.SaveLinks(wikiDoc.ID, links)
+}
+
+// resolveLink returns the ID of the wikiDoc a link of the source points to, empty if there is none.
+func (s *wikiDocsService) resolveLink(source WikiDoc, target string) (string, error) {
+	if model.IsValidId(target) {
+		wikiDoc, err := s.store.Get(target)
+		if err == nil && wikiDoc.TeamID == source.TeamID && wikiDoc.DeleteAt == 0 {
+			return wikiDoc.ID, nil
+		} else if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", errors.Wrapf(err, "failed to resolve link to '%s'", target)
+		}
+	}
+
+	candidates, err := s.linkStore.GetWikiDocsByName(source.TeamID, target)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve link to '%s'", target)
+	}
+
+	return pickLinkTarget(candidates, source.ChannelID), nil
+}
+
+// resolveLinks points the links of a team whose target is one of the given names to the wikiDoc
+// they resolve to now, as resolveLink does: links that resolved to no wikiDoc, to a wikiDoc in the
+// trash or to a wikiDoc renamed since are fixed.
+func (s *wikiDocsService) resolveLinks(teamID string, names ...string) error {
+	for _, name := range names {
+		links, err := s.linkStore.GetLinksByTarget(teamID, name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the links to '%s'", name)
+		}
+		if len(links) == 0 {
+			continue
+		}
+
+		candidates, err := s.linkStore.GetWikiDocsByName(teamID, name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve the links to '%s'", name)
+		}
+
+		var changed []WikiDocLink
+		for _, link := range links {
+			targetID := pickLinkTarget(candidates, link.SourceChannelID)
+			if targetID != link.TargetID {
+				link.TargetID = targetID
+				changed = append(changed, link)
+			}
+		}
+
+		if err = s.linkStore.SetLinkTargets(changed); err != nil {
+			return errors.Wrapf(err, "failed to resolve the links to '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+func (s *wikiDocsService) GetLinks(wikiDocID string) ([]WikiDocLink, error) {
+	links, err := s.linkStore.GetLinks(wikiDocID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range links {
+		if links[i].TargetID == "" {
+			links[i].Missing = true
+			continue
+		}
+
+		target, err := s.store.Get(links[i].TargetID)
+		if errors.Is(err, ErrNotFound) || (err == nil && target.DeleteAt != 0) {
+			links[i].Missing = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return links, nil
+}
+
+func (s *wikiDocsService) GetBacklinks(wikiDocID string) ([]WikiDoc, error) {
+	links, err := s.linkStore.GetBacklinks(wikiDocID)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]WikiDoc, 0, len(links))
+	for _, link := range links {
+		source, err := s.store.Get(link.SourceID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if source.DeleteAt != 0 {
+			continue
+		}
+
+		source.Content = ""
+		sources = append(sources, source)
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+
+	return sources, nil
+}
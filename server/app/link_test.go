@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resolveLinkStore serves fixed links and wikiDocs by name, and records the links it updates.
+type resolveLinkStore struct {
+	LinkStore
+
+	links    []WikiDocLink
+	wikiDocs []WikiDoc
+	updated  []WikiDocLink
+}
+
+func (s *resolveLinkStore) GetLinksByTarget(teamID, name string) ([]WikiDocLink, error) {
+	var links []WikiDocLink
+	for _, link := range s.links {
+		if strings.EqualFold(link.Target, name) {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (s *resolveLinkStore) GetWikiDocsByName(teamID, name string) ([]WikiDoc, error) {
+	var wikiDocs []WikiDoc
+	for _, wikiDoc := range s.wikiDocs {
+		if strings.EqualFold(wikiDoc.Name, name) && wikiDoc.DeleteAt == 0 {
+			wikiDocs = append(wikiDocs, wikiDoc)
+		}
+	}
+	return wikiDocs, nil
+}
+
+func (s *resolveLinkStore) SetLinkTargets(links []WikiDocLink) error {
+	s.updated = append(s.updated, links...)
+	return nil
+}
+
+func TestParseLinks(t *testing.T) {
+	t.Run("links by target, with the label of their first occurrence", func(t *testing.T) {
+		content := "See [[Onboarding]] and [[ abc123 | the setup ]], then [[Onboarding|again]]."
+		assert.Equal(t, []WikiDocLink{
+			{Target: "Onboarding"},
+			{Target: "abc123", Label: "the setup"},
+		}, parseLinks(content))
+	})
+
+	t.Run("links in code and empty links are ignored", func(t *testing.T) {
+		content := "Write `[[Page]]` or\n```\n[[Other]]\n```\n[[ ]] [[Real]]"
+		assert.Equal(t, []WikiDocLink{{Target: "Real"}}, parseLinks(content))
+	})
+}
+
+func TestResolveLinks(t *testing.T) {
+	store := &resolveLinkStore{
+		links: []WikiDocLink{
+			{SourceID: "unresolved", Target: "Guide", SourceChannelID: "c2"},
+			{SourceID: "to-trashed", Target: "guide", TargetID: "trashed", SourceChannelID: "c2"},
+			{SourceID: "to-renamed", Target: "Guide", TargetID: "renamed", SourceChannelID: "c1"},
+			{SourceID: "same-channel", Target: "Guide", TargetID: "c1-guide", SourceChannelID: "c1"},
+			{SourceID: "other-target", Target: "Other", SourceChannelID: "c1"},
+		},
+		wikiDocs: []WikiDoc{
+			{ID: "trashed", Name: "Guide", ChannelID: "c2", DeleteAt: 1},
+			{ID: "renamed", Name: "Handbook", ChannelID: "c1"},
+			{ID: "c1-guide", Name: "Guide", ChannelID: "c1"},
+			{ID: "c2-guide", Name: "guide", ChannelID: "c2"},
+		},
+	}
+
+	s := &wikiDocsService{linkStore: store}
+	require.NoError(t, s.resolveLinks("team", "Guide"))
+
+	assert.ElementsMatch(t, []WikiDocLink{
+		{SourceID: "unresolved", Target: "Guide", TargetID: "c2-guide", SourceChannelID: "c2"},
+		{SourceID: "to-trashed", Target: "guide", TargetID: "c2-guide", SourceChannelID: "c2"},
+		{SourceID: "to-renamed", Target: "Guide", TargetID: "c1-guide", SourceChannelID: "c1"},
+	}, store.updated)
+}
@@ -0,0 +1,558 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// maxImportRevisions is the maximum number of revisions imported per document. The oldest ones
+// are left out.
+const maxImportRevisions = 100
+
+// mediaWikiPage is a page of a MediaWiki XML dump.
+type mediaWikiPage struct {
+	Title     string `xml:"title"`
+	Namespace int    `xml:"ns"`
+	Redirect  *struct {
+		Title string `xml:"title,attr"`
+	} `xml:"redirect"`
+	Revisions []mediaWikiRevision `xml:"revision"`
+}
+
+// mediaWikiRevision is a revision of a page of a MediaWiki XML dump.
+type mediaWikiRevision struct {
+	Timestamp string `xml:"timestamp"`
+	Model     string `xml:"model"`
+	Text      string `xml:"text"`
+}
+
+var (
+	wikiCommentPattern   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	wikiRefPattern       = regexp.MustCompile(`(?is)<ref(?:\s[^>]*)?/>|<ref(?:\s[^>]*)?>.*?</ref\s*>|<references(?:\s[^>]*)?/>`)
+	wikiMagicWordPattern = regexp.MustCompile(`__[A-Z]+__`)
+	wikiBreakPattern     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	wikiHeadingPattern   = regexp.MustCompile(`^(={1,6})\s*(.+?)\s*(={1,6})\s*$`)
+	wikiListPattern      = regexp.MustCompile(`^([*#:;]+)\s*(.*)$`)
+	wikiLinkPattern      = regexp.MustCompile(`\[\[([^\[\]|]*)(?:\|([^\[\]]*))?\]\]`)
+	wikiExternalPattern  = regexp.MustCompile(`\[((?:https?|ftp|mailto):[^\s\]]+)(?:\s+([^\]]*))?\]`)
+	wikiLangPattern      = regexp.MustCompile(`(?i)lang\s*=\s*["']?([\w+#-]+)`)
+	placeholderPattern   = regexp.MustCompile(`\x00(\d+)\x00`)
+	blankLinesPattern    = regexp.MustCompile(`\n{3,}`)
+
+	wikiBoldItalicPattern = regexp.MustCompile(`'''''(.+?)'''''`)
+	wikiBoldPattern       = regexp.MustCompile(`'''(.+?)'''`)
+	wikiItalicPattern     = regexp.MustCompile(`''(.+?)''`)
+
+	wikiBoldTagPattern   = regexp.MustCompile(`(?i)</?(?:b|strong)\s*>`)
+	wikiItalicTagPattern = regexp.MustCompile(`(?i)</?(?:i|em)\s*>`)
+	wikiStrikeTagPattern = regexp.MustCompile(`(?i)</?(?:s|del|strike)\s*>`)
+	wikiOtherTagPattern  = regexp.MustCompile(`(?i)</?(?:span|div|font|center|small|big|u|ins|sup|sub|p|blockquote|abbr|cite)(?:\s[^>]*)?>`)
+)
+
+// wikiCodePatterns match the elements of wikitext whose content is not markup, with the format of
+// their content in Markdown.
+var wikiCodePatterns = []struct {
+	pattern *regexp.Regexp
+	block   bool
+}{
+	{regexp.MustCompile(`(?is)<nowiki\s*/>`), false},
+	{regexp.MustCompile(`(?is)<nowiki(?:\s[^>]*)?>(.*?)</nowiki\s*>`), false},
+	{regexp.MustCompile(`(?is)<code(?:\s[^>]*)?>(.*?)</code\s*>`), false},
+	{regexp.MustCompile(`(?is)<(?:pre|syntaxhighlight|source)((?:\s[^>]*)?)>(.*?)</(?:pre|syntaxhighlight|source)\s*>`), true},
+}
+
+// ReadMediaWikiDump returns the documents of a MediaWiki XML dump, parents first: a document per
+// page of the main namespace that is not a redirect, with its revisions. Subpages, such as
+// "Guide/Install", are imported under their parent page when the dump has it, named after their
+// last part. The wikitext of the pages is converted to Markdown.
+func ReadMediaWikiDump(r io.Reader) ([]ImportDoc, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var pages []mediaWikiPage
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(ErrMalformedImport, "invalid XML: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			if start.Name.Local != "mediawiki" {
+				return nil, errors.Wrap(ErrMalformedImport, "not a MediaWiki XML dump")
+			}
+			root = false
+			continue
+		}
+		if start.Name.Local != "page" {
+			continue
+		}
+
+		var page mediaWikiPage
+		if err = decoder.DecodeElement(&page, &start); err != nil {
+			return nil, errors.Wrapf(ErrMalformedImport, "invalid page: %v", err)
+		}
+		if page.Namespace != 0 || page.Redirect != nil || len(page.Revisions) == 0 {
+			continue
+		}
+		if len(pages) == maxImportFiles {
+			return nil, errors.Wrapf(ErrMalformedImport, "the dump has more than %d pages", maxImportFiles)
+		}
+		pages = append(pages, page)
+	}
+	if root {
+		return nil, errors.Wrap(ErrMalformedImport, "not a MediaWiki XML dump")
+	}
+
+	titles := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		titles[normalizeWikiTitle(page.Title)] = true
+	}
+	names := make(map[string]string, len(pages))
+	for _, page := range pages {
+		title := normalizeWikiTitle(page.Title)
+		names[title] = title
+		if parent := wikiParentTitle(title); parent != "" && titles[parent] {
+			names[title] = title[len(parent)+1:]
+		}
+	}
+
+	docs := make([]ImportDoc, 0, len(pages))
+	for _, page := range pages {
+		docs = append(docs, mediaWikiDoc(page, titles, names))
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return strings.Count(docs[i].Path, "/") < strings.Count(docs[j].Path, "/")
+	})
+
+	return docs, nil
+}
+
+// mediaWikiDoc returns the document of a page.
+func mediaWikiDoc(page mediaWikiPage, titles map[string]bool, names map[string]string) ImportDoc {
+	title := normalizeWikiTitle(page.Title)
+	doc := ImportDoc{Path: title}
+	if parent := wikiParentTitle(title); parent != "" && titles[parent] {
+		doc.ParentPath = parent
+	}
+
+	sort.SliceStable(page.Revisions, func(i, j int) bool {
+		return page.Revisions[i].Timestamp < page.Revisions[j].Timestamp
+	})
+
+	createAt := parseWikiTimestamp(page.Revisions[0].Timestamp)
+	if len(page.Revisions) > maxImportRevisions {
+		page.Revisions = page.Revisions[len(page.Revisions)-maxImportRevisions:]
+	}
+
+	versions := make([]WikiDoc, 0, len(page.Revisions))
+	for _, revision := range page.Revisions {
+		if revision.Model != "" && revision.Model != "wikitext" {
+			doc.Err = errors.Wrapf(ErrMalformedImport, "unsupported content model '%s'", revision.Model)
+			return doc
+		}
+		versions = append(versions, WikiDoc{
+			Name:     names[title],
+			Content:  WikitextToMarkdown(revision.Text, title, names),
+			UpdateAt: parseWikiTimestamp(revision.Timestamp),
+		})
+	}
+
+	doc.WikiDoc = versions[len(versions)-1]
+	doc.WikiDoc.CreateAt = createAt
+	doc.Revisions = versions[:len(versions)-1]
+
+	return doc
+}
+
+// normalizeWikiTitle returns the title of a page as MediaWiki stores it: with spaces rather than
+// underscores, and an uppercase first letter.
+func normalizeWikiTitle(title string) string {
+	title = strings.TrimSpace(strings.ReplaceAll(title, "_", " "))
+	first, size := utf8.DecodeRuneInString(title)
+	if size == 0 {
+		return title
+	}
+	return string(unicode.ToUpper(first)) + title[size:]
+}
+
+// wikiParentTitle returns the title of the parent of a subpage, empty for the other pages.
+func wikiParentTitle(title string) string {
+	i := strings.LastIndex(title, "/")
+	if i <= 0 || i == len(title)-1 {
+		return ""
+	}
+	return title[:i]
+}
+
+// parseWikiTimestamp returns the time of a timestamp of a MediaWiki dump, in milliseconds, or 0
+// if it is not valid.
+func parseWikiTimestamp(timestamp string) int64 {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(timestamp))
+	if err != nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// WikitextToMarkdown converts the wikitext of a MediaWiki page to Markdown. Links to pages become
+// [[links]] to the names of the wikiDocs given by names, keyed by title. Templates, references and
+// the other elements that cannot be rendered without the wiki are left out.
+func WikitextToMarkdown(text, title string, names map[string]string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = wikiCommentPattern.ReplaceAllString(text, "")
+
+	// The elements whose content is not markup are set aside, and put back once converted.
+	var protected []string
+	protect := func(markdown string) string {
+		protected = append(protected, markdown)
+		return fmt.Sprintf("\x00%d\x00", len(protected)-1)
+	}
+	for _, code := range wikiCodePatterns {
+		code := code
+		text = code.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			groups := code.pattern.FindStringSubmatch(match)
+			switch {
+			case len(groups) == 1:
+				return ""
+			case code.block:
+				lang := ""
+				if attributes := wikiLangPattern.FindStringSubmatch(groups[1]); attributes != nil {
+					lang = attributes[1]
+				}
+				return protect("\n" + fence(html.UnescapeString(groups[2]), lang) + "\n")
+			case strings.HasPrefix(strings.ToLower(match), "<nowiki"):
+				return protect(html.UnescapeString(groups[1]))
+			default:
+				return protect(inlineCode(html.UnescapeString(groups[1])))
+			}
+		})
+	}
+
+	text = removeWikiTemplates(text)
+	text = wikiRefPattern.ReplaceAllString(text, "")
+	text = wikiMagicWordPattern.ReplaceAllString(text, "")
+
+	converter := wikitextConverter{title: title, names: names}
+	markdown := converter.convertBlocks(strings.Split(text, "\n"))
+
+	markdown = placeholderPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		i, err := strconv.Atoi(strings.Trim(match, "\x00"))
+		if err != nil || i >= len(protected) {
+			return ""
+		}
+		return protected[i]
+	})
+
+	markdown = strings.TrimSpace(blankLinesPattern.ReplaceAllString(markdown, "\n\n"))
+	if markdown == "" {
+		return ""
+	}
+	return markdown + "\n"
+}
+
+// inlineCode returns the Markdown code span of a text, delimited by enough backticks.
+func inlineCode(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	delimiter := "`"
+	for strings.Contains(code, delimiter) {
+		delimiter += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return delimiter + code + delimiter
+}
+
+// removeWikiTemplates removes the {{templates}}, parser functions and {{{parameters}}} of
+// wikitext, nested or not. Their output depends on the wiki.
+func removeWikiTemplates(text string) string {
+	var out strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "{{"):
+			depth++
+			i++
+		case depth > 0 && strings.HasPrefix(text[i:], "}}"):
+			depth--
+			i++
+		case depth == 0:
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String()
+}
+
+// wikitextConverter converts the blocks and inline markup of a page.
+type wikitextConverter struct {
+	title string
+	names map[string]string
+}
+
+// convertBlocks converts the lines of a page: headings, lists, tables, preformatted text and
+// paragraphs.
+func (c wikitextConverter) convertBlocks(lines []string) string {
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch trimmed := strings.TrimSpace(line); {
+		case strings.HasPrefix(trimmed, "{|"):
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "|}") {
+				end++
+			}
+			out = append(out, "", c.convertTable(lines[i+1:minInt(end, len(lines))]), "")
+			i = end
+
+		case strings.HasPrefix(line, " ") && trimmed != "":
+			var code []string
+			for ; i < len(lines) && strings.HasPrefix(lines[i], " ") && strings.TrimSpace(lines[i]) != ""; i++ {
+				code = append(code, html.UnescapeString(lines[i][1:]))
+			}
+			i--
+			out = append(out, fence(strings.Join(code, "\n"), ""))
+
+		case trimmed == "----" || strings.HasPrefix(trimmed, "----") && strings.Trim(trimmed, "-") == "":
+			out = append(out, "", "---", "")
+
+		case wikiHeadingPattern.MatchString(trimmed):
+			groups := wikiHeadingPattern.FindStringSubmatch(trimmed)
+			level := minInt(len(groups[1]), len(groups[3]))
+			out = append(out, "", strings.Repeat("#", level)+" "+c.convertInline(groups[2]), "")
+
+		case wikiListPattern.MatchString(line):
+			groups := wikiListPattern.FindStringSubmatch(line)
+			out = append(out, c.convertListItem(groups[1], groups[2])...)
+
+		default:
+			out = append(out, wikiBreakPattern.ReplaceAllString(c.convertInline(line), "  \n"))
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// convertListItem converts an item of a list, whose markers give the nesting: * for bullets, # for
+// numbers, : for indentation and ; for terms.
+func (c wikitextConverter) convertListItem(markers, text string) []string {
+	indent := ""
+	for _, marker := range markers[:len(markers)-1] {
+		if marker == '#' {
+			indent += "   "
+		} else {
+			indent += "  "
+		}
+	}
+
+	switch markers[len(markers)-1] {
+	case '*':
+		return []string{indent + "- " + c.convertInline(text)}
+	case '#':
+		return []string{indent + "1. " + c.convertInline(text)}
+	case ';':
+		term, definition, found := strings.Cut(text, " : ")
+		lines := []string{indent + "**" + strings.TrimSpace(c.convertInline(term)) + "**"}
+		if found {
+			lines = append(lines, indent+": "+c.convertInline(definition))
+		}
+		return lines
+	default:
+		if indent == "" {
+			return []string{"> " + c.convertInline(text)}
+		}
+		return []string{indent + c.convertInline(text)}
+	}
+}
+
+// convertTable converts the lines of a table, without its {| and |} lines, to a Markdown table.
+// The first row is the header.
+func (c wikitextConverter) convertTable(lines []string) string {
+	var rows [][]string
+	var row []string
+	caption := ""
+	flush := func() {
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		row = nil
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "|-"):
+			flush()
+		case strings.HasPrefix(line, "|+"):
+			caption = c.tableCell(line[2:])
+		case strings.HasPrefix(line, "!"):
+			for _, cell := range strings.Split(strings.ReplaceAll(line[1:], "||", "!!"), "!!") {
+				row = append(row, c.tableCell(cell))
+			}
+		case strings.HasPrefix(line, "|"):
+			for _, cell := range strings.Split(line[1:], "||") {
+				row = append(row, c.tableCell(cell))
+			}
+		case len(row) > 0 && line != "":
+			row[len(row)-1] = strings.TrimSpace(row[len(row)-1] + " " + c.tableCell(line))
+		}
+	}
+	flush()
+	if len(rows) == 0 {
+		return caption
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = maxInt(columns, len(row))
+	}
+	var out []string
+	if caption != "" {
+		out = append(out, "**"+caption+"**", "")
+	}
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		out = append(out, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			out = append(out, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// tableCell converts the text of a cell, without its attributes.
+func (c wikitextConverter) tableCell(cell string) string {
+	if i := strings.Index(cell, "|"); i >= 0 && !strings.Contains(cell[:i], "[[") {
+		cell = cell[i+1:]
+	}
+	cell = wikiBreakPattern.ReplaceAllString(c.convertInline(strings.TrimSpace(cell)), " ")
+	return strings.ReplaceAll(cell, "|", "\\|")
+}
+
+// convertInline converts the links, emphasis and HTML formatting of a line.
+func (c wikitextConverter) convertInline(text string) string {
+	text = wikiLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := wikiLinkPattern.FindStringSubmatch(match)
+		return c.convertLink(groups[1], groups[2])
+	})
+	text = wikiExternalPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := wikiExternalPattern.FindStringSubmatch(match)
+		if strings.TrimSpace(groups[2]) == "" {
+			return "<" + groups[1] + ">"
+		}
+		return "[" + strings.TrimSpace(groups[2]) + "](" + groups[1] + ")"
+	})
+
+	text = wikiBoldItalicPattern.ReplaceAllString(text, "***$1***")
+	text = wikiBoldPattern.ReplaceAllString(text, "**$1**")
+	text = wikiItalicPattern.ReplaceAllString(text, "*$1*")
+	text = wikiBoldTagPattern.ReplaceAllString(text, "**")
+	text = wikiItalicTagPattern.ReplaceAllString(text, "*")
+	text = wikiStrikeTagPattern.ReplaceAllString(text, "~~")
+	text = wikiOtherTagPattern.ReplaceAllString(text, "")
+
+	return html.UnescapeString(text)
+}
+
+// convertLink converts an internal link. Links to the pages of the dump become [[links]] to their
+// wikiDoc, files become their name and the other namespaces their label.
+func (c wikitextConverter) convertLink(target, label string) string {
+	target = strings.TrimSpace(target)
+	label = strings.TrimSpace(label)
+
+	if namespace, name, found := strings.Cut(strings.TrimPrefix(target, ":"), ":"); found && !strings.Contains(namespace, " ") && namespace != "" {
+		switch strings.ToLower(namespace) {
+		case "category":
+			if strings.HasPrefix(target, ":") {
+				return firstNonEmpty(label, name)
+			}
+			return ""
+		case "file", "image", "media":
+			parts := strings.Split(label, "|")
+			return "[" + namespace + ": " + strings.TrimSpace(name) + "]" + wikiFileCaption(parts)
+		default:
+			return firstNonEmpty(label, target)
+		}
+	}
+
+	if strings.HasPrefix(target, "/") {
+		target = c.title + strings.TrimSuffix(target, "/")
+	}
+	page, _, _ := strings.Cut(target, "#")
+	if page == "" {
+		return firstNonEmpty(label, strings.TrimPrefix(target, "#"))
+	}
+
+	name, ok := c.names[normalizeWikiTitle(page)]
+	if !ok {
+		name = normalizeWikiTitle(page)
+	}
+	if label == "" {
+		label = strings.TrimSpace(strings.ReplaceAll(page, "_", " "))
+	}
+	if strings.EqualFold(label, name) {
+		return "[[" + name + "]]"
+	}
+	return "[[" + name + "|" + label + "]]"
+}
+
+// wikiFileCaption returns the caption of a file, the last of its parameters that is not an option.
+func wikiFileCaption(parameters []string) string {
+	for i := len(parameters) - 1; i >= 0; i-- {
+		parameter := strings.TrimSpace(parameters[i])
+		switch {
+		case parameter == "", strings.Contains(parameter, "="), strings.HasSuffix(parameter, "px"):
+			continue
+		case parameter == "thumb", parameter == "thumbnail", parameter == "frame", parameter == "frameless",
+			parameter == "border", parameter == "left", parameter == "right", parameter == "center", parameter == "none":
+			continue
+		}
+		return " " + parameter
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWikitextToMarkdown(t *testing.T) {
+	names := map[string]string{"Guide": "Guide", "Guide/Install": "Install"}
+
+	for name, test := range map[string]struct {
+		wikitext string
+		expected string
+	}{
+		"headings and emphasis": {
+			"== Setup ==\nThis is '''bold''', ''italic'' and '''''both'''''.<!-- hidden -->",
+			"## Setup\n\nThis is **bold**, *italic* and ***both***.\n",
+		},
+		"links": {
+			"See [[Guide/Install|the install]], [[guide]], [[/Install]], [[Missing page#Top]] and [https://example.com the site].",
+			"See [[Install|the install]], [[Guide]], [[Install|Guide/Install]], [[Missing page]] and [the site](https://example.com).",
+		},
+		"namespaces": {
+			"[[File:Logo.png|thumb|200px|The logo]] [[Category:Docs]][[Help:Editing|help]]",
+			"[File: Logo.png] The logo help",
+		},
+		"lists": {
+			"* One\n** Nested\n# First\n#* Bullet\n; Term : Definition\n: Quoted",
+			"- One\n  - Nested\n1. First\n   - Bullet\n**Term**\n: Definition\n> Quoted",
+		},
+		"code": {
+			"Use <code>go test</code> or <nowiki>''raw''</nowiki>.\n<syntaxhighlight lang=\"go\">\nfunc main() {}\n</syntaxhighlight>\n leading\n space",
+			"Use `go test` or ''raw''.\n\n```go\nfunc main() {}\n```\n\n```\nleading\nspace\n```",
+		},
+		"templates and references": {
+			"{{Infobox|name={{PAGENAME}}}}Text<ref>Source</ref>.__TOC__",
+			"Text.",
+		},
+		"table": {
+			"{| class=\"wikitable\"\n! Name !! Value\n|-\n| style=\"color:red\" | a || [[Guide|b]]\n|-\n| c\n|}",
+			"| Name | Value |\n| --- | --- |\n| a | [[Guide\\|b]] |\n| c |  |",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, strings.TrimSpace(test.expected)+"\n", WikitextToMarkdown(test.wikitext, "Guide", names))
+		})
+	}
+}
+
+func TestReadMediaWikiDump(t *testing.T) {
+	dump := `<mediawiki xmlns="http://www.mediawiki.org/xml/export-0.10/" version="0.10">
+  <siteinfo><sitename>Wiki</sitename></siteinfo>
+  <page>
+    <title>Guide/Install</title>
+    <ns>0</ns>
+    <revision>
+      <timestamp>2020-01-02T00:00:00Z</timestamp>
+      <model>wikitext</model>
+      <text xml:space="preserve">Run it.</text>
+    </revision>
+  </page>
+  <page>
+    <title>Guide</title>
+    <ns>0</ns>
+    <revision>
+      <timestamp>2020-01-01T00:00:00Z</timestamp>
+      <text xml:space="preserve">First</text>
+    </revision>
+    <revision>
+      <timestamp>2020-02-01T00:00:00Z</timestamp>
+      <text xml:space="preserve">See [[Guide/Install]]</text>
+    </revision>
+  </page>
+  <page>
+    <title>Old name</title>
+    <ns>0</ns>
+    <redirect title="Guide" />
+    <revision><timestamp>2020-01-01T00:00:00Z</timestamp><text>#REDIRECT [[Guide]]</text></revision>
+  </page>
+  <page>
+    <title>Talk:Guide</title>
+    <ns>1</ns>
+    <revision><timestamp>2020-01-01T00:00:00Z</timestamp><text>Chat</text></revision>
+  </page>
+</mediawiki>`
+
+	docs, err := ReadMediaWikiDump(strings.NewReader(dump))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	guide := docs[0]
+	assert.Equal(t, "Guide", guide.Path)
+	assert.Equal(t, "Guide", guide.WikiDoc.Name)
+	assert.Equal(t, "See [[Install|Guide/Install]]\n", guide.WikiDoc.Content)
+	assert.Equal(t, int64(1577836800000), guide.WikiDoc.CreateAt)
+	assert.Equal(t, int64(1580515200000), guide.WikiDoc.UpdateAt)
+	require.Len(t, guide.Revisions, 1)
+	assert.Equal(t, "First\n", guide.Revisions[0].Content)
+
+	install := docs[1]
+	assert.Equal(t, "Install", install.WikiDoc.Name)
+	assert.Equal(t, "Guide", install.ParentPath)
+	assert.Empty(t, install.Revisions)
+
+	_, err = ReadMediaWikiDump(strings.NewReader(`<html><body/></html>`))
+	assert.ErrorIs(t, err, ErrMalformedImport)
+}
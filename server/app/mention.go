@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// mentionParagraphLength is the maximum number of characters of the paragraph quoted when notifying
+// a mention.
+const mentionParagraphLength = 500
+
+var (
+	// mentionPattern matches a mention of a username, which cannot follow a word character, so that
+	// email addresses are not mentions.
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z0-9][a-zA-Z0-9._-]*)`)
+
+	// codePattern matches fenced code blocks and inline code, where mentions and links are ignored.
+	codePattern = regexp.MustCompile("(?s)```.*?(```|$)|`[^`\n]*`")
+)
+
+// stripCode blanks the code of a content, keeping the offsets of the rest of the content.
+func stripCode(content string) string {
+	return codePattern.ReplaceAllStringFunc(content, func(code string) string {
+		return strings.Repeat(" ", len(code))
+	})
+}
+
+// parseMentions returns the usernames mentioned in the content, lowercased, in order of first
+// appearance. Mentions in code are ignored.
+func parseMentions(content string) []string {
+	content = stripCode(content)
+
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		// A mention ending a sentence is followed by a period that is not part of the username.
+		username := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
+
+// newMentions returns the usernames mentioned in the content but not in the previous content.
+func newMentions(previous, content string) []string {
+	mentioned := map[string]bool{}
+	for _, username := range parseMentions(previous) {
+		mentioned[username] = true
+	}
+
+	var usernames []string
+	for _, username := range parseMentions(content) {
+		if !mentioned[username] {
+			usernames = append(usernames, username)
+		}
+	}
+
+	return usernames
+}
+
+// mentionParagraph returns the paragraph of the content where the username is first mentioned,
+// shortened to mentionParagraphLength characters.
+func mentionParagraph(content, username string) string {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		found := false
+		for _, mention := range parseMentions(paragraph) {
+			if mention == username {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		paragraph = strings.TrimSpace(paragraph)
+		if utf8.RuneCountInString(paragraph) > mentionParagraphLength {
+			paragraph = strings.TrimSpace(string([]rune(paragraph)[:mentionParagraphLength])) + "…"
+		}
+		return paragraph
+	}
+
+	return ""
+}
+
+// notifyMentions sends a direct message to the users newly mentioned in the content of a wikiDoc,
+// quoting the paragraph they are mentioned in. Only the users who can read the channel of the
+// wikiDoc are notified, and guests only of public wikiDocs. Restricted wikiDocs are not notified,
+// as not every member of the channel can read them. Failures are logged: the change itself
+// already happened.
+func (s *wikiDocsService) notifyMentions(previous, wikiDoc WikiDoc, userID string) {
+	if wikiDoc.Restricted {
+		return
+	}
+
+	usernames := newMentions(previous.Content, wikiDoc.Content)
+	if len(usernames) == 0 {
+		return
+	}
+
+	actor, err := s.api.User.Get(userID)
+	if err != nil {
+		s.logger.Warnf("failed to get the author of the mentions in wikiDoc %s: %v", wikiDoc.ID, err)
+		return
+	}
+
+	isPublic := IsPublicStatus(wikiDoc.Status)
+
+	link := wikiDocLink(siteURL(s.api), wikiDoc)
+	for _, username := range usernames {
+		user, err := s.api.User.GetByUsername(username)
+		if err != nil || user.Id == userID || user.DeleteAt != 0 {
+			continue
+		}
+		if !IsMemberOfChannel(user.Id, wikiDoc.ChannelID, s.api) {
+			continue
+		}
+		if user.IsGuest() && !isPublic {
+			continue
+		}
+
+		post := mentionPost(wikiDoc, actor, mentionParagraph(wikiDoc.Content, username), link)
+		if err = s.poster.DM(user.Id, post); err != nil {
+			s.logger.Warnf("failed to notify %s of their mention in wikiDoc %s: %v", user.Id, wikiDoc.ID, err)
+		}
+	}
+}
+
+// mentionPost is the direct message notifying a user that they were mentioned in a wikiDoc.
+func mentionPost(wikiDoc WikiDoc, actor *model.User, paragraph, link string) *model.Post {
+	message := fmt.Sprintf("@%s mentioned you in the wiki doc [%s](%s):", actor.Username, wikiDoc.Name, link)
+	if paragraph != "" {
+		message += "\n> " + strings.ReplaceAll(paragraph, "\n", "\n> ")
+	}
+
+	return &model.Post{Message: message}
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	t.Run("mentions in order of appearance", func(t *testing.T) {
+		assert.Equal(t, []string{"alice", "bob.smith"}, parseMentions("@Alice and @bob.smith, then @alice again."))
+	})
+
+	t.Run("emails and code are not mentions", func(t *testing.T) {
+		content := "Mail alice@example.com, run `@bob` or\n```\n@carol\n```\nask @dave."
+		assert.Equal(t, []string{"dave"}, parseMentions(content))
+	})
+}
+
+func TestNewMentions(t *testing.T) {
+	assert.Equal(t, []string{"bob"}, newMentions("Ask @alice.", "Ask @alice and @bob."))
+	assert.Empty(t, newMentions("Ask @alice and @bob.", "Ask @bob."))
+}
+
+func TestMentionParagraph(t *testing.T) {
+	content := "Intro for @alice.\n\nThe plan\nneeds @bob.\n\nOutro."
+	assert.Equal(t, "The plan\nneeds @bob.", mentionParagraph(content, "bob"))
+	assert.Equal(t, "", mentionParagraph(content, "carol"))
+}
@@ -11,37 +11,83 @@ var ErrNoPermissions = errors.New("does not have permissions")
 
 type PermissionsService struct {
 	wikiDocsService WikiDocService
+	memberService   WikiDocMemberService
 	pluginAPI       *pluginapi.Client
 }
 
 func NewPermissionsService(
 	wikiDocsService WikiDocService,
+	memberService WikiDocMemberService,
 	pluginAPI *pluginapi.Client,
 ) *PermissionsService {
 	return &PermissionsService{
 		wikiDocsService,
+		memberService,
 		pluginAPI,
 	}
 }
 
 func (p *PermissionsService) WikiDocIsPublic(wikiDoc WikiDoc) bool {
-	return wikiDoc.Status == StatusPublished
+	return IsPublicStatus(wikiDoc.Status)
 }
 
+// HasEditPermissionsToWikiDocs checks that the user can edit a wikiDoc, or the wikiDocs of its
+// channel when it has no ID. System admins can edit every wikiDoc, editors and owners the wikiDocs
+// they or their groups are granted, and the users who can manage the properties of the channel the
+// wikiDocs that are not restricted to their members.
 func (p *PermissionsService) HasEditPermissionsToWikiDocs(userID string, wikiDoc WikiDoc) error {
-	if IsSystemAdmin(userID, p.pluginAPI) || CanManageChannelProperties(userID, wikiDoc.ChannelID, p.pluginAPI) {
+	return p.checkWikiDocRole(userID, wikiDoc, RoleEditor)
+}
+
+// WikiDocManageMembers checks that the user can manage the members of a wikiDoc, and restrict it to
+// them. Owners of the wikiDoc can, as well as the users who can edit it through its channel.
+func (p *PermissionsService) WikiDocManageMembers(userID string, wikiDoc WikiDoc) error {
+	return p.checkWikiDocRole(userID, wikiDoc, RoleOwner)
+}
+
+// checkWikiDocRole checks that the user has at least the given role on the wikiDoc, or can manage
+// the properties of its channel when the wikiDoc is not restricted to its members.
+func (p *PermissionsService) checkWikiDocRole(userID string, wikiDoc WikiDoc, minimum WikiDocRole) error {
+	if IsSystemAdmin(userID, p.pluginAPI) {
+		return nil
+	}
+
+	roles, err := p.memberService.GetChannelRoles(userID, wikiDoc.ChannelID)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to get roles to determine permissions, wikiDoc id `%s`", wikiDoc.ID)
+	}
+	if roles.RoleOn(wikiDoc).Allows(minimum) {
+		return nil
+	}
+
+	if !wikiDoc.Restricted && CanManageChannelProperties(userID, wikiDoc.ChannelID, p.pluginAPI) {
 		return nil
 	}
 
 	return ErrNoPermissions
 }
 
+// ChannelApprovals checks that the user can change the number of approvals the wikiDocs of a channel
+// need to be published: owners of the channel wiki can, as well as the users who can manage the
+// properties of the channel. Editors cannot, or they could publish without review.
+func (p *PermissionsService) ChannelApprovals(userID string, channelID string) error {
+	return p.checkWikiDocRole(userID, WikiDoc{ChannelID: channelID}, RoleOwner)
+}
+
+// WikiReport checks that the user can read and post the report of the wiki of a channel: owners of
+// the channel wiki can, as well as the users who can manage the properties of the channel.
+func (p *PermissionsService) WikiReport(userID string, channelID string) error {
+	return p.checkWikiDocRole(userID, WikiDoc{ChannelID: channelID}, RoleOwner)
+}
+
+// canReadChannel returns true if the user is a member of the channel. Membership rather than the
+// read_channel permission is checked, as the store can only join ChannelMembers when listing.
 func (p *PermissionsService) canReadChannel(userID string, channelID string) bool {
 	if channelID == "" || userID == "" {
 		return false
 	}
 
-	return p.pluginAPI.User.HasPermissionToTeam(userID, channelID, model.PermissionReadChannel)
+	return IsMemberOfChannel(userID, channelID, p.pluginAPI)
 }
 
 func (p *PermissionsService) WikiDocCreate(wikiDoc WikiDoc) error {
@@ -53,49 +99,184 @@ func (p *PermissionsService) WikiDocCreate(wikiDoc WikiDoc) error {
 }
 
 func (p *PermissionsService) DeleteWikiDoc(userID string, wikiDoc WikiDoc) error {
-	if IsSystemAdmin(userID, p.pluginAPI) || CanManageChannelProperties(userID, wikiDoc.ChannelID, p.pluginAPI) {
+	return p.checkWikiDocRole(userID, wikiDoc, RoleOwner)
+}
+
+// DeleteWikiDocWithOptions checks that the user can delete a wikiDoc as specified by the options:
+// when its children are deleted along with it, the user must be able to delete each of its
+// descendants too.
+func (p *PermissionsService) DeleteWikiDocWithOptions(userID string, wikiDoc WikiDoc, options DeleteOptions) error {
+	if err := p.DeleteWikiDoc(userID, wikiDoc); err != nil {
+		return err
+	}
+	if options.Children != ChildrenCascade {
 		return nil
 	}
 
-	return ErrNoPermissions
+	tree, err := p.wikiDocsService.GetTree(wikiDoc.ChannelID)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to get tree to determine permissions, wikiDoc id `%s`", wikiDoc.ID)
+	}
+
+	node := findWikiDocNode(tree, wikiDoc.ID)
+	if node == nil {
+		return nil
+	}
+
+	var checkDescendants func(nodes []*WikiDocNode) error
+	checkDescendants = func(nodes []*WikiDocNode) error {
+		for _, child := range nodes {
+			if err := p.DeleteWikiDoc(userID, child.WikiDoc); err != nil {
+				return errors.Wrapf(err, "cannot delete descendant `%s`", child.ID)
+			}
+			if err := checkDescendants(child.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return checkDescendants(node.Children)
 }
 
+// WikiDocView checks that the user can read a wikiDoc. System admins can read every wikiDoc, and
+// members the wikiDocs they or their groups are granted. Other users can read the wikiDocs of the channels they
+// are members of, unless they are restricted to their members, and guests only the public ones among
+// those, see PublicStatuses.
+// The same rules are applied by the store when listing wikiDocs.
 func (p *PermissionsService) WikiDocView(userID string, wikiDocID string) error {
 	wikiDoc, err := p.wikiDocsService.Get(wikiDocID)
 	if err != nil {
 		return errors.Wrapf(err, "Unable to get wikidoc to determine permissions, wikiDoc id `%s`", wikiDocID)
 	}
 
-	if p.canReadChannel(userID, wikiDoc.ChannelID) {
+	if wikiDoc.DeleteAt != 0 {
+		// Trashed wikiDocs are only shown to the users who can restore them.
+		if err = p.checkWikiDocRole(userID, wikiDoc, RoleEditor); err != nil {
+			return errors.Wrapf(ErrNotFound, "wikiDoc `%s` is in the trash", wikiDocID)
+		}
 		return nil
 	}
 
-	return ErrNoPermissions
+	if IsSystemAdmin(userID, p.pluginAPI) {
+		return nil
+	}
+
+	roles, err := p.memberService.GetChannelRoles(userID, wikiDoc.ChannelID)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to get roles to determine permissions, wikiDoc id `%s`", wikiDocID)
+	}
+	if roles.RoleOn(wikiDoc).Allows(RoleViewer) {
+		return nil
+	}
+
+	if wikiDoc.Restricted || !p.canReadChannel(userID, wikiDoc.ChannelID) {
+		return ErrNoPermissions
+	}
+
+	isGuest, err := IsGuest(userID, p.pluginAPI)
+	if err != nil {
+		return err
+	}
+	if isGuest && !p.WikiDocIsPublic(wikiDoc) {
+		return ErrNoPermissions
+	}
+
+	return nil
 }
 
+// WikiDocList checks that the user can list the wikiDocs of a channel, because they are members of the
+// channel or one of their groups is granted its wiki. Guests can list them but only see the public
+// ones, see FilterWikiDocTree.
 func (p *PermissionsService) WikiDocList(userID string, channelID string) error {
-	// Can list wikiDocs if you are on the team
-	if p.canReadChannel(userID, channelID) {
+	if IsSystemAdmin(userID, p.pluginAPI) || p.canReadChannel(userID, channelID) {
+		return nil
+	}
+
+	roles, err := p.memberService.GetChannelRoles(userID, channelID)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to get roles to determine permissions, channel id `%s`", channelID)
+	}
+	if roles.Channel.Allows(RoleViewer) {
 		return nil
 	}
 
 	return ErrNoPermissions
 }
 
-func (p *PermissionsService) WikiDocMakePrivate(userID string, wikiDoc WikiDoc) error {
-	if IsSystemAdmin(userID, p.pluginAPI) || CanManageChannelProperties(userID, wikiDoc.ChannelID, p.pluginAPI) {
-		return nil
+// FilterWikiDocTree removes the wikiDocs the user cannot read from a channel tree they can list,
+// following the rules of WikiDocView. The visible children of a hidden wikiDoc take its place.
+func (p *PermissionsService) FilterWikiDocTree(userID, channelID string, tree []*WikiDocNode) ([]*WikiDocNode, error) {
+	if IsSystemAdmin(userID, p.pluginAPI) {
+		return tree, nil
 	}
 
-	return ErrNoPermissions
+	isGuest, err := IsGuest(userID, p.pluginAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := p.memberService.GetChannelRoles(userID, channelID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get roles to determine permissions, channel id `%s`", channelID)
+	}
+	canReadChannel := p.canReadChannel(userID, channelID)
+
+	return filterWikiDocTree(tree, func(wikiDoc WikiDoc) bool {
+		if roles.RoleOn(wikiDoc).Allows(RoleViewer) {
+			return true
+		}
+		return canReadChannel && !wikiDoc.Restricted && (!isGuest || p.WikiDocIsPublic(wikiDoc))
+	}), nil
 }
 
-func (p *PermissionsService) WikiDocMakePublic(userID string, wikiDoc WikiDoc) error {
-	if IsSystemAdmin(userID, p.pluginAPI) || CanManageChannelProperties(userID, wikiDoc.ChannelID, p.pluginAPI) {
-		return nil
+// FilterTrash removes from the trash of a channel the wikiDocs the user cannot edit, following the
+// rules of HasEditPermissionsToWikiDocs.
+func (p *PermissionsService) FilterTrash(userID, channelID string, trash []WikiDoc) ([]WikiDoc, error) {
+	if IsSystemAdmin(userID, p.pluginAPI) {
+		return trash, nil
 	}
 
-	return ErrNoPermissions
+	roles, err := p.memberService.GetChannelRoles(userID, channelID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get roles to determine permissions, channel id `%s`", channelID)
+	}
+	canManageChannel := CanManageChannelProperties(userID, channelID, p.pluginAPI)
+
+	filtered := []WikiDoc{}
+	for _, wikiDoc := range trash {
+		if roles.RoleOn(wikiDoc).Allows(RoleEditor) || (!wikiDoc.Restricted && canManageChannel) {
+			filtered = append(filtered, wikiDoc)
+		}
+	}
+
+	return filtered, nil
+}
+
+// filterWikiDocTree keeps the nodes of a tree whose wikiDoc is visible, lifting the visible children
+// of the hidden ones.
+func filterWikiDocTree(nodes []*WikiDocNode, visible func(WikiDoc) bool) []*WikiDocNode {
+	filtered := []*WikiDocNode{}
+	for _, node := range nodes {
+		children := filterWikiDocTree(node.Children, visible)
+		if !visible(node.WikiDoc) {
+			filtered = append(filtered, children...)
+			continue
+		}
+
+		node.Children = children
+		filtered = append(filtered, node)
+	}
+
+	return filtered
+}
+
+func (p *PermissionsService) WikiDocMakePrivate(userID string, wikiDoc WikiDoc) error {
+	return p.HasEditPermissionsToWikiDocs(userID, wikiDoc)
+}
+
+func (p *PermissionsService) WikiDocMakePublic(userID string, wikiDoc WikiDoc) error {
+	return p.HasEditPermissionsToWikiDocs(userID, wikiDoc)
 }
 
 // IsSystemAdmin returns true if the userID is a system admin
@@ -132,6 +313,12 @@ func CanPostToChannel(userID, channelID string, pluginAPI *pluginapi.Client) boo
 	return pluginAPI.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost)
 }
 
+// IsMemberOfChannel returns true if the userID is a member of channelID
+func IsMemberOfChannel(userID, channelID string, pluginAPI *pluginapi.Client) bool {
+	_, err := pluginAPI.Channel.GetMember(channelID, userID)
+	return err == nil
+}
+
 func IsMemberOfTeam(userID, teamID string, pluginAPI *pluginapi.Client) bool {
 	teamMember, err := pluginAPI.Team.GetMember(teamID, userID)
 	if err != nil {
@@ -148,6 +335,31 @@ type RequesterInfo struct {
 	TeamID  string
 	IsAdmin bool
 	IsGuest bool
+
+	// GroupIDs are the groups the user is a member of, which may be granted access to wikiDocs.
+	GroupIDs []string
+}
+
+// GetRequesterInfo returns the permissions of the user making a request, with the groups they are
+// members of.
+func (p *PermissionsService) GetRequesterInfo(userID string) (RequesterInfo, error) {
+	info, err := GetRequesterInfo(userID, p.pluginAPI)
+	if err != nil {
+		return RequesterInfo{}, err
+	}
+	if info.IsAdmin {
+		return info, nil
+	}
+
+	groupIDs, err := p.memberService.GetUserGroupIDs(userID)
+	if err != nil {
+		return RequesterInfo{}, errors.Wrapf(err, "Unable to get groups to determine permissions, user id `%s`", userID)
+	}
+	if len(groupIDs) > 0 {
+		info.GroupIDs = groupIDs
+	}
+
+	return info, nil
 }
 
 func GetRequesterInfo(userID string, pluginAPI *pluginapi.Client) (RequesterInfo, error) {
@@ -0,0 +1,349 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// maxRenderCacheEntries is the maximum number of rendered wikiDocs kept in memory.
+const maxRenderCacheEntries = 1000
+
+// wikiLinkStartPattern matches a [[link]] at the start of a text.
+var wikiLinkStartPattern = regexp.MustCompile(`^` + linkPattern.String())
+
+// markdown renders the Markdown of wikiDocs: CommonMark with the tables, strikethrough, task lists
+// and autolinks of GitHub, and [[links]]. Raw HTML is left out.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.TaskList,
+		extension.Linkify,
+	),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 199)),
+	),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(wikiDocRenderer{}, 100)),
+	),
+)
+
+// Heading is a heading of the content of a wikiDoc.
+type Heading struct {
+	// Level is the level of the heading, from 1 to 6.
+	Level int `json:"level"`
+
+	// Text is the text of the heading, without its Markdown.
+	Text string `json:"text"`
+
+	// Slug is the ID of the heading in the rendered HTML, unique within the wikiDoc.
+	Slug string `json:"slug"`
+}
+
+// RenderedWikiDoc is the content of a wikiDoc rendered to HTML.
+type RenderedWikiDoc struct {
+	WikiDocID string `json:"wikidoc_id"`
+
+	// UpdateAt is the version of the wikiDoc that was rendered.
+	UpdateAt int64 `json:"update_at"`
+
+	// HTML is the sanitized HTML of the content. Headings have an ID and an anchor linking to it.
+	HTML string `json:"html"`
+
+	// TOC is the table of contents of the content, a list of links to its headings nested by
+	// level, empty if the content has no headings.
+	TOC string `json:"toc"`
+
+	// Outline lists the headings of the content, in order.
+	Outline []Heading `json:"outline"`
+}
+
+// Render renders the content of a wikiDoc. The [[links]] that resolve to a wikiDoc the reader can
+// view point to it on the plugin API, the others are marked as missing. Renders are cached by
+// version of the wikiDoc and of its links.
+func (s *wikiDocsService) Render(wikiDoc WikiDoc, canView func(wikiDocID string) bool) (*RenderedWikiDoc, error) {
+	links, err := s.GetLinks(wikiDoc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(links))
+	var linkKey strings.Builder
+	for _, link := range links {
+		if !link.Missing && canView(link.TargetID) {
+			targets[link.Target] = link.TargetID
+		}
+		fmt.Fprintf(&linkKey, "%s\x00%s\x00", link.Target, targets[link.Target])
+	}
+
+	if rendered, ok := s.renders.get(wikiDoc.ID, wikiDoc.UpdateAt, linkKey.String()); ok {
+		return &rendered, nil
+	}
+
+	site := siteURL(s.api)
+	rendered, err := RenderMarkdown(wikiDoc.Content, func(target string) string {
+		if id, ok := targets[strings.TrimSpace(target)]; ok {
+			return wikiDocLink(site, WikiDoc{ID: id})
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render wikiDoc %s", wikiDoc.ID)
+	}
+	rendered.WikiDocID = wikiDoc.ID
+	rendered.UpdateAt = wikiDoc.UpdateAt
+
+	s.renders.put(wikiDoc.ID, wikiDoc.UpdateAt, linkKey.String(), *rendered)
+
+	return rendered, nil
+}
+
+// RenderMarkdown renders Markdown to sanitized HTML, with its table of contents and outline.
+// resolve returns the URL a [[link]] target points to, empty if it is missing.
+func RenderMarkdown(content string, resolve func(target string) string) (*RenderedWikiDoc, error) {
+	source := []byte(content)
+	document := markdown.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext(parser.WithIDs(newHeadingIDs()))))
+
+	outline := []Heading{}
+	err := ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.Heading:
+			slug, _ := node.AttributeString("id")
+			slugBytes, _ := slug.([]byte)
+			outline = append(outline, Heading{
+				Level: node.Level,
+				Text:  strings.TrimSpace(string(node.Text(source))),
+				Slug:  string(slugBytes),
+			})
+		case *wikiLinkNode:
+			node.URL = resolve(node.Target)
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err = markdown.Renderer().Render(&out, source, document); err != nil {
+		return nil, err
+	}
+
+	return &RenderedWikiDoc{
+		HTML:    SanitizeHTML(out.String()),
+		TOC:     tableOfContents(outline),
+		Outline: outline,
+	}, nil
+}
+
+// tableOfContents returns the nested lists of links to the headings of an outline. A heading more
+// than one level below the previous one is nested a single level deeper.
+func tableOfContents(outline []Heading) string {
+	if len(outline) == 0 {
+		return ""
+	}
+
+	top := outline[0].Level
+	for _, heading := range outline {
+		top = minInt(top, heading.Level)
+	}
+
+	var toc strings.Builder
+	toc.WriteString(`<nav class="toc">`)
+	depth := 0
+	for _, heading := range outline {
+		level := heading.Level - top + 1
+		switch {
+		case level > depth:
+			toc.WriteString("<ul><li>")
+			depth++
+		default:
+			toc.WriteString("</li>")
+			for ; depth > level; depth-- {
+				toc.WriteString("</ul></li>")
+			}
+			toc.WriteString("<li>")
+		}
+		fmt.Fprintf(&toc, `<a href="#%s">%s</a>`, html.EscapeString(heading.Slug), html.EscapeString(heading.Text))
+	}
+	for ; depth > 0; depth-- {
+		toc.WriteString("</li></ul>")
+	}
+	toc.WriteString("</nav>")
+
+	return toc.String()
+}
+
+// headingIDs generates the IDs of the headings of a content: the slug of their text, numbered
+// when it is already used.
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: map[string]bool{}}
+}
+
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	slug := "section"
+	if name := string(value); strings.TrimSpace(name) != "" {
+		slug = exportSlug(name)
+	}
+	return []byte(uniqueSlug(slug, ids.used))
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.used[string(value)] = true
+}
+
+// kindWikiLink is the kind of the [[link]] nodes.
+var kindWikiLink = ast.NewNodeKind("WikiLink")
+
+// wikiLinkNode is a [[target|label]] link.
+type wikiLinkNode struct {
+	ast.BaseInline
+	Target string
+	Label  string
+
+	// URL is the URL of the wikiDoc the target resolves to, empty if it is missing.
+	URL string
+}
+
+func (n *wikiLinkNode) Kind() ast.NodeKind {
+	return kindWikiLink
+}
+
+// Text returns the text the link is displayed with.
+func (n *wikiLinkNode) Text(source []byte) []byte {
+	if n.Label != "" {
+		return []byte(n.Label)
+	}
+	return []byte(n.Target)
+}
+
+func (n *wikiLinkNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.Target, "Label": n.Label}, nil)
+}
+
+// wikiLinkParser parses the [[links]] of a content, before the Markdown links.
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	match := wikiLinkStartPattern.FindSubmatch(line)
+	if match == nil || strings.TrimSpace(string(match[1])) == "" {
+		return nil
+	}
+	block.Advance(len(match[0]))
+
+	return &wikiLinkNode{
+		Target: strings.TrimSpace(string(match[1])),
+		Label:  strings.TrimSpace(string(match[2])),
+	}
+}
+
+// wikiDocRenderer renders the [[links]], and the headings with an anchor linking to them.
+type wikiDocRenderer struct{}
+
+func (r wikiDocRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(kindWikiLink, r.renderWikiLink)
+	registerer.Register(ast.KindHeading, r.renderHeading)
+}
+
+func (r wikiDocRenderer) renderWikiLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*wikiLinkNode)
+	label := html.EscapeString(string(n.Text(source)))
+	if n.URL == "" {
+		fmt.Fprintf(w, `<span class="wiki-link missing" title="%s">%s</span>`, html.EscapeString(n.Target), label)
+	} else {
+		fmt.Fprintf(w, `<a class="wiki-link" href="%s">%s</a>`, html.EscapeString(n.URL), label)
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+func (r wikiDocRenderer) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Heading)
+	id, _ := n.AttributeString("id")
+	slug, _ := id.([]byte)
+
+	if entering {
+		fmt.Fprintf(w, `<h%d id="%s">`, n.Level, html.EscapeString(string(slug)))
+	} else {
+		fmt.Fprintf(w, ` <a class="anchor" href="#%s">#</a></h%d>`+"\n", html.EscapeString(string(slug)), n.Level)
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// renderCache keeps the latest renders of wikiDocs, by version.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]renderCacheEntry
+	max     int
+}
+
+type renderCacheEntry struct {
+	updateAt int64
+	linkKey  string
+	rendered RenderedWikiDoc
+}
+
+func newRenderCache(max int) *renderCache {
+	return &renderCache{
+		entries: map[string]renderCacheEntry{},
+		max:     max,
+	}
+}
+
+// get returns the render of a version of a wikiDoc, if it is cached.
+func (c *renderCache) get(wikiDocID string, updateAt int64, linkKey string) (RenderedWikiDoc, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[wikiDocID]
+	if !ok || entry.updateAt != updateAt || entry.linkKey != linkKey {
+		return RenderedWikiDoc{}, false
+	}
+	return entry.rendered, true
+}
+
+// put caches the render of a version of a wikiDoc, replacing its previous versions. When the cache
+// is full, an arbitrary entry is dropped.
+func (c *renderCache) put(wikiDocID string, updateAt int64, linkKey string, rendered RenderedWikiDoc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[wikiDocID]; !ok && len(c.entries) >= c.max {
+		for id := range c.entries {
+			delete(c.entries, id)
+			break
+		}
+	}
+	c.entries[wikiDocID] = renderCacheEntry{updateAt: updateAt, linkKey: linkKey, rendered: rendered}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	content := "# Guide\n\nSee [[Install|the install]], [[Missing]] and `[[code]]`.\n\n" +
+		"## Setup *now*\n\n#### Deep\n\n## Setup now\n\n- [x] done\n\n| a | b |\n|:-|-:|\n| 1 | ~~2~~ |\n"
+
+	rendered, err := RenderMarkdown(content, func(target string) string {
+		if target == "Install" {
+			return "/plugins/wiki/api/v0/wikiDocs/abc"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `<h1 id="guide">Guide <a class="anchor" href="#guide">#</a></h1>
+<p>See <a class="wiki-link" href="/plugins/wiki/api/v0/wikiDocs/abc">the install</a>, <span class="wiki-link missing" title="Missing">Missing</span> and <code>[[code]]</code>.</p>
+<h2 id="setup-now">Setup <em>now</em> <a class="anchor" href="#setup-now">#</a></h2>
+<h4 id="deep">Deep <a class="anchor" href="#deep">#</a></h4>
+<h2 id="setup-now-2">Setup now <a class="anchor" href="#setup-now-2">#</a></h2>
+<ul>
+<li><input checked="" type="checkbox" disabled=""> done</li>
+</ul>
+<table>
+<thead>
+<tr>
+<th align="left">a</th>
+<th align="right">b</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td align="left">1</td>
+<td align="right"><del>2</del></td>
+</tr>
+</tbody>
+</table>
+`, rendered.HTML)
+
+	assert.Equal(t, []Heading{
+		{Level: 1, Text: "Guide", Slug: "guide"},
+		{Level: 2, Text: "Setup now", Slug: "setup-now"},
+		{Level: 4, Text: "Deep", Slug: "deep"},
+		{Level: 2, Text: "Setup now", Slug: "setup-now-2"},
+	}, rendered.Outline)
+
+	assert.Equal(t, `<nav class="toc"><ul><li><a href="#guide">Guide</a>`+
+		`<ul><li><a href="#setup-now">Setup now</a><ul><li><a href="#deep">Deep</a></li></ul></li>`+
+		`<li><a href="#setup-now-2">Setup now</a></li></ul></li></ul></nav>`, rendered.TOC)
+
+	t.Run("no headings", func(t *testing.T) {
+		rendered, err := RenderMarkdown("Just text", func(string) string { return "" })
+		require.NoError(t, err)
+		assert.Equal(t, "<p>Just text</p>\n", rendered.HTML)
+		assert.Empty(t, rendered.TOC)
+		assert.Empty(t, rendered.Outline)
+	})
+
+	t.Run("unsafe markdown", func(t *testing.T) {
+		rendered, err := RenderMarkdown("<script>alert(1)</script>\n\n[x](javascript:alert(1)) <img src=x onerror=alert(1)>", func(string) string { return "" })
+		require.NoError(t, err)
+		assert.NotContains(t, rendered.HTML, "<script")
+		assert.NotContains(t, rendered.HTML, "javascript:")
+		assert.NotContains(t, rendered.HTML, "onerror")
+	})
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	for name, test := range map[string]struct {
+		html     string
+		expected string
+	}{
+		"allowed": {
+			`<p><a href="https://example.com" title="t">a</a> <code class="language-go">b</code></p>`,
+			`<p><a href="https://example.com" title="t">a</a> <code class="language-go">b</code></p>`,
+		},
+		"unknown elements keep their text": {
+			`<div><font color="red">text</font></div>`,
+			`text`,
+		},
+		"dropped elements": {
+			`<p>a<script>alert("<p>")</script><style>p{}</style>b</p>`,
+			`<p>ab</p>`,
+		},
+		"unsafe attributes": {
+			`<a href="javascript:alert(1)" onclick="x()">a</a><img src="data:text/html,x" alt="b"><span class="a&quot;b" style="x">c</span>`,
+			`<a>a</a><img alt="b"><span>c</span>`,
+		},
+		"inputs": {
+			`<input type="text" value="x"><input type="checkbox" checked name="y">`,
+			`<input checked="" type="checkbox" disabled="">`,
+		},
+		"comments and entities": {
+			`<!-- raw HTML omitted --><p>&lt;b&gt; &amp;</p>`,
+			`<p>&lt;b&gt; &amp;</p>`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, SanitizeHTML(test.html))
+		})
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	cache := newRenderCache(2)
+
+	cache.put("a", 1, "", RenderedWikiDoc{HTML: "a1"})
+	rendered, ok := cache.get("a", 1, "")
+	require.True(t, ok)
+	assert.Equal(t, "a1", rendered.HTML)
+
+	_, ok = cache.get("a", 2, "")
+	assert.False(t, ok, "another version of the wikiDoc")
+	_, ok = cache.get("a", 1, "Install\x00abc\x00")
+	assert.False(t, ok, "another version of the links")
+
+	cache.put("a", 2, "", RenderedWikiDoc{HTML: "a2"})
+	_, ok = cache.get("a", 1, "")
+	assert.False(t, ok, "the previous version is replaced")
+
+	cache.put("b", 1, "", RenderedWikiDoc{HTML: "b1"})
+	cache.put("c", 1, "", RenderedWikiDoc{HTML: "c1"})
+	assert.Len(t, cache.entries, 2)
+	_, ok = cache.get("c", 1, "")
+	assert.True(t, ok)
+}
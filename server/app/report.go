@@ -0,0 +1,112 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+
+	root "github.com/CyberPeace-Institute/mattermost-plugin-wiki"
+)
+
+// ReportIssueKind is the kind of problem found in the wiki of a channel.
+type ReportIssueKind string
+
+const (
+	// ReportIssueBrokenLink is a link whose target is not a wikiDoc or a post anymore.
+	ReportIssueBrokenLink ReportIssueKind = "broken_link"
+
+	// ReportIssueOrphan is a wikiDoc no other wikiDoc links to.
+	ReportIssueOrphan ReportIssueKind = "orphan"
+)
+
+var (
+	// permalinkPattern matches the permalinks to Mattermost posts.
+	permalinkPattern = regexp.MustCompile(`https?://[^\s()<>\[\]]+/pl/([a-z0-9]{26})`)
+
+	// wikiDocURLPattern matches the URLs of wikiDocs on the plugin API, see wikiDocLink.
+	wikiDocURLPattern = regexp.MustCompile(`(?:https?://[^\s()<>\[\]]*)?/plugins/` + regexp.QuoteMeta(root.Manifest.Id) + `/api/v0/wikiDocs/([a-z0-9]{26})`)
+)
+
+// ReportIssue is a problem found in a wikiDoc.
+type ReportIssue struct {
+	Kind        ReportIssueKind `json:"kind"`
+	WikiDocID   string          `json:"wiki_doc_id"`
+	WikiDocName string          `json:"wiki_doc_name"`
+
+	// Target is the target of a broken link, empty for orphans.
+	Target string `json:"target,omitempty"`
+}
+
+// WikiReport lists the broken links and orphaned wikiDocs of the wiki of a channel. Restricted
+// wikiDocs are left out, as the channel admins cannot necessarily read them.
+type WikiReport struct {
+	ChannelID    string        `json:"channel_id"`
+	TeamID       string        `json:"team_id"`
+	WikiDocCount int           `json:"wiki_doc_count"`
+	Issues       []ReportIssue `json:"issues"`
+	CreateAt     int64         `json:"create_at"`
+}
+
+// ReportStore is an interface for storing the reports of channel wikis
+type ReportStore interface {
+	// GetReport retrieves the last report of a channel. Returns ErrNotFound if there is none.
+	GetReport(channelID string) (WikiReport, error)
+
+	// SaveReport creates or replaces the report of a channel
+	SaveReport(report WikiReport) error
+
+	// GetTeamIDs retrieves the teams with wikiDocs outside of the trash
+	GetTeamIDs() ([]string, error)
+
+	// GetWikiDocsForTeam retrieves the wikiDocs of a team that are not in the trash, with their
+	// content, oldest first
+	GetWikiDocsForTeam(teamID string) ([]WikiDoc, error)
+}
+
+// teamWiki indexes the wikiDocs of a team to resolve their links.
+type teamWiki struct {
+	byID   map[string]WikiDoc
+	byName map[string][]WikiDoc
+}
+
+func newTeamWiki(wikiDocs []WikiDoc) *teamWiki {
+	wiki := &teamWiki{
+		byID:   map[string]WikiDoc{},
+		byName: map[string][]WikiDoc{},
+	}
+	for _, wikiDoc := range wikiDocs {
+		wiki.byID[wikiDoc.ID] = wikiDoc
+		name := strings.ToLower(strings.TrimSpace(wikiDoc.Name))
+		wiki.byName[name] = append(wiki.byName[name], wikiDoc)
+	}
+
+	return wiki
+}
+
+// resolve returns the ID of the wikiDoc a [[link]] of the source points to, empty if there is none.
+// Targets are resolved as by wikiDocsService.resolveLink.
+func (w *teamWiki) resolve(source WikiDoc, target string) string {
+	if model.IsValidId(target) {
+		if wikiDoc, ok := w.byID[target]; ok {
+			return wikiDoc.ID
+		}
+	}
+
+	return pickLinkTarget(w.byName[strings.ToLower(target)], source.ChannelID)
+}
+
+// pickLinkTarget returns the ID of the candidate in the channel of the source, or else of the first
+// candidate. Returns an empty ID when there is no candidate.
+func pickLinkTarget(candidates []WikiDoc, channelID string) string {
+	for _, candidate := range candidates {
+		if candidate.ChannelID == channelID {
+			return candidate.ID
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0].ID
+	}
+
+	return ""
+}
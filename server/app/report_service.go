@@ -0,0 +1,259 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+// reportPostMaxIssues is the maximum number of issues listed when posting a report.
+const reportPostMaxIssues = 50
+
+// ReportService finds the broken links and orphaned wikiDocs of channel wikis
+type ReportService interface {
+	// Get retrieves the last report of a channel. Returns ErrNotFound if the channel was not scanned
+	// yet.
+	Get(channelID string) (WikiReport, error)
+
+	// Generate scans the wikiDocs of the team of a channel, stores the reports of its channels and
+	// returns the report of the channel.
+	Generate(channelID string) (WikiReport, error)
+
+	// GenerateAll scans the wikiDocs of every team and stores the reports of their channels. Returns
+	// the number of reports stored.
+	GenerateAll() (int, error)
+
+	// Post posts the last report of a channel in the channel. Returns ErrNotFound if the channel
+	// was not scanned yet.
+	Post(channelID string) error
+}
+
+type reportService struct {
+	store           ReportStore
+	wikiDocsService WikiDocService
+	poster          bot.Poster
+	api             *pluginapi.Client
+	logger          bot.Logger
+}
+
+func NewReportService(store ReportStore, wikiDocsService WikiDocService, poster bot.Poster, logger bot.Logger, api *pluginapi.Client) ReportService {
+	return &reportService{
+		store:           store,
+		wikiDocsService: wikiDocsService,
+		poster:          poster,
+		api:             api,
+		logger:          logger,
+	}
+}
+
+func (s *reportService) Get(channelID string) (WikiReport, error) {
+	return s.store.GetReport(channelID)
+}
+
+func (s *reportService) Generate(channelID string) (WikiReport, error) {
+	channel, err := s.api.Channel.Get(channelID)
+	if err != nil {
+		return WikiReport{}, errors.Wrapf(err, "failed to get channel %s", channelID)
+	}
+
+	reports, err := s.generateTeam(channel.TeamId)
+	if err != nil {
+		return WikiReport{}, err
+	}
+
+	// A channel without wikiDocs has nothing to report, but was scanned.
+	report, ok := reports[channelID]
+	if !ok {
+		report = WikiReport{ChannelID: channelID, TeamID: channel.TeamId, Issues: []ReportIssue{}, CreateAt: model.GetMillis()}
+		if err = s.store.SaveReport(report); err != nil {
+			return WikiReport{}, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *reportService) GenerateAll() (int, error) {
+	teamIDs, err := s.store.GetTeamIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, teamID := range teamIDs {
+		reports, err := s.generateTeam(teamID)
+		if err != nil {
+			return count, err
+		}
+		count += len(reports)
+	}
+
+	return count, nil
+}
+
+// generateTeam scans the wikiDocs of a team and stores the reports of its channels, by channel ID.
+func (s *reportService) generateTeam(teamID string) (map[string]WikiReport, error) {
+	wikiDocs, err := s.store.GetWikiDocsForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := scanTeamWiki(wikiDocs, s.wikiDocExists, s.postExists, model.GetMillis())
+	for _, report := range reports {
+		if err = s.store.SaveReport(report); err != nil {
+			return nil, err
+		}
+	}
+
+	return reports, nil
+}
+
+// wikiDocExists returns true if the wikiDoc exists and is not in the trash.
+func (s *reportService) wikiDocExists(id string) bool {
+	wikiDoc, err := s.wikiDocsService.Get(id)
+	return err == nil && wikiDoc.DeleteAt == 0
+}
+
+// postExists returns true if the post exists and was not deleted.
+func (s *reportService) postExists(id string) bool {
+	post, err := s.api.Post.GetPost(id)
+	return err == nil && post.DeleteAt == 0
+}
+
+func (s *reportService) Post(channelID string) error {
+	report, err := s.store.GetReport(channelID)
+	if err != nil {
+		return err
+	}
+
+	return s.poster.Post(channelID, reportPost(report, siteURL(s.api)))
+}
+
+// scanTeamWiki finds the broken links and orphaned wikiDocs among the wikiDocs of a team, and
+// returns the reports of their channels by channel ID. The [[links]], links to wikiDocs and
+// permalinks of the content are checked: wikiDocs of other teams and posts are looked up with the
+// given functions, at most once each. The links of restricted wikiDocs make their targets linked,
+// but their issues are not reported.
+func scanTeamWiki(wikiDocs []WikiDoc, wikiDocExists, postExists func(id string) bool, now int64) map[string]WikiReport {
+	wiki := newTeamWiki(wikiDocs)
+	cached := func(exists func(string) bool) func(string) bool {
+		known := map[string]bool{}
+		return func(id string) bool {
+			if _, ok := known[id]; !ok {
+				known[id] = exists(id)
+			}
+			return known[id]
+		}
+	}
+	wikiDocExists, postExists = cached(wikiDocExists), cached(postExists)
+
+	reports := map[string]WikiReport{}
+	linked := map[string]bool{}
+	for _, wikiDoc := range wikiDocs {
+		report, ok := reports[wikiDoc.ChannelID]
+		if !ok {
+			report = WikiReport{ChannelID: wikiDoc.ChannelID, TeamID: wikiDoc.TeamID, Issues: []ReportIssue{}, CreateAt: now}
+		}
+		report.WikiDocCount++
+
+		var broken []string
+		link := func(targetID string) {
+			if targetID != wikiDoc.ID {
+				linked[targetID] = true
+			}
+		}
+
+		for _, wikiLink := range parseLinks(wikiDoc.Content) {
+			if targetID := wiki.resolve(wikiDoc, wikiLink.Target); targetID != "" {
+				link(targetID)
+			} else {
+				broken = append(broken, wikiLink.Target)
+			}
+		}
+
+		content := stripCode(wikiDoc.Content)
+		for _, match := range wikiDocURLPattern.FindAllStringSubmatch(content, -1) {
+			if _, ok := wiki.byID[match[1]]; ok {
+				link(match[1])
+			} else if !wikiDocExists(match[1]) {
+				broken = append(broken, match[0])
+			}
+		}
+		for _, match := range permalinkPattern.FindAllStringSubmatch(content, -1) {
+			if !postExists(match[1]) {
+				broken = append(broken, match[0])
+			}
+		}
+
+		if !wikiDoc.Restricted {
+			for _, target := range broken {
+				report.Issues = append(report.Issues, ReportIssue{
+					Kind:        ReportIssueBrokenLink,
+					WikiDocID:   wikiDoc.ID,
+					WikiDocName: wikiDoc.Name,
+					Target:      target,
+				})
+			}
+		}
+		reports[wikiDoc.ChannelID] = report
+	}
+
+	for _, wikiDoc := range wikiDocs {
+		if linked[wikiDoc.ID] || wikiDoc.Restricted {
+			continue
+		}
+
+		report := reports[wikiDoc.ChannelID]
+		report.Issues = append(report.Issues, ReportIssue{
+			Kind:        ReportIssueOrphan,
+			WikiDocID:   wikiDoc.ID,
+			WikiDocName: wikiDoc.Name,
+		})
+		reports[wikiDoc.ChannelID] = report
+	}
+
+	return reports
+}
+
+// reportPost is the post listing the issues of a report, up to reportPostMaxIssues of them.
+func reportPost(report WikiReport, siteURL string) *model.Post {
+	scannedAt := time.UnixMilli(report.CreateAt).UTC().Format("2006-01-02 15:04 MST")
+	message := fmt.Sprintf("#### Wiki report\nScanned %d wiki docs on %s.", report.WikiDocCount, scannedAt)
+	if len(report.Issues) == 0 {
+		return &model.Post{Message: message + " No broken links or orphaned docs were found."}
+	}
+
+	var brokenLinks, orphans []string
+	for i, issue := range report.Issues {
+		if i == reportPostMaxIssues {
+			break
+		}
+
+		link := fmt.Sprintf("[%s](%s)", issue.WikiDocName, wikiDocLink(siteURL, WikiDoc{ID: issue.WikiDocID}))
+		switch issue.Kind {
+		case ReportIssueBrokenLink:
+			brokenLinks = append(brokenLinks, fmt.Sprintf("* %s links to `%s`", link, issue.Target))
+		case ReportIssueOrphan:
+			orphans = append(orphans, "* "+link)
+		}
+	}
+
+	if len(brokenLinks) > 0 {
+		message += "\n\n**Broken links**\n" + strings.Join(brokenLinks, "\n")
+	}
+	if len(orphans) > 0 {
+		message += "\n\n**Docs no other doc links to**\n" + strings.Join(orphans, "\n")
+	}
+	if len(report.Issues) > reportPostMaxIssues {
+		message += fmt.Sprintf("\n\nAnd %d more issues.", len(report.Issues)-reportPostMaxIssues)
+	}
+
+	return &model.Post{Message: message}
+}
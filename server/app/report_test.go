@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanTeamWiki(t *testing.T) {
+	channelID, otherChannelID := model.NewId(), model.NewId()
+	goneDocID, livePostID, gonePostID := model.NewId(), model.NewId(), model.NewId()
+
+	home := WikiDoc{ID: model.NewId(), Name: "Home", ChannelID: channelID}
+	guide := WikiDoc{ID: model.NewId(), Name: "Guide", ChannelID: channelID}
+	secret := WikiDoc{ID: model.NewId(), Name: "Secret", ChannelID: channelID, Restricted: true}
+	faq := WikiDoc{ID: model.NewId(), Name: "FAQ", ChannelID: otherChannelID}
+
+	home.Content = "Read the [[guide]], [[Missing page]] and " +
+		"https://chat.example.com/plugins/cpi.wiki/api/v0/wikiDocs/" + goneDocID + "."
+	guide.Content = "Back [[" + home.ID + "|home]], see https://chat.example.com/team/pl/" + livePostID +
+		" and https://chat.example.com/team/pl/" + gonePostID + ". `[[Ignored]]`"
+	secret.Content = "[[FAQ]] [[Nowhere]]"
+
+	postLookups := 0
+	reports := scanTeamWiki(
+		[]WikiDoc{home, guide, secret, faq},
+		func(id string) bool { return false },
+		func(id string) bool {
+			postLookups++
+			return id == livePostID
+		},
+		42,
+	)
+	require.Len(t, reports, 2)
+	assert.Equal(t, 2, postLookups)
+
+	report := reports[channelID]
+	assert.Equal(t, 3, report.WikiDocCount)
+	assert.Equal(t, int64(42), report.CreateAt)
+	assert.Equal(t, []ReportIssue{
+		{Kind: ReportIssueBrokenLink, WikiDocID: home.ID, WikiDocName: "Home", Target: "Missing page"},
+		{Kind: ReportIssueBrokenLink, WikiDocID: home.ID, WikiDocName: "Home", Target: "https://chat.example.com/plugins/cpi.wiki/api/v0/wikiDocs/" + goneDocID},
+		{Kind: ReportIssueBrokenLink, WikiDocID: guide.ID, WikiDocName: "Guide", Target: "https://chat.example.com/team/pl/" + gonePostID},
+	}, report.Issues, "restricted docs are left out, and link their targets")
+
+	assert.Equal(t, 1, reports[otherChannelID].WikiDocCount)
+	assert.Empty(t, reports[otherChannelID].Issues)
+}
+
+func TestScanTeamWikiOrphans(t *testing.T) {
+	channelID := model.NewId()
+	linked := WikiDoc{ID: model.NewId(), Name: "Linked", ChannelID: channelID}
+	self := WikiDoc{ID: model.NewId(), Name: "Self", ChannelID: channelID, Content: "[[Self]] [[Linked]]"}
+
+	reports := scanTeamWiki([]WikiDoc{linked, self}, nil, nil, 0)
+	assert.Equal(t, []ReportIssue{
+		{Kind: ReportIssueOrphan, WikiDocID: self.ID, WikiDocName: "Self"},
+	}, reports[channelID].Issues, "links to itself do not count")
+}
@@ -0,0 +1,50 @@
+package app
+
+// ReviewState is the decision of a reviewer on a wikiDoc.
+type ReviewState string
+
+const (
+	ReviewStatePending          ReviewState = "pending"
+	ReviewStateApproved         ReviewState = "approved"
+	ReviewStateChangesRequested ReviewState = "changes_requested"
+)
+
+// Review is the review of a wikiDoc by one reviewer. A reviewer has at most one review per wikiDoc;
+// requesting their review again sets it back to pending.
+type Review struct {
+	ID        string `json:"id"`
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// ReviewerID is the user asked to review the wikiDoc.
+	ReviewerID string `json:"reviewer_id"`
+
+	// RequesterID is the user who asked for the review.
+	RequesterID string `json:"requester_id"`
+
+	State ReviewState `json:"state"`
+
+	// Comment explains the decision of the reviewer. It is required when requesting changes.
+	Comment string `json:"comment"`
+
+	// WikiDocUpdateAt is the version of the wikiDoc the reviewer was asked to review, then the
+	// version they decided on. Approvals only count for that version.
+	WikiDocUpdateAt int64 `json:"wiki_doc_update_at"`
+
+	CreateAt int64 `json:"create_at"`
+	UpdateAt int64 `json:"update_at"`
+}
+
+// ReviewStore is an interface for storing the reviews of wikiDocs
+type ReviewStore interface {
+	// GetReviews retrieves the reviews of a wikiDoc, oldest first
+	GetReviews(wikiDocID string) ([]Review, error)
+
+	// GetReview retrieves the review of a wikiDoc by a reviewer
+	GetReview(wikiDocID, reviewerID string) (Review, error)
+
+	// SaveReview creates the review of a wikiDoc by a reviewer, or replaces the existing one
+	SaveReview(review Review) (Review, error)
+
+	// CountApprovals returns the number of reviewers who approved the given version of a wikiDoc
+	CountApprovals(wikiDocID string, updateAt int64) (int, error)
+}
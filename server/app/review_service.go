@@ -0,0 +1,270 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	root "github.com/CyberPeace-Institute/mattermost-plugin-wiki"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const (
+	// ReviewActionApprove is the action of the Approve button of review requests.
+	ReviewActionApprove = "approve"
+
+	// ReviewActionReject is the action of the Reject button of review requests.
+	ReviewActionReject = "reject"
+
+	// ReviewContextWikiDocIDKey is the key of the wikiDoc ID in the context of review request buttons.
+	ReviewContextWikiDocIDKey = "wiki_doc_id"
+
+	// ReviewContextUpdateAtKey is the key of the version of the wikiDoc to review in the context of
+	// review request buttons, as a decimal string.
+	ReviewContextUpdateAtKey = "update_at"
+
+	// DialogFieldCommentKey is the key of the comment field of the request changes dialog.
+	DialogFieldCommentKey = "comment"
+)
+
+// ReviewService manages the reviews of wikiDocs
+type ReviewService interface {
+	// GetReviews retrieves the reviews of a wikiDoc, oldest first
+	GetReviews(wikiDocID string) ([]Review, error)
+
+	// RequestReviews asks the reviewers to review a wikiDoc on behalf of userID, and sends them a
+	// direct message to approve or reject it. A draft wikiDoc moves to In Review. Returns
+	// ErrMalformedReview if no reviewer is given or if the user asks for their own review.
+	RequestReviews(wikiDoc WikiDoc, reviewerIDs []string, userID string) ([]Review, error)
+
+	// Approve records the approval of the version updateAt of a wikiDoc by a reviewer. Returns
+	// ErrNotFound if the reviewer was not asked to review the wikiDoc, and ErrConflict if the
+	// wikiDoc was modified since that version.
+	Approve(wikiDocID, reviewerID string, updateAt int64, comment string) (Review, error)
+
+	// RequestChanges records that a reviewer requests changes to the version updateAt of a wikiDoc,
+	// explained by the comment. Returns ErrNotFound if the reviewer was not asked to review the
+	// wikiDoc, ErrConflict if the wikiDoc was modified since that version, and ErrMalformedReview if
+	// the comment is empty.
+	RequestChanges(wikiDocID, reviewerID string, updateAt int64, comment string) (Review, error)
+
+	// OpenRequestChangesDialog opens the interactive dialog asking a reviewer for the changes they
+	// request. The state of the dialog is given back on submission.
+	OpenRequestChangesDialog(triggerID, state string) error
+}
+
+type reviewService struct {
+	store           ReviewStore
+	wikiDocsService WikiDocService
+	poster          bot.Poster
+	api             *pluginapi.Client
+	logger          bot.Logger
+}
+
+func NewReviewService(store ReviewStore, wikiDocsService WikiDocService, poster bot.Poster, logger bot.Logger, api *pluginapi.Client) ReviewService {
+	return &reviewService{
+		store:           store,
+		wikiDocsService: wikiDocsService,
+		poster:          poster,
+		api:             api,
+		logger:          logger,
+	}
+}
+
+func (s *reviewService) GetReviews(wikiDocID string) ([]Review, error) {
+	return s.store.GetReviews(wikiDocID)
+}
+
+func (s *reviewService) RequestReviews(wikiDoc WikiDoc, reviewerIDs []string, userID string) ([]Review, error) {
+	if wikiDoc.DeleteAt != 0 {
+		return nil, errors.New("cannot request reviews of a wikiDoc that is archived")
+	}
+
+	var reviewers []string
+	seen := map[string]bool{}
+	for _, reviewerID := range reviewerIDs {
+		if !model.IsValidId(reviewerID) {
+			return nil, errors.Wrapf(ErrMalformedReview, "invalid reviewer id '%s'", reviewerID)
+		}
+		if reviewerID == userID {
+			return nil, errors.Wrap(ErrMalformedReview, "users cannot review their own request")
+		}
+		if !seen[reviewerID] {
+			seen[reviewerID] = true
+			reviewers = append(reviewers, reviewerID)
+		}
+	}
+	if len(reviewers) == 0 {
+		return nil, errors.Wrap(ErrMalformedReview, "at least one reviewer is required")
+	}
+
+	if NormalizeStatus(wikiDoc.Status) == StatusDraft {
+		wikiDoc.Status = StatusInReview
+		updated, err := s.wikiDocsService.Update(wikiDoc, userID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to move wikiDoc '%s' to review", wikiDoc.ID)
+		}
+		wikiDoc = updated
+	}
+
+	requester, err := s.api.User.Get(userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user %s", userID)
+	}
+
+	now := model.GetMillis()
+	reviews := make([]Review, 0, len(reviewers))
+	for _, reviewerID := range reviewers {
+		review, err := s.store.SaveReview(Review{
+			WikiDocID:       wikiDoc.ID,
+			ReviewerID:      reviewerID,
+			RequesterID:     userID,
+			State:           ReviewStatePending,
+			WikiDocUpdateAt: wikiDoc.UpdateAt,
+			CreateAt:        now,
+			UpdateAt:        now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+
+		// The review is recorded even if the reviewer cannot be notified.
+		if err := s.poster.DM(reviewerID, reviewRequestPost(wikiDoc, requester)); err != nil {
+			s.logger.Warnf("failed to notify reviewer %s of wikiDoc %s: %v", reviewerID, wikiDoc.ID, err)
+		}
+	}
+
+	return reviews, nil
+}
+
+func (s *reviewService) Approve(wikiDocID, reviewerID string, updateAt int64, comment string) (Review, error) {
+	return s.decide(wikiDocID, reviewerID, updateAt, ReviewStateApproved, comment)
+}
+
+func (s *reviewService) RequestChanges(wikiDocID, reviewerID string, updateAt int64, comment string) (Review, error) {
+	if comment == "" {
+		return Review{}, errors.Wrap(ErrMalformedReview, "a comment is required to request changes")
+	}
+
+	return s.decide(wikiDocID, reviewerID, updateAt, ReviewStateChangesRequested, comment)
+}
+
+// decide records the decision of a reviewer on the version updateAt of a wikiDoc, which must be
+// its current version, and notifies the user who requested the review.
+func (s *reviewService) decide(wikiDocID, reviewerID string, updateAt int64, state ReviewState, comment string) (Review, error) {
+	review, err := s.store.GetReview(wikiDocID, reviewerID)
+	if err != nil {
+		return Review{}, err
+	}
+
+	wikiDoc, err := s.wikiDocsService.Get(wikiDocID)
+	if err != nil {
+		return Review{}, err
+	}
+
+	if wikiDoc.UpdateAt != updateAt {
+		return Review{}, errors.Wrapf(ErrConflict, "wikiDoc '%s' was modified since %d", wikiDocID, updateAt)
+	}
+
+	review.State = state
+	review.Comment = comment
+	review.WikiDocUpdateAt = updateAt
+	review.UpdateAt = model.GetMillis()
+
+	review, err = s.store.SaveReview(review)
+	if err != nil {
+		return Review{}, err
+	}
+
+	reviewer, err := s.api.User.Get(reviewerID)
+	if err != nil {
+		return Review{}, errors.Wrapf(err, "failed to get user %s", reviewerID)
+	}
+
+	if err := s.poster.DM(review.RequesterID, reviewDecisionPost(wikiDoc, reviewer, review)); err != nil {
+		s.logger.Warnf("failed to notify user %s of the review of wikiDoc %s: %v", review.RequesterID, wikiDoc.ID, err)
+	}
+
+	return review, nil
+}
+
+func (s *reviewService) OpenRequestChangesDialog(triggerID, state string) error {
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s/api/v0/reviews/dialog", root.Manifest.Id),
+		Dialog: model.Dialog{
+			Title:       "Request changes",
+			SubmitLabel: "Request changes",
+			State:       state,
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Comment",
+					Name:        DialogFieldCommentKey,
+					Type:        "textarea",
+					MaxLength:   4096,
+					HelpText:    "Describe the changes the author should make.",
+				},
+			},
+		},
+	}
+
+	if err := s.api.Frontend.OpenInteractiveDialog(dialog); err != nil {
+		return errors.Wrap(err, "failed to open the request changes dialog")
+	}
+
+	return nil
+}
+
+// reviewRequestPost is the direct message asking a reviewer to review a wikiDoc, with buttons
+// calling back the review action endpoint.
+func reviewRequestPost(wikiDoc WikiDoc, requester *model.User) *model.Post {
+	action := func(id, name, style string) *model.PostAction {
+		return &model.PostAction{
+			Id:    id,
+			Name:  name,
+			Type:  model.PostActionTypeButton,
+			Style: style,
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/api/v0/reviews/actions/%s", root.Manifest.Id, id),
+				Context: map[string]interface{}{
+					ReviewContextWikiDocIDKey: wikiDoc.ID,
+					ReviewContextUpdateAtKey:  strconv.FormatInt(wikiDoc.UpdateAt, 10),
+				},
+			},
+		}
+	}
+
+	post := &model.Post{
+		Message: fmt.Sprintf("@%s requested your review of the wiki doc **%s**.", requester.Username, wikiDoc.Name),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Title: wikiDoc.Name,
+		Text:  wikiDoc.Description,
+		Actions: []*model.PostAction{
+			action(ReviewActionApprove, "Approve", "primary"),
+			action(ReviewActionReject, "Request changes", "danger"),
+		},
+	}})
+
+	return post
+}
+
+// reviewDecisionPost is the direct message telling the requester of a review about the decision
+// of the reviewer.
+func reviewDecisionPost(wikiDoc WikiDoc, reviewer *model.User, review Review) *model.Post {
+	message := fmt.Sprintf("@%s approved the wiki doc **%s**.", reviewer.Username, wikiDoc.Name)
+	if review.State == ReviewStateChangesRequested {
+		message = fmt.Sprintf("@%s requested changes to the wiki doc **%s**.", reviewer.Username, wikiDoc.Name)
+	}
+	if review.Comment != "" {
+		message += "\n> " + strings.ReplaceAll(review.Comment, "\n", "\n> ")
+	}
+
+	return &model.Post{Message: message}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var errStoreUpdated = errors.New("stored")
+
+type reviewSettingsStore struct {
+	ChannelSettingsStore
+	requiredApprovals int
+}
+
+func (s *reviewSettingsStore) GetChannelSettings(channelID string) (ChannelSettings, error) {
+	return ChannelSettings{ChannelID: channelID, RequiredApprovals: s.requiredApprovals}, nil
+}
+
+// reviewReviewStore has the given approvals of a version of a wikiDoc, and a pending review.
+type reviewReviewStore struct {
+	ReviewStore
+	approvals int
+	version   int64
+}
+
+func (s *reviewReviewStore) CountApprovals(wikiDocID string, updateAt int64) (int, error) {
+	if updateAt != s.version {
+		return 0, nil
+	}
+	return s.approvals, nil
+}
+
+func (s *reviewReviewStore) GetReview(wikiDocID, reviewerID string) (Review, error) {
+	return Review{WikiDocID: wikiDocID, ReviewerID: reviewerID, State: ReviewStatePending, WikiDocUpdateAt: s.version}, nil
+}
+
+type reviewWikiDocService struct {
+	WikiDocService
+	wikiDoc WikiDoc
+}
+
+func (s *reviewWikiDocService) Get(id string) (WikiDoc, error) {
+	return s.wikiDoc, nil
+}
+
+func TestUpdatePublishRequiresApprovals(t *testing.T) {
+	reviewed := WikiDoc{ID: "doc", ChannelID: "channel", Name: "Guide", Content: "Reviewed", Status: StatusInReview, UpdateAt: 1}
+	store := newFakeWikiDocStore(reviewed)
+	store.updateErr = errStoreUpdated
+	service := &wikiDocsService{
+		store:         store,
+		settingsStore: &reviewSettingsStore{requiredApprovals: 1},
+		reviewStore:   &reviewReviewStore{approvals: 1, version: 1},
+	}
+
+	t.Run("the approved version is published", func(t *testing.T) {
+		published := reviewed
+		published.Status = StatusPublished
+		_, err := service.Update(published, "user")
+		assert.ErrorIs(t, err, errStoreUpdated)
+	})
+
+	t.Run("the approvals of another version do not count", func(t *testing.T) {
+		moved := reviewed
+		moved.UpdateAt = 2
+		service := &wikiDocsService{
+			store:         newFakeWikiDocStore(moved),
+			settingsStore: &reviewSettingsStore{requiredApprovals: 1},
+			reviewStore:   &reviewReviewStore{approvals: 1, version: 1},
+		}
+
+		published := moved
+		published.Status = StatusPublished
+		_, err := service.Update(published, "user")
+		assert.ErrorIs(t, err, ErrApprovalsRequired)
+	})
+
+	t.Run("an edit published at once needs new approvals", func(t *testing.T) {
+		published := reviewed
+		published.Status = StatusPublished
+		published.Content = "Not reviewed"
+		_, err := service.Update(published, "user")
+		assert.ErrorIs(t, err, ErrApprovalsRequired)
+	})
+}
+
+func TestDecideOnOutdatedVersion(t *testing.T) {
+	edited := WikiDoc{ID: "doc", Status: StatusInReview, UpdateAt: 2}
+	service := &reviewService{
+		store:           &reviewReviewStore{version: 1},
+		wikiDocsService: &reviewWikiDocService{wikiDoc: edited},
+	}
+
+	_, err := service.Approve("doc", "reviewer", 1, "")
+	assert.ErrorIs(t, err, ErrConflict, "the approval of the version reviewed")
+
+	_, err = service.RequestChanges("doc", "reviewer", 1, "Fix it")
+	assert.ErrorIs(t, err, ErrConflict)
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// sanitizeAttributes lists, by element, the elements and attributes that sanitized HTML may
+// contain. The elements that are not listed are dropped, keeping their text.
+var sanitizeAttributes = map[string]map[string]bool{
+	"a":          {"href": true, "title": true, "class": true},
+	"blockquote": {},
+	"br":         {},
+	"code":       {"class": true},
+	"del":        {},
+	"em":         {},
+	"h1":         {"id": true},
+	"h2":         {"id": true},
+	"h3":         {"id": true},
+	"h4":         {"id": true},
+	"h5":         {"id": true},
+	"h6":         {"id": true},
+	"hr":         {},
+	"img":        {"src": true, "alt": true, "title": true},
+	"input":      {"checked": true},
+	"li":         {},
+	"ol":         {"start": true},
+	"p":          {},
+	"pre":        {},
+	"span":       {"class": true, "title": true},
+	"strong":     {},
+	"table":      {},
+	"tbody":      {},
+	"td":         {"align": true},
+	"th":         {"align": true},
+	"thead":      {},
+	"tr":         {},
+	"ul":         {},
+}
+
+// sanitizeDropped are the elements dropped along with their content.
+var sanitizeDropped = map[string]bool{"script": true, "style": true, "iframe": true, "object": true, "template": true, "textarea": true}
+
+// sanitizeURLSchemes are the schemes of the URLs that links and images may point to. URLs without
+// a scheme are relative.
+var sanitizeURLSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+
+// sanitizeTokenPattern matches the values of the class and id attributes that are kept.
+var sanitizeTokenPattern = regexp.MustCompile(`^[\w -]*$`)
+
+// SanitizeHTML keeps the elements and attributes of an HTML fragment that are allowed by
+// sanitizeAttributes. Links and images must point to relative, HTTP or mail URLs, and inputs can
+// only be disabled checkboxes, as those of task lists. Comments are dropped.
+func SanitizeHTML(fragment string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+
+	var out strings.Builder
+	dropped := ""
+	depth := 0
+	for {
+		if tokenizer.Next() == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		if dropped != "" {
+			switch {
+			case token.Type == html.StartTagToken && token.Data == dropped:
+				depth++
+			case token.Type == html.EndTagToken && token.Data == dropped:
+				if depth--; depth == 0 {
+					dropped = ""
+				}
+			}
+			continue
+		}
+
+		switch token.Type {
+		case html.TextToken:
+			out.WriteString(html.EscapeString(token.Data))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if sanitizeDropped[token.Data] && token.Type == html.StartTagToken {
+				dropped, depth = token.Data, 1
+				continue
+			}
+			allowed, ok := sanitizeAttributes[token.Data]
+			if !ok || (token.Data == "input" && !isCheckbox(token)) {
+				continue
+			}
+			token.Attr = sanitizeAttrs(token.Attr, allowed)
+			if token.Data == "input" {
+				token.Attr = append(token.Attr, html.Attribute{Key: "type", Val: "checkbox"}, html.Attribute{Key: "disabled"})
+			}
+			out.WriteString(token.String())
+		case html.EndTagToken:
+			if _, ok := sanitizeAttributes[token.Data]; ok {
+				out.WriteString(token.String())
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// sanitizeAttrs returns the allowed attributes whose value is safe.
+func sanitizeAttrs(attrs []html.Attribute, allowed map[string]bool) []html.Attribute {
+	kept := make([]html.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Namespace != "" || !allowed[attr.Key] {
+			continue
+		}
+		switch attr.Key {
+		case "href", "src":
+			if !isSafeURL(attr.Val) {
+				continue
+			}
+		case "class", "id":
+			if !sanitizeTokenPattern.MatchString(attr.Val) {
+				continue
+			}
+		case "align":
+			if attr.Val != "left" && attr.Val != "center" && attr.Val != "right" {
+				continue
+			}
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// isCheckbox returns true for the input elements that are checkboxes.
+func isCheckbox(token html.Token) bool {
+	for _, attr := range token.Attr {
+		if attr.Key == "type" {
+			return strings.EqualFold(attr.Val, "checkbox")
+		}
+	}
+	return false
+}
+
+// isSafeURL returns true for the URLs that are relative or whose scheme is allowed.
+func isSafeURL(value string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	return sanitizeURLSchemes[strings.ToLower(parsed.Scheme)]
+}
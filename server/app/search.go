@@ -0,0 +1,148 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// searchSnippetLength is the approximate number of characters of content returned around a match.
+const searchSnippetLength = 240
+
+// searchHighlightMarker wraps matched words in snippets, rendering them in bold as markdown.
+const searchHighlightMarker = "**"
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchHighlight holds the parts of a wikiDoc matching a search, with matched words wrapped in
+// bold markdown markers. Fields that do not match are left empty.
+type SearchHighlight struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"`
+}
+
+// SearchTerms splits a search term into the lowercase words it is made of, dropping punctuation
+// and search operators. Each word matches any word it is a prefix of.
+func SearchTerms(searchTerm string) []string {
+	words := searchTermPattern.FindAllString(strings.ToLower(searchTerm), -1)
+
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// HighlightWikiDoc returns the highlighted name, description and a content snippet of a wikiDoc
+// for the given search terms.
+func HighlightWikiDoc(wikiDoc WikiDoc, terms []string) SearchHighlight {
+	return SearchHighlight{
+		Name:        snippet(wikiDoc.Name, terms),
+		Description: snippet(wikiDoc.Description, terms),
+		Content:     snippet(wikiDoc.Content, terms),
+	}
+}
+
+// snippet returns an extract of text around the first word matching terms, with every matching
+// word highlighted, or an empty string if nothing matches.
+func snippet(text string, terms []string) string {
+	matches := matchingWords(text, terms)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if utf8.RuneCountInString(text) > searchSnippetLength {
+		start = moveBack(text, matches[0][0], searchSnippetLength/3)
+		// The first match is kept whole, even when it is a long word such as a URL.
+		end = maxInt(moveForward(text, start, searchSnippetLength), matches[0][1])
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	position := start
+	for _, match := range matches {
+		if match[0] < start {
+			continue
+		}
+		if match[1] > end {
+			break
+		}
+		b.WriteString(text[position:match[0]])
+		b.WriteString(searchHighlightMarker)
+		b.WriteString(text[match[0]:match[1]])
+		b.WriteString(searchHighlightMarker)
+		position = match[1]
+	}
+	b.WriteString(text[position:end])
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// matchingWords returns the byte ranges of the words of text starting with one of the terms.
+func matchingWords(text string, terms []string) [][]int {
+	var matches [][]int
+	for _, word := range searchTermPattern.FindAllStringIndex(text, -1) {
+		lowerWord := strings.ToLower(text[word[0]:word[1]])
+		for _, term := range terms {
+			if strings.HasPrefix(lowerWord, term) {
+				matches = append(matches, word)
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// moveBack returns the byte offset of the start of the word about count runes before offset.
+func moveBack(text string, offset, count int) int {
+	start := offset
+	for count > 0 && start > 0 {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+		count--
+	}
+
+	if start == 0 {
+		return 0
+	}
+
+	if i := strings.IndexAny(text[start:offset], " \n\t"); i >= 0 {
+		return start + i + 1
+	}
+	return start
+}
+
+// moveForward returns the byte offset of the end of the word about count runes after offset. The
+// result is never before offset.
+func moveForward(text string, offset, count int) int {
+	end := offset
+	for count > 0 && end < len(text) {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+		count--
+	}
+
+	if end >= len(text) {
+		return len(text)
+	}
+
+	if i := strings.LastIndexAny(text[offset:end], " \n\t"); i > 0 {
+		return offset + i
+	}
+	return end
+}
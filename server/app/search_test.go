@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"wiki", "go", "été"}, SearchTerms("Wiki, -go \"wiki\" ÉTÉ"))
+	assert.Empty(t, SearchTerms("  !? "))
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("b", 100) + " " + strings.Repeat("c", 20) + "-" + strings.Repeat("a", 300)
+
+	for name, test := range map[string]struct {
+		text     string
+		terms    []string
+		expected string
+	}{
+		"no match": {
+			"Nothing here", []string{"wiki"}, "",
+		},
+		"short text": {
+			"The Wiki of wikis,\n and more", []string{"wiki"}, "The **Wiki** of **wikis**, and more",
+		},
+		"long text": {
+			strings.Repeat("x ", 200) + "match " + strings.Repeat("y ", 200),
+			[]string{"match"},
+			"…" + strings.TrimSpace(strings.Repeat("x ", 39)) + " **match** " + strings.TrimSpace(strings.Repeat("y ", 78)) + "…",
+		},
+		"long word after a space": {
+			long, SearchTerms("aaa"), "…" + strings.Repeat("c", 20) + "-**" + strings.Repeat("a", 300) + "**",
+		},
+		"long word at the start": {
+			strings.Repeat("a", 500), []string{"a"}, "**" + strings.Repeat("a", 500) + "**",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, snippet(test.text, test.terms))
+		})
+	}
+}
+
+func TestMoveBack(t *testing.T) {
+	for name, test := range map[string]struct {
+		text     string
+		offset   int
+		count    int
+		expected int
+	}{
+		"start of text":      {"abc def", 4, 10, 0},
+		"partial word":       {"abc def ghi", 8, 2, 8},
+		"start of word":      {"abc def ghi", 8, 5, 4},
+		"within a long word": {"abcdefghij", 8, 3, 5},
+		"multibyte runes":    {"éééé éé", 11, 4, 9},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, moveBack(test.text, test.offset, test.count))
+		})
+	}
+}
+
+func TestMoveForward(t *testing.T) {
+	for name, test := range map[string]struct {
+		text     string
+		offset   int
+		count    int
+		expected int
+	}{
+		"end of text":            {"abc def", 4, 10, 7},
+		"end of word":            {"abc def ghi", 0, 9, 7},
+		"within a long word":     {"abcdefghij", 2, 3, 5},
+		"space before offset":    {"abc defghij", 4, 3, 7},
+		"multibyte runes":        {"éé éé", 0, 4, 4},
+		"never before the start": {"ab cdefgh", 3, 2, 5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, moveForward(test.text, test.offset, test.count))
+		})
+	}
+}
@@ -24,6 +24,10 @@ const (
 
 	// SortByStatus sorts by the status of a wikiDoc run.
 	SortByStatus SortField = "status"
+
+	// SortByRelevance sorts by how well a wikiDoc matches the search term, most relevant first
+	// in descending order. Only available when searching.
+	SortByRelevance SortField = "relevance"
 )
 
 // SortDirection is the type used to specify the ascending or descending order of returned results.
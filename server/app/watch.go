@@ -0,0 +1,76 @@
+package app
+
+// WatchScope is what a watch covers.
+type WatchScope string
+
+const (
+	// WatchScopeWikiDoc covers a single wikiDoc.
+	WatchScopeWikiDoc WatchScope = "wiki_doc"
+
+	// WatchScopeTree covers a wikiDoc and all of its descendants.
+	WatchScopeTree WatchScope = "tree"
+
+	// WatchScopeChannel covers every wikiDoc of a channel.
+	WatchScopeChannel WatchScope = "channel"
+)
+
+// Watch subscribes a user to the changes of wikiDocs.
+type Watch struct {
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+
+	// WikiDocID is the watched wikiDoc, empty when the whole channel wiki is watched.
+	WikiDocID string     `json:"wiki_doc_id"`
+	Scope     WatchScope `json:"scope"`
+	CreateAt  int64      `json:"create_at"`
+}
+
+// WikiDocNotification is a change of a wikiDoc waiting to be sent to a watcher. The changes of a
+// watcher are batched in a single message, see WatchService.SendNotifications.
+type WikiDocNotification struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// ActorID is the user who made the change.
+	ActorID string `json:"actor_id"`
+
+	// Fields are the names of the fields that changed, comma separated.
+	Fields string `json:"fields"`
+
+	// PreviousStatus and Status are the statuses before and after the change.
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+
+	// Excerpt is the beginning of the diff of the content, empty if it did not change.
+	Excerpt string `json:"excerpt"`
+
+	CreateAt int64 `json:"create_at"`
+}
+
+// WatchStore is an interface for storing watches and the notifications waiting to be sent
+type WatchStore interface {
+	// GetWatches retrieves the watches of a user in a channel
+	GetWatches(userID, channelID string) ([]Watch, error)
+
+	// GetWatcherIDs retrieves the users watching a wikiDoc of a channel: through the wikiDoc itself,
+	// a tree watch on one of the given ancestors, or the channel
+	GetWatcherIDs(channelID, wikiDocID string, ancestorIDs []string) ([]string, error)
+
+	// SaveWatch creates a watch, or replaces the scope of an existing one
+	SaveWatch(watch Watch) error
+
+	// DeleteWatch removes the watch of a user on a wikiDoc, or on the channel wiki when wikiDocID is
+	// empty
+	DeleteWatch(userID, channelID, wikiDocID string) error
+
+	// AddNotifications queues notifications to be sent
+	AddNotifications(notifications []WikiDocNotification) error
+
+	// GetDueNotifications retrieves every queued notification of the users whose oldest queued
+	// notification was created before the given time, oldest first
+	GetDueNotifications(before int64) ([]WikiDocNotification, error)
+
+	// DeleteNotifications removes sent notifications
+	DeleteNotifications(ids []string) error
+}
@@ -0,0 +1,314 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const (
+	// notificationExcerptLines is the maximum number of changed lines in the excerpt of a notification.
+	notificationExcerptLines = 6
+
+	// notificationExcerptLineLength is the maximum number of characters of a line of an excerpt.
+	notificationExcerptLineLength = 120
+)
+
+// WatchService manages the watches of wikiDocs and the notifications of their watchers
+type WatchService interface {
+	// GetWatches retrieves the watches of a user in a channel
+	GetWatches(userID, channelID string) ([]Watch, error)
+
+	// Watch subscribes a user to the changes of a wikiDoc, with WatchScopeWikiDoc or WatchScopeTree,
+	// or of the channel wiki when wikiDocID is empty. Returns ErrMalformedWikiDoc if the scope is not
+	// valid.
+	Watch(userID, channelID, wikiDocID string, scope WatchScope) (Watch, error)
+
+	// Unwatch removes the watch of a user on a wikiDoc, or on the channel wiki when wikiDocID is empty
+	Unwatch(userID, channelID, wikiDocID string) error
+
+	// SendNotifications sends a direct message to each watcher whose oldest queued notification was
+	// created before the given time, summarizing all their queued notifications. Changes to
+	// wikiDocs the watcher can no longer read are dropped. Returns the number of messages sent.
+	SendNotifications(before int64) (int, error)
+}
+
+type watchService struct {
+	store           WatchStore
+	wikiDocsService WikiDocService
+	permissions     *PermissionsService
+	poster          bot.Poster
+	api             *pluginapi.Client
+	logger          bot.Logger
+}
+
+func NewWatchService(store WatchStore, wikiDocsService WikiDocService, permissions *PermissionsService, poster bot.Poster, logger bot.Logger, api *pluginapi.Client) WatchService {
+	return &watchService{
+		store:           store,
+		wikiDocsService: wikiDocsService,
+		permissions:     permissions,
+		poster:          poster,
+		api:             api,
+		logger:          logger,
+	}
+}
+
+func (s *watchService) GetWatches(userID, channelID string) ([]Watch, error) {
+	return s.store.GetWatches(userID, channelID)
+}
+
+func (s *watchService) Watch(userID, channelID, wikiDocID string, scope WatchScope) (Watch, error) {
+	if wikiDocID == "" {
+		scope = WatchScopeChannel
+	} else if scope != WatchScopeWikiDoc && scope != WatchScopeTree {
+		return Watch{}, errors.Wrapf(ErrMalformedWikiDoc, "invalid scope '%s': must be wiki_doc or tree", scope)
+	}
+
+	watch := Watch{
+		UserID:    userID,
+		ChannelID: channelID,
+		WikiDocID: wikiDocID,
+		Scope:     scope,
+		CreateAt:  model.GetMillis(),
+	}
+	if err := s.store.SaveWatch(watch); err != nil {
+		return Watch{}, err
+	}
+
+	return watch, nil
+}
+
+func (s *watchService) Unwatch(userID, channelID, wikiDocID string) error {
+	return s.store.DeleteWatch(userID, channelID, wikiDocID)
+}
+
+func (s *watchService) SendNotifications(before int64) (int, error) {
+	notifications, err := s.store.GetDueNotifications(before)
+	if err != nil {
+		return 0, err
+	}
+
+	byUser := map[string][]WikiDocNotification{}
+	var userIDs []string
+	for _, notification := range notifications {
+		if _, ok := byUser[notification.UserID]; !ok {
+			userIDs = append(userIDs, notification.UserID)
+		}
+		byUser[notification.UserID] = append(byUser[notification.UserID], notification)
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		post, err := s.notificationPost(userID, byUser[userID])
+		if err != nil {
+			return sent, err
+		}
+
+		// A watcher who cannot be notified is skipped, their notifications are dropped with the others.
+		if post != nil {
+			if err = s.poster.DM(userID, post); err != nil {
+				s.logger.Warnf("failed to notify watcher %s: %v", userID, err)
+			} else {
+				sent++
+			}
+		}
+
+		ids := make([]string, 0, len(byUser[userID]))
+		for _, notification := range byUser[userID] {
+			ids = append(ids, notification.ID)
+		}
+		if err = s.store.DeleteNotifications(ids); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// notificationPost summarizes the notifications of a watcher, grouped by wikiDoc. Returns nil if
+// the watcher can read none of the wikiDocs.
+func (s *watchService) notificationPost(userID string, notifications []WikiDocNotification) (*model.Post, error) {
+	var wikiDocIDs []string
+	byWikiDoc := map[string][]WikiDocNotification{}
+	for _, notification := range notifications {
+		if _, ok := byWikiDoc[notification.WikiDocID]; !ok {
+			wikiDocIDs = append(wikiDocIDs, notification.WikiDocID)
+		}
+		byWikiDoc[notification.WikiDocID] = append(byWikiDoc[notification.WikiDocID], notification)
+	}
+
+	usernames := map[string]string{}
+	username := func(id string) string {
+		if _, ok := usernames[id]; !ok {
+			usernames[id] = id
+			if user, err := s.api.User.Get(id); err == nil {
+				usernames[id] = user.Username
+			}
+		}
+		return usernames[id]
+	}
+
+	var sections []string
+	for _, wikiDocID := range wikiDocIDs {
+		err := s.permissions.WikiDocView(userID, wikiDocID)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNoPermissions) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		wikiDoc, err := s.wikiDocsService.Get(wikiDocID)
+		if err != nil {
+			return nil, err
+		}
+		if wikiDoc.DeleteAt != 0 {
+			continue
+		}
+
+		section := fmt.Sprintf("#### %s", wikiDoc.Name)
+		for _, notification := range byWikiDoc[wikiDocID] {
+			section += "\n" + notificationSummary(notification, "@"+username(notification.ActorID))
+		}
+		sections = append(sections, section)
+	}
+
+	if len(sections) == 0 {
+		return nil, nil
+	}
+
+	return &model.Post{
+		Message: "Wiki docs you watch changed:\n" + strings.Join(sections, "\n"),
+	}, nil
+}
+
+// notificationSummary describes a single change: who made it, the fields it changed and the
+// excerpt of the diff of the content.
+func notificationSummary(notification WikiDocNotification, actor string) string {
+	var changes []string
+	for _, field := range strings.Split(notification.Fields, ",") {
+		if field == "status" {
+			changes = append(changes, fmt.Sprintf("status (%s → %s)", notification.PreviousStatus, notification.Status))
+			continue
+		}
+		changes = append(changes, field)
+	}
+
+	summary := fmt.Sprintf("* %s changed the %s", actor, strings.Join(changes, ", "))
+	if notification.Excerpt != "" {
+		summary += "\n```diff\n" + notification.Excerpt + "\n```"
+	}
+
+	return summary
+}
+
+// queueNotifications queues a notification of the change of a wikiDoc for each of its watchers but
+// the user who made it.
+func (s *wikiDocsService) queueNotifications(previous, wikiDoc WikiDoc, userID string) error {
+	var fields []string
+	if wikiDoc.Name != previous.Name {
+		fields = append(fields, "name")
+	}
+	if wikiDoc.Description != previous.Description {
+		fields = append(fields, "description")
+	}
+	if wikiDoc.Content != previous.Content {
+		fields = append(fields, "content")
+	}
+	if wikiDoc.Status != previous.Status {
+		fields = append(fields, "status")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ancestorIDs, err := s.ancestorIDs(wikiDoc)
+	if err != nil {
+		return err
+	}
+
+	watcherIDs, err := s.watchStore.GetWatcherIDs(wikiDoc.ChannelID, wikiDoc.ID, ancestorIDs)
+	if err != nil {
+		return err
+	}
+	sort.Strings(watcherIDs)
+
+	excerpt := diffExcerpt(previous.Content, wikiDoc.Content)
+	now := model.GetMillis()
+
+	notifications := make([]WikiDocNotification, 0, len(watcherIDs))
+	for _, watcherID := range watcherIDs {
+		if watcherID == userID {
+			continue
+		}
+		notifications = append(notifications, WikiDocNotification{
+			ID:             model.NewId(),
+			UserID:         watcherID,
+			WikiDocID:      wikiDoc.ID,
+			ActorID:        userID,
+			Fields:         strings.Join(fields, ","),
+			PreviousStatus: previous.Status,
+			Status:         wikiDoc.Status,
+			Excerpt:        excerpt,
+			CreateAt:       now,
+		})
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	return s.watchStore.AddNotifications(notifications)
+}
+
+// ancestorIDs returns the IDs of the parent of a wikiDoc, the parent of its parent, and so on.
+func (s *wikiDocsService) ancestorIDs(wikiDoc WikiDoc) ([]string, error) {
+	var ancestorIDs []string
+	seen := map[string]bool{wikiDoc.ID: true}
+	for parentID := wikiDoc.ParentID; parentID != "" && !seen[parentID]; {
+		seen[parentID] = true
+		ancestorIDs = append(ancestorIDs, parentID)
+
+		parent, err := s.store.Get(parentID)
+		if errors.Is(err, ErrNotFound) {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to get parent '%s'", parentID)
+		}
+		parentID = parent.ParentID
+	}
+
+	return ancestorIDs, nil
+}
+
+// diffExcerpt returns the first changed lines between two contents, in the unified diff format.
+func diffExcerpt(from, to string) string {
+	var lines []string
+	for _, hunk := range DiffText(from, to, DiffOptions{Context: 0}).Hunks {
+		for _, line := range hunk.Lines {
+			if len(lines) == notificationExcerptLines {
+				return strings.Join(append(lines, "…"), "\n")
+			}
+
+			text := line.Text
+			if utf8.RuneCountInString(text) > notificationExcerptLineLength {
+				text = string([]rune(text)[:notificationExcerptLineLength]) + "…"
+			}
+
+			switch line.Type {
+			case DiffAdded:
+				lines = append(lines, "+ "+text)
+			case DiffRemoved:
+				lines = append(lines, "- "+text)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationSummary(t *testing.T) {
+	t.Run("status change shows both statuses", func(t *testing.T) {
+		summary := notificationSummary(WikiDocNotification{
+			Fields:         "name,status",
+			PreviousStatus: StatusDraft,
+			Status:         StatusInReview,
+		}, "@alice")
+		assert.Equal(t, "* @alice changed the name, status ("+StatusDraft+" → "+StatusInReview+")", summary)
+	})
+
+	t.Run("content change quotes the excerpt", func(t *testing.T) {
+		summary := notificationSummary(WikiDocNotification{
+			Fields:  "content",
+			Excerpt: diffExcerpt("a\nb\n", "a\nc\n"),
+		}, "@bob")
+		assert.Equal(t, "* @bob changed the content\n```diff\n- b\n+ c\n```", summary)
+	})
+}
+
+func TestDiffExcerpt(t *testing.T) {
+	t.Run("no change", func(t *testing.T) {
+		assert.Equal(t, "", diffExcerpt("same\n", "same\n"))
+	})
+
+	t.Run("long diffs are cut", func(t *testing.T) {
+		to := strings.Repeat("line\n", notificationExcerptLines+2)
+		lines := strings.Split(diffExcerpt("", to), "\n")
+		assert.Len(t, lines, notificationExcerptLines+1)
+		assert.Equal(t, "…", lines[notificationExcerptLines])
+	})
+
+	t.Run("long lines are cut", func(t *testing.T) {
+		excerpt := diffExcerpt("", strings.Repeat("x", notificationExcerptLineLength+10))
+		assert.Equal(t, "+ "+strings.Repeat("x", notificationExcerptLineLength)+"…", excerpt)
+	})
+}
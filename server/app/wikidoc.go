@@ -7,8 +7,14 @@ import (
 )
 
 const (
-	StatusPrivate   = "Private"
-	StatusPublished = "Published"
+	StatusDraft      = "Draft"
+	StatusInReview   = "In Review"
+	StatusPublished  = "Published"
+	StatusDeprecated = "Deprecated"
+	StatusArchived   = "Archived"
+
+	// StatusPrivate is the former name of StatusDraft. It is still accepted as input.
+	StatusPrivate = "Private"
 )
 
 type WikiDoc struct {
@@ -24,7 +30,8 @@ type WikiDoc struct {
 	// Description is field for describing the doc.
 	Description string `json:"description" export:"description"`
 
-	// It can be StatusPrivate ("Private") or StatusPublished ("Published")
+	// Status is the lifecycle state of the doc: StatusDraft, StatusInReview, StatusPublished,
+	// StatusDeprecated or StatusArchived. See CanTransition for the allowed changes.
 	Status string `json:"status" export:"status"`
 
 	// OwnerUserID is the user identifier of the wikiDoc's owner.
@@ -36,30 +43,101 @@ type WikiDoc struct {
 	// ChannelID is the identifier of the wikiDoc's channel.
 	ChannelID string `json:"channel_id" export:"-"`
 
+	// ParentID is the identifier of the parent wikiDoc in the same channel, empty for top-level docs.
+	ParentID string `json:"parent_id" export:"-"`
+
+	// SortOrder is the position of the wikiDoc among its siblings.
+	SortOrder int `json:"sort_order" export:"-"`
+
+	// SourceID is the identifier of the wikiDoc this one was duplicated from, empty otherwise.
+	SourceID string `json:"source_id" export:"-"`
+
+	// Restricted limits access to the members of the wikiDoc, ignoring the access given by its
+	// channel. System admins keep access. See WikiDocMember.
+	Restricted bool `json:"restricted" export:"-"`
+
 	CreateAt int64 `json:"create_at" export:"-"`
 	UpdateAt int64 `json:"update_at" export:"-"`
 	DeleteAt int64 `json:"delete_at" export:"-"`
 }
 
+// DuplicateOptions specifies where a wikiDoc is duplicated to.
+type DuplicateOptions struct {
+	// ChannelID is the channel of the copy, defaulting to the channel of the original.
+	ChannelID string
+
+	// TeamID is the team of the copy. It must be the team of ChannelID when given.
+	TeamID string
+
+	// Name is the name of the copy. Defaults to the name of the original, marked as a copy when
+	// duplicated in the same channel.
+	Name string
+}
+
 // WikiDocStore is an interface for storing wikiDocs
 type WikiDocStore interface {
 	// Get retrieves a wikiDoc
 	Get(id string) (WikiDoc, error)
 
-	// Create creates a new wikiDoc
+	// Create creates a new wikiDoc, with a new ID unless it already has one
 	Create(wikiDoc WikiDoc) (string, error)
 
+	// CreateWithAttachments creates a new wikiDoc as Create does, along with its attachments
+	CreateWithAttachments(wikiDoc WikiDoc, attachments []Attachment) (string, error)
+
+	// CreateWithRevisions creates a new wikiDoc as CreateWithAttachments does, recording its later
+	// versions, oldest first, as revisions authored by userID. The wikiDoc is left in the last one.
+	CreateWithRevisions(wikiDoc WikiDoc, revisions []WikiDoc, attachments []Attachment, userID string) (string, error)
+
 	// GetWikiDocs retrieves all wikiDocs
 	GetWikiDocs(requesterInfo RequesterInfo, options WikiDocFilterOptions) (*GetWikiDocsResults, error)
 
-	// Update updates a wikiDoc
-	Update(wikiDoc WikiDoc) error
+	// Update updates a wikiDoc and records a new revision authored by userID, along with a status
+	// change if its status changed. If resetReviews is set, the reviews of the wikiDoc are set back
+	// to pending along with the update. Returns ErrConflict if the wikiDoc was modified since
+	// previousUpdateAt.
+	Update(wikiDoc WikiDoc, previousUpdateAt int64, userID string, resetReviews bool) error
 
-	// Archive archives a wikiDoc
-	Archive(id string) error
+	// Archive moves a wikiDoc to the trash, deleted at deleteAt, along with the given descendants in
+	// the same transaction. Returns ErrConflict if the wikiDoc was modified since updateAt, or is
+	// already in the trash.
+	Archive(id string, updateAt int64, descendantIDs []string, deleteAt int64) error
 
-	// Delete deletes a wikiDoc
+	// Unarchive takes a wikiDoc out of the trash, updated at updateAt
+	Unarchive(id string, updateAt int64) error
+
+	// SetRestricted changes whether a wikiDoc is restricted to its members, updated at updateAt
+	SetRestricted(id string, restricted bool, updateAt int64) error
+
+	// Delete deletes a wikiDoc along with its revisions, status changes, reviews, members, group
+	// grants, comments, watches, queued notifications, links and attachments
 	Delete(id string) error
+
+	// GetWikiDocsForChannel retrieves all wikiDocs of a channel that are not deleted, without their content
+	GetWikiDocsForChannel(channelID string) ([]WikiDoc, error)
+
+	// GetChannelIDs retrieves the channels of a team with wikiDocs that are not deleted
+	GetChannelIDs(teamID string) ([]string, error)
+
+	// GetArchivedWikiDocsForChannel retrieves the wikiDocs of a channel that are in the trash, without
+	// their content, most recently deleted first
+	GetArchivedWikiDocsForChannel(channelID string) ([]WikiDoc, error)
+
+	// GetArchivedWikiDocIDs retrieves the IDs of the wikiDocs moved to the trash before the given time
+	GetArchivedWikiDocIDs(before int64) ([]string, error)
+
+	// Reorder places the given wikiDocs under parentID, in the given order. The moved wikiDocs, by
+	// ID, are guarded by their UpdateAt as in Update, and their UpdateAt is increased to updateAt.
+	Reorder(parentID string, orderedIDs []string, moved map[string]int64, updateAt int64) error
+
+	// GetRevisions retrieves the revisions of a wikiDoc, newest first, without their content
+	GetRevisions(wikiDocID string) ([]WikiDocRevision, error)
+
+	// GetRevision retrieves a single revision of a wikiDoc
+	GetRevision(wikiDocID string, revision int64) (WikiDocRevision, error)
+
+	// GetStatusChanges retrieves the status transitions of a wikiDoc, newest first
+	GetStatusChanges(wikiDocID string) ([]WikiDocStatusChange, error)
 }
 
 const PerPageDefault = 1000
@@ -89,9 +167,8 @@ type WikiDocFilterOptions struct {
 	// OwnerID filters by owner's Mattermost user ID. Defaults to blank (no filter).
 	OwnerID string `url:"owner_user_id,omitempty"`
 
-	// SearchTerm returns results of the search term and respecting the other header filter options.
-	// The search term acts as a filter and respects the Sort and Direction fields (i.e., results are
-	// not returned in relevance order).
+	// SearchTerm returns results of a full-text search over name, description and content, respecting
+	// the other filter options. Results are returned in relevance order unless Sort is specified.
 	SearchTerm string `url:"search_term,omitempty"`
 }
 
@@ -113,6 +190,11 @@ func (o WikiDocFilterOptions) Validate() (WikiDocFilterOptions, error) {
 	}
 
 	options.Sort = SortField(strings.ToLower(string(options.Sort)))
+	if options.Sort == "" && options.Direction == "" && strings.TrimSpace(options.SearchTerm) != "" {
+		options.Sort = SortByRelevance
+		options.Direction = DirectionDesc
+	}
+
 	switch options.Sort {
 	case SortByCreateAt:
 	case SortByID:
@@ -121,6 +203,10 @@ func (o WikiDocFilterOptions) Validate() (WikiDocFilterOptions, error) {
 	case SortByTeamID:
 	case SortByChannelID:
 	case SortByStatus:
+	case SortByRelevance:
+		if strings.TrimSpace(options.SearchTerm) == "" {
+			return WikiDocFilterOptions{}, errors.New("sort 'relevance' requires a search term")
+		}
 	case "": // default
 		options.Sort = SortByCreateAt
 	default:
@@ -149,17 +235,23 @@ func (o WikiDocFilterOptions) Validate() (WikiDocFilterOptions, error) {
 		return WikiDocFilterOptions{}, errors.New("bad parameter 'owner_id': must be 26 characters or blank")
 	}
 
-	for _, s := range options.Statuses {
-		if !ValidStatus(s) {
-			return WikiDocFilterOptions{}, errors.New("bad parameter in 'statuses': must be Private or Published")
+	for i, s := range options.Statuses {
+		if s == "" || !ValidStatus(s) {
+			return WikiDocFilterOptions{}, errors.New("bad parameter in 'statuses': must be Draft, In Review, Published, Deprecated or Archived")
 		}
+		options.Statuses[i] = NormalizeStatus(s)
 	}
 
 	return options, nil
 }
 
+// ValidStatus returns true if the status is known, legacy or blank.
 func ValidStatus(status string) bool {
-	return status == "" || status == StatusPrivate || status == StatusPublished
+	switch status {
+	case "", StatusPrivate, StatusDraft, StatusInReview, StatusPublished, StatusDeprecated, StatusArchived:
+		return true
+	}
+	return false
 }
 
 type GetWikiDocsResults struct {
@@ -167,4 +259,7 @@ type GetWikiDocsResults struct {
 	PageCount  int       `json:"page_count"`
 	HasMore    bool      `json:"has_more"`
 	Items      []WikiDoc `json:"items"`
+
+	// Highlights holds the matching parts of each item by wikiDoc ID, when searching.
+	Highlights map[string]SearchHighlight `json:"highlights,omitempty"`
 }
@@ -0,0 +1,59 @@
+package app
+
+// WikiDocChangedWSEvent is the websocket event published to a channel when one of its wikiDocs changes.
+// Clients receive it as "custom_<plugin id>_wiki_doc_changed".
+const WikiDocChangedWSEvent = "wiki_doc_changed"
+
+// ChangeType enumerates the kinds of changes made to a wikiDoc.
+type ChangeType string
+
+const (
+	// ChangeTypeCreated is used when a wikiDoc is created.
+	ChangeTypeCreated ChangeType = "created"
+
+	// ChangeTypeUpdated is used when the name, description or content of a wikiDoc changes.
+	ChangeTypeUpdated ChangeType = "updated"
+
+	// ChangeTypeStatusChanged is used when the status of a wikiDoc changes.
+	ChangeTypeStatusChanged ChangeType = "status_changed"
+
+	// ChangeTypeAccessChanged is used when a wikiDoc is restricted to its members, or opened to its channel again.
+	ChangeTypeAccessChanged ChangeType = "access_changed"
+
+	// ChangeTypeMoved is used when a wikiDoc is moved to another parent or position.
+	ChangeTypeMoved ChangeType = "moved"
+
+	// ChangeTypeDeleted is used when a wikiDoc is moved to the trash.
+	ChangeTypeDeleted ChangeType = "deleted"
+
+	// ChangeTypeRestored is used when a wikiDoc is taken out of the trash.
+	ChangeTypeRestored ChangeType = "restored"
+
+	// ChangeTypePurged is used when a wikiDoc is permanently deleted from the trash.
+	ChangeTypePurged ChangeType = "purged"
+)
+
+// WikiDocChange describes a change made to a wikiDoc by a user.
+type WikiDocChange struct {
+	Type ChangeType
+
+	// ActorID is the user who made the change.
+	ActorID string
+
+	// WikiDoc is the state of the wikiDoc after the change.
+	WikiDoc WikiDoc
+
+	// Previous is the state of the wikiDoc before the change, nil on creation.
+	Previous *WikiDoc
+}
+
+// webSocketPayload returns the payload of the WikiDocChangedWSEvent for this change.
+func (c WikiDocChange) webSocketPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"wiki_doc_id": c.WikiDoc.ID,
+		"channel_id":  c.WikiDoc.ChannelID,
+		"change_type": string(c.Type),
+		"actor_id":    c.ActorID,
+		"update_at":   c.WikiDoc.UpdateAt,
+	}
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// WikiDocRole is the role granted to a user on a single wikiDoc.
+type WikiDocRole string
+
+const (
+	// RoleViewer can read the wikiDoc.
+	RoleViewer WikiDocRole = "viewer"
+
+	// RoleEditor can read and edit the wikiDoc.
+	RoleEditor WikiDocRole = "editor"
+
+	// RoleOwner can read, edit and delete the wikiDoc, and manage who has access to it.
+	RoleOwner WikiDocRole = "owner"
+)
+
+var roleRanks = map[WikiDocRole]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ValidRole returns true if the role is known.
+func ValidRole(role WikiDocRole) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Allows returns true if the role grants at least the rights of the other role. No role allows nothing.
+func (r WikiDocRole) Allows(other WikiDocRole) bool {
+	return r != "" && roleRanks[r] >= roleRanks[other]
+}
+
+// WikiDocMember is a role granted to a user on a wikiDoc. Members have access to the wikiDoc on top
+// of the access they get from its channel; see WikiDoc.Restricted to only let members in.
+type WikiDocMember struct {
+	WikiDocID string      `json:"wiki_doc_id"`
+	UserID    string      `json:"user_id"`
+	Role      WikiDocRole `json:"role"`
+	CreateAt  int64       `json:"create_at"`
+	UpdateAt  int64       `json:"update_at"`
+}
+
+// WikiDocMemberStore is an interface for storing the members of wikiDocs
+type WikiDocMemberStore interface {
+	// GetMembers retrieves the members of a wikiDoc
+	GetMembers(wikiDocID string) ([]WikiDocMember, error)
+
+	// GetRole retrieves the role of a user on a wikiDoc, empty if they have none
+	GetRole(wikiDocID, userID string) (WikiDocRole, error)
+
+	// GetRolesInChannel retrieves the roles of a user on the wikiDocs of a channel, by wikiDoc ID
+	GetRolesInChannel(userID, channelID string) (map[string]WikiDocRole, error)
+
+	// SaveMember grants a role to a user on a wikiDoc, replacing the role they had
+	SaveMember(member WikiDocMember) (WikiDocMember, error)
+
+	// DeleteMember revokes the role of a user on a wikiDoc
+	DeleteMember(wikiDocID, userID string) error
+
+	// GetGroupGrants retrieves the roles granted to groups on a wikiDoc, or on the channel wiki when
+	// wikiDocID is empty
+	GetGroupGrants(channelID, wikiDocID string) ([]GroupGrant, error)
+
+	// GetGroupGrantsForGroups retrieves the roles granted to some groups in a channel, on the channel
+	// wiki and on its wikiDocs
+	GetGroupGrantsForGroups(channelID string, groupIDs []string) ([]GroupGrant, error)
+
+	// SaveGroupGrant grants a role to a group, replacing the role it had
+	SaveGroupGrant(grant GroupGrant) (GroupGrant, error)
+
+	// DeleteGroupGrant revokes the role of a group on a wikiDoc, or on the channel wiki when
+	// wikiDocID is empty
+	DeleteGroupGrant(groupID, channelID, wikiDocID string) error
+}
+
+// WikiDocMemberService manages who has access to wikiDocs
+type WikiDocMemberService interface {
+	// GetMembers retrieves the members of a wikiDoc
+	GetMembers(wikiDocID string) ([]WikiDocMember, error)
+
+	// GetRole retrieves the role of a user on a wikiDoc, empty if they have none
+	GetRole(wikiDocID, userID string) (WikiDocRole, error)
+
+	// GetChannelRoles retrieves the roles of a user on the wikiDocs of a channel, granted to them or
+	// to the groups they are members of
+	GetChannelRoles(userID, channelID string) (ChannelRoles, error)
+
+	// Grant gives a role on a wikiDoc to a user, replacing the role they had. Returns
+	// ErrMalformedWikiDoc if the user or the role is not valid.
+	Grant(wikiDocID, userID string, role WikiDocRole) (WikiDocMember, error)
+
+	// Revoke removes the role of a user on a wikiDoc
+	Revoke(wikiDocID, userID string) error
+
+	// GetGroupGrants retrieves the roles granted to groups on a wikiDoc, or on the channel wiki when
+	// wikiDocID is empty
+	GetGroupGrants(channelID, wikiDocID string) ([]GroupGrant, error)
+
+	// GrantGroup gives a role to the members of a group on a wikiDoc, or on the channel wiki when
+	// wikiDocID is empty, replacing the role it had. Returns ErrMalformedWikiDoc if the group or the
+	// role is not valid.
+	GrantGroup(channelID, wikiDocID, groupID string, role WikiDocRole) (GroupGrant, error)
+
+	// RevokeGroup removes the role of a group on a wikiDoc, or on the channel wiki when wikiDocID is
+	// empty
+	RevokeGroup(channelID, wikiDocID, groupID string) error
+
+	// GetUserGroupIDs retrieves the IDs of the groups a user is a member of, cached for GroupCacheTTL
+	GetUserGroupIDs(userID string) ([]string, error)
+
+	// InvalidateUserGroups forgets the cached groups of a user, or of every user when userID is empty
+	InvalidateUserGroups(userID string)
+}
+
+type wikiDocMemberService struct {
+	store  WikiDocMemberStore
+	groups *groupCache
+}
+
+func NewWikiDocMemberService(store WikiDocMemberStore, pluginAPI *pluginapi.Client) WikiDocMemberService {
+	return &wikiDocMemberService{
+		store: store,
+		groups: newGroupCache(GroupCacheTTL, func(userID string) ([]string, error) {
+			groups, err := pluginAPI.Group.ListForUser(userID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get groups of user '%s'", userID)
+			}
+
+			groupIDs := make([]string, 0, len(groups))
+			for _, group := range groups {
+				groupIDs = append(groupIDs, group.Id)
+			}
+			return groupIDs, nil
+		}),
+	}
+}
+
+func (s *wikiDocMemberService) GetMembers(wikiDocID string) ([]WikiDocMember, error) {
+	return s.store.GetMembers(wikiDocID)
+}
+
+func (s *wikiDocMemberService) GetRole(wikiDocID, userID string) (WikiDocRole, error) {
+	return s.store.GetRole(wikiDocID, userID)
+}
+
+func (s *wikiDocMemberService) GetChannelRoles(userID, channelID string) (ChannelRoles, error) {
+	roles, err := s.store.GetRolesInChannel(userID, channelID)
+	if err != nil {
+		return ChannelRoles{}, err
+	}
+
+	groupIDs, err := s.groups.get(userID)
+	if err != nil {
+		return ChannelRoles{}, err
+	}
+	if len(groupIDs) == 0 {
+		return ChannelRoles{WikiDocs: roles}, nil
+	}
+
+	grants, err := s.store.GetGroupGrantsForGroups(channelID, groupIDs)
+	if err != nil {
+		return ChannelRoles{}, err
+	}
+
+	channelRoles := ChannelRoles{WikiDocs: roles}
+	for _, grant := range grants {
+		if grant.WikiDocID == "" {
+			channelRoles.Channel = maxRole(channelRoles.Channel, grant.Role)
+			continue
+		}
+		roles[grant.WikiDocID] = maxRole(roles[grant.WikiDocID], grant.Role)
+	}
+
+	return channelRoles, nil
+}
+
+func (s *wikiDocMemberService) Grant(wikiDocID, userID string, role WikiDocRole) (WikiDocMember, error) {
+	if !model.IsValidId(userID) {
+		return WikiDocMember{}, errors.Wrap(ErrMalformedWikiDoc, "user id must be 26 characters")
+	}
+
+	if !ValidRole(role) {
+		return WikiDocMember{}, errors.Wrapf(ErrMalformedWikiDoc, "invalid role '%s': must be viewer, editor or owner", role)
+	}
+
+	now := model.GetMillis()
+	return s.store.SaveMember(WikiDocMember{
+		WikiDocID: wikiDocID,
+		UserID:    userID,
+		Role:      role,
+		CreateAt:  now,
+		UpdateAt:  now,
+	})
+}
+
+func (s *wikiDocMemberService) Revoke(wikiDocID, userID string) error {
+	return s.store.DeleteMember(wikiDocID, userID)
+}
+
+func (s *wikiDocMemberService) GetGroupGrants(channelID, wikiDocID string) ([]GroupGrant, error) {
+	return s.store.GetGroupGrants(channelID, wikiDocID)
+}
+
+func (s *wikiDocMemberService) GrantGroup(channelID, wikiDocID, groupID string, role WikiDocRole) (GroupGrant, error) {
+	if !model.IsValidId(groupID) {
+		return GroupGrant{}, errors.Wrap(ErrMalformedWikiDoc, "group id must be 26 characters")
+	}
+
+	if !ValidRole(role) {
+		return GroupGrant{}, errors.Wrapf(ErrMalformedWikiDoc, "invalid role '%s': must be viewer, editor or owner", role)
+	}
+
+	now := model.GetMillis()
+	return s.store.SaveGroupGrant(GroupGrant{
+		GroupID:   groupID,
+		ChannelID: channelID,
+		WikiDocID: wikiDocID,
+		Role:      role,
+		CreateAt:  now,
+		UpdateAt:  now,
+	})
+}
+
+func (s *wikiDocMemberService) RevokeGroup(channelID, wikiDocID, groupID string) error {
+	return s.store.DeleteGroupGrant(groupID, channelID, wikiDocID)
+}
+
+func (s *wikiDocMemberService) GetUserGroupIDs(userID string) ([]string, error) {
+	return s.groups.get(userID)
+}
+
+func (s *wikiDocMemberService) InvalidateUserGroups(userID string) {
+	s.groups.invalidate(userID)
+}
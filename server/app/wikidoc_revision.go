@@ -0,0 +1,30 @@
+package app
+
+// WikiDocRevision is a snapshot of a wikiDoc taken every time it is written.
+type WikiDocRevision struct {
+	// ID is the unique identifier of the revision.
+	ID string `json:"id"`
+
+	// WikiDocID is the identifier of the wikiDoc this revision belongs to.
+	WikiDocID string `json:"wiki_doc_id"`
+
+	// Revision is the sequence number of the revision, starting at 1 for every wikiDoc.
+	Revision int64 `json:"revision"`
+
+	// Name is the name of the doc at this revision.
+	Name string `json:"name"`
+
+	// Content is the content of the doc at this revision. It is left empty when listing revisions.
+	Content string `json:"content"`
+
+	// Description is the description of the doc at this revision.
+	Description string `json:"description"`
+
+	// Status is the status of the doc at this revision.
+	Status string `json:"status"`
+
+	// UserID is the identifier of the user who made the change.
+	UserID string `json:"user_id"`
+
+	CreateAt int64 `json:"create_at"`
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// fakeWikiDocStore is an in-memory WikiDocStore for the tests of the services, keeping its wikiDocs
+// in the order they were added. The methods the tests do not use are left to the embedded interface.
+type fakeWikiDocStore struct {
+	WikiDocStore
+	wikiDocs []WikiDoc
+
+	// updateErr, when set, fails the updates once checked, to stop a service right before it stores.
+	updateErr error
+}
+
+func newFakeWikiDocStore(wikiDocs ...WikiDoc) *fakeWikiDocStore {
+	return &fakeWikiDocStore{wikiDocs: wikiDocs}
+}
+
+func (s *fakeWikiDocStore) index(id string) int {
+	for i, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *fakeWikiDocStore) Get(id string) (WikiDoc, error) {
+	i := s.index(id)
+	if i < 0 {
+		return WikiDoc{}, errors.Wrapf(ErrNotFound, "wikiDoc does not exist for id '%s'", id)
+	}
+	return s.wikiDocs[i], nil
+}
+
+func (s *fakeWikiDocStore) Create(wikiDoc WikiDoc) (string, error) {
+	return s.CreateWithRevisions(wikiDoc, nil, nil, "")
+}
+
+func (s *fakeWikiDocStore) CreateWithAttachments(wikiDoc WikiDoc, attachments []Attachment) (string, error) {
+	return s.CreateWithRevisions(wikiDoc, nil, attachments, "")
+}
+
+func (s *fakeWikiDocStore) CreateWithRevisions(wikiDoc WikiDoc, revisions []WikiDoc, attachments []Attachment, userID string) (string, error) {
+	if wikiDoc.ID == "" {
+		wikiDoc.ID = model.NewId()
+	}
+	if len(revisions) > 0 {
+		id := wikiDoc.ID
+		wikiDoc = revisions[len(revisions)-1]
+		wikiDoc.ID = id
+	}
+
+	s.wikiDocs = append(s.wikiDocs, wikiDoc)
+	return wikiDoc.ID, nil
+}
+
+func (s *fakeWikiDocStore) Update(wikiDoc WikiDoc, previousUpdateAt int64, userID string, resetReviews bool) error {
+	i := s.index(wikiDoc.ID)
+	if i < 0 {
+		return errors.Wrapf(ErrNotFound, "wikiDoc does not exist for id '%s'", wikiDoc.ID)
+	}
+	if s.wikiDocs[i].UpdateAt != previousUpdateAt {
+		return errors.Wrapf(ErrConflict, "wikiDoc with id '%s' was modified since %d", wikiDoc.ID, previousUpdateAt)
+	}
+	if s.updateErr != nil {
+		return s.updateErr
+	}
+
+	s.wikiDocs[i] = wikiDoc
+	return nil
+}
+
+func (s *fakeWikiDocStore) Archive(id string, updateAt int64, descendantIDs []string, deleteAt int64) error {
+	i := s.index(id)
+	if i < 0 || s.wikiDocs[i].UpdateAt != updateAt || s.wikiDocs[i].DeleteAt != 0 {
+		return errors.Wrapf(ErrConflict, "wikiDoc with id '%s' was modified since %d", id, updateAt)
+	}
+
+	for _, archivedID := range append(descendantIDs, id) {
+		if j := s.index(archivedID); j >= 0 && s.wikiDocs[j].DeleteAt == 0 {
+			s.wikiDocs[j].DeleteAt = deleteAt
+			s.wikiDocs[j].UpdateAt = nextUpdateAt(s.wikiDocs[j].UpdateAt, deleteAt)
+		}
+	}
+	return nil
+}
+
+func (s *fakeWikiDocStore) Unarchive(id string, updateAt int64) error {
+	if i := s.index(id); i >= 0 {
+		s.wikiDocs[i].DeleteAt = 0
+		s.wikiDocs[i].UpdateAt = nextUpdateAt(s.wikiDocs[i].UpdateAt, updateAt)
+	}
+	return nil
+}
+
+func (s *fakeWikiDocStore) GetWikiDocsForChannel(channelID string) ([]WikiDoc, error) {
+	wikiDocs := []WikiDoc{}
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ChannelID == channelID && wikiDoc.DeleteAt == 0 {
+			wikiDocs = append(wikiDocs, wikiDoc)
+		}
+	}
+
+	sort.SliceStable(wikiDocs, func(i, j int) bool { return wikiDocs[i].SortOrder < wikiDocs[j].SortOrder })
+	return wikiDocs, nil
+}
+
+func (s *fakeWikiDocStore) GetArchivedWikiDocsForChannel(channelID string) ([]WikiDoc, error) {
+	wikiDocs := []WikiDoc{}
+	for _, wikiDoc := range s.wikiDocs {
+		if wikiDoc.ChannelID == channelID && wikiDoc.DeleteAt != 0 {
+			wikiDocs = append(wikiDocs, wikiDoc)
+		}
+	}
+
+	sort.SliceStable(wikiDocs, func(i, j int) bool { return wikiDocs[i].DeleteAt > wikiDocs[j].DeleteAt })
+	return wikiDocs, nil
+}
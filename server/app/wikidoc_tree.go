@@ -0,0 +1,150 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ChildrenPolicy enumerates what happens to the children of a deleted wikiDoc.
+type ChildrenPolicy string
+
+const (
+	// ChildrenCascade deletes the children, and their descendants, along with the wikiDoc.
+	ChildrenCascade ChildrenPolicy = "cascade"
+
+	// ChildrenReparent moves the children to the parent of the deleted wikiDoc, in its place.
+	ChildrenReparent ChildrenPolicy = "reparent"
+)
+
+// ValidChildrenPolicy returns true if the policy is known, or blank.
+func ValidChildrenPolicy(policy ChildrenPolicy) bool {
+	return policy == "" || policy == ChildrenCascade || policy == ChildrenReparent
+}
+
+// DeleteOptions specifies how a wikiDoc is deleted.
+type DeleteOptions struct {
+	// Children is required when the wikiDoc has children; ErrWikiDocHasChildren is returned otherwise.
+	Children ChildrenPolicy
+}
+
+// WikiDocNode is a wikiDoc, without its content, along with its children.
+type WikiDocNode struct {
+	WikiDoc
+	Children []*WikiDocNode `json:"children"`
+}
+
+// BuildWikiDocTree nests the wikiDocs of a channel under their parents. WikiDocs whose parent is
+// not part of the list are treated as top-level docs. Siblings are ordered by SortOrder then name.
+func BuildWikiDocTree(wikiDocs []WikiDoc) []*WikiDocNode {
+	nodes := make(map[string]*WikiDocNode, len(wikiDocs))
+	for _, wikiDoc := range wikiDocs {
+		nodes[wikiDoc.ID] = &WikiDocNode{WikiDoc: wikiDoc, Children: []*WikiDocNode{}}
+	}
+
+	roots := []*WikiDocNode{}
+	for _, wikiDoc := range wikiDocs {
+		node := nodes[wikiDoc.ID]
+		if parent, ok := nodes[wikiDoc.ParentID]; ok && wikiDoc.ParentID != wikiDoc.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortWikiDocNodes(roots)
+
+	return roots
+}
+
+func sortWikiDocNodes(nodes []*WikiDocNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].SortOrder != nodes[j].SortOrder {
+			return nodes[i].SortOrder < nodes[j].SortOrder
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	for _, node := range nodes {
+		sortWikiDocNodes(node.Children)
+	}
+}
+
+// childrenOf returns the IDs of the direct children of parentID, in sibling order.
+func childrenOf(wikiDocs []WikiDoc, parentID string) []string {
+	var children []WikiDoc
+	for _, wikiDoc := range wikiDocs {
+		if wikiDoc.ParentID == parentID && wikiDoc.ID != parentID {
+			children = append(children, wikiDoc)
+		}
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].SortOrder != children[j].SortOrder {
+			return children[i].SortOrder < children[j].SortOrder
+		}
+		return children[i].Name < children[j].Name
+	})
+
+	ids := make([]string, 0, len(children))
+	for _, child := range children {
+		ids = append(ids, child.ID)
+	}
+	return ids
+}
+
+// findWikiDocNode returns the node of wikiDocID in the tree, or nil if it is not there.
+func findWikiDocNode(nodes []*WikiDocNode, wikiDocID string) *WikiDocNode {
+	for _, node := range nodes {
+		if node.ID == wikiDocID {
+			return node
+		}
+		if found := findWikiDocNode(node.Children, wikiDocID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// descendantsOf returns the IDs of all descendants of wikiDocID, deepest first.
+func descendantsOf(wikiDocs []WikiDoc, wikiDocID string) []string {
+	var descendants []string
+	for _, childID := range childrenOf(wikiDocs, wikiDocID) {
+		descendants = append(descendants, descendantsOf(wikiDocs, childID)...)
+		descendants = append(descendants, childID)
+	}
+	return descendants
+}
+
+// checkNoCycle returns ErrWikiDocCycle if parentID is wikiDocID or one of its descendants.
+func checkNoCycle(wikiDocs []WikiDoc, wikiDocID, parentID string) error {
+	parents := make(map[string]string, len(wikiDocs))
+	for _, wikiDoc := range wikiDocs {
+		parents[wikiDoc.ID] = wikiDoc.ParentID
+	}
+
+	visited := map[string]bool{}
+	for current := parentID; current != ""; current = parents[current] {
+		if current == wikiDocID {
+			return errors.Wrapf(ErrWikiDocCycle, "'%s' is '%s' or one of its descendants", parentID, wikiDocID)
+		}
+		if visited[current] {
+			return errors.Wrapf(ErrWikiDocCycle, "the ancestors of '%s' already form a cycle", parentID)
+		}
+		visited[current] = true
+	}
+
+	return nil
+}
+
+// insertAt returns ids with id inserted at position, or appended if position is out of range.
+func insertAt(ids []string, id string, position int) []string {
+	if position < 0 || position >= len(ids) {
+		return append(ids, id)
+	}
+
+	result := make([]string, 0, len(ids)+1)
+	result = append(result, ids[:position]...)
+	result = append(result, id)
+	return append(result, ids[position:]...)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// treeWikiDocs is a channel with the tree a > (b > d, c), and e at the top level.
+var treeWikiDocs = []WikiDoc{
+	{ID: "e", Name: "E", SortOrder: 1},
+	{ID: "c", Name: "C", ParentID: "a", SortOrder: 1},
+	{ID: "b", Name: "B", ParentID: "a", SortOrder: 0},
+	{ID: "a", Name: "A", SortOrder: 0},
+	{ID: "d", Name: "D", ParentID: "b"},
+}
+
+func TestChildrenOf(t *testing.T) {
+	for name, test := range map[string]struct {
+		wikiDocs []WikiDoc
+		parentID string
+		expected []string
+	}{
+		"top level":        {treeWikiDocs, "", []string{"a", "e"}},
+		"by sort order":    {treeWikiDocs, "a", []string{"b", "c"}},
+		"leaf":             {treeWikiDocs, "d", []string{}},
+		"unknown parent":   {treeWikiDocs, "z", []string{}},
+		"same sort order":  {[]WikiDoc{{ID: "2", Name: "Zeta"}, {ID: "1", Name: "Alpha"}}, "", []string{"1", "2"}},
+		"own parent":       {[]WikiDoc{{ID: "x", ParentID: "x"}}, "x", []string{}},
+		"without wikiDocs": {nil, "", []string{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, childrenOf(test.wikiDocs, test.parentID))
+		})
+	}
+}
+
+func TestCheckNoCycle(t *testing.T) {
+	for name, test := range map[string]struct {
+		wikiDocs  []WikiDoc
+		wikiDocID string
+		parentID  string
+		cycle     bool
+	}{
+		"to the top level":        {treeWikiDocs, "b", "", false},
+		"under a sibling":         {treeWikiDocs, "c", "b", false},
+		"under another branch":    {treeWikiDocs, "e", "d", false},
+		"under itself":            {treeWikiDocs, "a", "a", true},
+		"under its child":         {treeWikiDocs, "a", "b", true},
+		"under its descendant":    {treeWikiDocs, "a", "d", true},
+		"under an existing cycle": {[]WikiDoc{{ID: "x", ParentID: "y"}, {ID: "y", ParentID: "x"}}, "z", "x", true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkNoCycle(test.wikiDocs, test.wikiDocID, test.parentID)
+			if test.cycle {
+				assert.ErrorIs(t, err, ErrWikiDocCycle)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInsertAt(t *testing.T) {
+	for name, test := range map[string]struct {
+		ids      []string
+		position int
+		expected []string
+	}{
+		"first":            {[]string{"a", "b"}, 0, []string{"x", "a", "b"}},
+		"middle":           {[]string{"a", "b"}, 1, []string{"a", "x", "b"}},
+		"at the end":       {[]string{"a", "b"}, 2, []string{"a", "b", "x"}},
+		"past the end":     {[]string{"a", "b"}, 5, []string{"a", "b", "x"}},
+		"negative":         {[]string{"a", "b"}, -1, []string{"a", "b", "x"}},
+		"into no siblings": {nil, 0, []string{"x"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, insertAt(test.ids, "x", test.position))
+		})
+	}
+}
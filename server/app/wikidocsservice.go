@@ -1,18 +1,31 @@
 package app
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/pkg/errors"
 
+	root "github.com/CyberPeace-Institute/mattermost-plugin-wiki"
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
 
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
 )
 
 type wikiDocsService struct {
-	store  WikiDocStore
-	api    *pluginapi.Client
-	logger bot.Logger
+	store           WikiDocStore
+	reviewStore     ReviewStore
+	settingsStore   ChannelSettingsStore
+	commentStore    CommentStore
+	watchStore      WatchStore
+	linkStore       LinkStore
+	attachmentStore AttachmentStore
+	renders         *renderCache
+	poster          bot.Poster
+	api             *pluginapi.Client
+	logger          bot.Logger
 }
 
 // WikiDocService is the wikiDoc service for managing wikiDocs
@@ -21,7 +34,10 @@ type WikiDocService interface {
 	// Get retrieves a wikiDoc. Returns ErrNotFound if not found.
 	Get(id string) (WikiDoc, error)
 
-	// Create creates a new wikiDoc
+	// Create creates a new wikiDoc on behalf of its owner, announces it in its channel and notifies
+	// the users mentioned in its content. Its [[links]] are stored, and the links to its name that
+	// resolved to no wikiDoc now resolve to it. Returns ErrApprovalsRequired if it is published in a
+	// channel that requires approvals.
 	Create(wikiDoc WikiDoc) (string, error)
 
 	// GetWikiDocs retrieves all wikiDocs
@@ -30,14 +46,104 @@ type WikiDocService interface {
 	// GetWikiDocsForChannel retrieves all wikiDocs on the specified channel given the provided options
 	//GetWikiDocsForChannel(requesterInfo RequesterInfo, channelID string, opts WikiDocFilterOptions) (GetWikiDocsResults, error)
 
-	// Update updates a wikiDoc
-	Update(wikiDoc WikiDoc) error
+	// Update updates a wikiDoc and returns its new state. The UpdateAt of the given wikiDoc is the
+	// version the change is based on; returns ErrConflict if the wikiDoc was modified since.
+	// Returns ErrInvalidTransition if the new status cannot follow the current one, and
+	// ErrApprovalsRequired if it is published without the approvals its channel requires.
+	// Changes to the name, description or content set its reviews back to pending, and the comment
+	// threads follow the text they quote and the [[links]] are stored again when the content changes.
+	// Renaming it resolves the links to its new name that resolved to no wikiDoc. Publishing a
+	// wikiDoc announces it in its channel. The watchers of the wikiDoc are notified of the change, see WatchService, and
+	// the users newly mentioned in its content are notified right away.
+	Update(wikiDoc WikiDoc, userID string) (WikiDoc, error)
+
+	// SetRestricted changes whether a wikiDoc is restricted to its members and returns its new state.
+	SetRestricted(wikiDoc WikiDoc, restricted bool, userID string) (WikiDoc, error)
+
+	// Duplicate copies a wikiDoc, as a top-level draft owned by userID, to the channel given
+	// by the options. The copy records the original as its source, and has copies of its
+	// attachments. Returns the ID of the copy.
+	Duplicate(wikiDoc WikiDoc, options DuplicateOptions, userID string) (string, error)
+
+	// Delete moves a wikiDoc to the trash, handling its children as specified by the options.
+	// Returns ErrWikiDocInTrash if the wikiDoc is already in the trash, and ErrConflict if the
+	// wikiDoc or a child to reparent was modified meanwhile.
+	Delete(id string, userID string, options DeleteOptions) error
+
+	// GetTrash retrieves the wikiDocs of a channel that are in the trash, without their content,
+	// most recently deleted first
+	GetTrash(channelID string) ([]WikiDoc, error)
+
+	// Restore takes a wikiDoc out of the trash, along with the descendants deleted with it. The
+	// wikiDoc goes back under its parent, or to the top level if the parent is no longer there.
+	// Returns ErrWikiDocNotInTrash if the wikiDoc is not in the trash.
+	Restore(id string, userID string) (WikiDoc, error)
+
+	// Purge permanently deletes a wikiDoc in the trash, along with the descendants deleted with it.
+	// Their attachments are detached, but the files stay in the storage of the server since the
+	// Mattermost file API cannot delete files. Returns ErrWikiDocNotInTrash if the wikiDoc is not in
+	// the trash.
+	Purge(id string, userID string) error
+
+	// PurgeTrash permanently deletes the wikiDocs moved to the trash before the given time, as Purge
+	// does, and returns how many were deleted
+	PurgeTrash(before int64) (int, error)
+
+	// GetTree retrieves the wikiDocs of a channel, without their content, nested under their parents
+	GetTree(channelID string) ([]*WikiDocNode, error)
+
+	// GetChannelIDs retrieves the channels of a team with wikiDocs outside of the trash
+	GetChannelIDs(teamID string) ([]string, error)
+
+	// Export streams a zip archive of the wikiDocs of the given channels: a Markdown file per
+	// wikiDoc, with a YAML front matter made of its export-tagged fields, laid out by hierarchy,
+	// and the manifest describing them, see ExportManifest.
+	Export(w io.Writer, manifest ExportManifest, channels []ExportChannel) error
+
+	// Import creates wikiDocs in a channel from documents, nested as the documents are, see
+	// ReadMarkdownArchive. The documents named like a wikiDoc of the channel are handled as the
+	// conflict policy of the options says. The wikiDocs created are neither announced nor notified
+	// to the users they mention. A document that cannot be imported is reported as failed, and the
+	// import goes on. Returns ErrMalformedImport if the conflict policy is unknown.
+	Import(docs []ImportDoc, options ImportOptions) (*ImportReport, error)
+
+	// Move places a wikiDoc under parentID at the given position among its siblings; a negative
+	// position appends it. Returns ErrWikiDocCycle if parentID is the wikiDoc or one of its descendants,
+	// and ErrConflict if the wikiDoc was modified since its UpdateAt.
+	Move(wikiDoc WikiDoc, parentID string, position int, userID string) (WikiDoc, error)
+
+	// GetRevisions retrieves the revision history of a wikiDoc, newest first
+	GetRevisions(wikiDocID string) ([]WikiDocRevision, error)
+
+	// GetRevision retrieves a single revision of a wikiDoc. Returns ErrNotFound if not found.
+	GetRevision(wikiDocID string, revision int64) (WikiDocRevision, error)
+
+	// GetStatusChanges retrieves the status transitions of a wikiDoc, newest first
+	GetStatusChanges(wikiDocID string) ([]WikiDocStatusChange, error)
+
+	// RestoreRevision overwrites the name, description and content of a wikiDoc with those of one of
+	// its revisions. The status of the wikiDoc is kept.
+	RestoreRevision(wikiDoc WikiDoc, revision int64, userID string) (WikiDoc, error)
+
+	// GetLinks retrieves the [[links]] of a wikiDoc, marking those whose target is not a wikiDoc
+	// outside of the trash as missing
+	GetLinks(wikiDocID string) ([]WikiDocLink, error)
 
-	// Duplicate duplicates a wikiDoc
-	Duplicate(wikiDoc WikiDoc, userID string) (string, error)
+	// GetBacklinks retrieves the wikiDocs outside of the trash that link to a wikiDoc, without their
+	// content, by name
+	GetBacklinks(wikiDocID string) ([]WikiDoc, error)
 
-	// Delete deletes a wikiDoc
-	Delete(id string) error
+	// Render renders the content of a wikiDoc to sanitized HTML, with anchors on its headings, its
+	// table of contents and its outline. The [[links]] to wikiDocs that canView rejects are rendered
+	// as missing. Renders are cached by version.
+	Render(wikiDoc WikiDoc, canView func(wikiDocID string) bool) (*RenderedWikiDoc, error)
+
+	// Diff computes the changes between two versions of a wikiDoc. Revision 0 designates the current doc.
+	Diff(wikiDocID string, from, to int64, options DiffOptions) (*WikiDocDiff, error)
+
+	// OpenCreateWikiDocDialog opens the interactive dialog to create a wikiDoc. The dialog is
+	// submitted to the createWikiDocFromDialog endpoint.
+	OpenCreateWikiDocDialog(triggerID string) error
 }
 
 // DialogFieldWikiDocIDKey is the key for the wikiDoc ID field used in OpenCreateWikiDocRunDialog.
@@ -55,24 +161,99 @@ const DialogFieldStatusKey = "status"
 // DialogFieldDescriptionKey is the key for the description textarea field used in UpdateWikiDocRunDialog
 const DialogFieldDescriptionKey = "description"
 
-func NewWikiDocService(store WikiDocStore, logger bot.Logger, api *pluginapi.Client) WikiDocService {
+func NewWikiDocService(store WikiDocStore, reviewStore ReviewStore, settingsStore ChannelSettingsStore, commentStore CommentStore, watchStore WatchStore, linkStore LinkStore, attachmentStore AttachmentStore, poster bot.Poster, logger bot.Logger, api *pluginapi.Client) WikiDocService {
 	return &wikiDocsService{
-		store:  store,
-		logger: logger,
-		api:    api,
+		store:           store,
+		reviewStore:     reviewStore,
+		settingsStore:   settingsStore,
+		commentStore:    commentStore,
+		watchStore:      watchStore,
+		linkStore:       linkStore,
+		attachmentStore: attachmentStore,
+		renders:         newRenderCache(maxRenderCacheEntries),
+		poster:          poster,
+		logger:          logger,
+		api:             api,
 	}
 }
 
 func (s *wikiDocsService) Create(wikiDoc WikiDoc) (string, error) {
-	wikiDoc.CreateAt = model.GetMillis()
-	wikiDoc.UpdateAt = wikiDoc.CreateAt
+	return s.create(wikiDoc, false)
+}
+
+// create creates a wikiDoc. Quiet creations, such as imports, are neither announced nor notified to
+// the users mentioned in the content.
+func (s *wikiDocsService) create(wikiDoc WikiDoc, quiet bool) (string, error) {
+	wikiDoc, err := s.checkCreatable(wikiDoc)
+	if err != nil {
+		return "", err
+	}
+
+	return s.storeCreated(wikiDoc, nil, nil, quiet)
+}
+
+// checkCreatable verifies that a wikiDoc can be created, and returns it with its status normalized
+// and its dates set.
+func (s *wikiDocsService) checkCreatable(wikiDoc WikiDoc) (WikiDoc, error) {
+	if err := checkCreatableStatus(wikiDoc.Status); err != nil {
+		return WikiDoc{}, err
+	}
+	wikiDoc.Status = NormalizeStatus(wikiDoc.Status)
+
+	if wikiDoc.Status == StatusPublished {
+		if err := s.checkApprovals(wikiDoc, false); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	if wikiDoc.ParentID != "" {
+		if err := s.checkParent(wikiDoc.ChannelID, wikiDoc.ParentID); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	// Imported wikiDocs keep their original dates.
+	if wikiDoc.CreateAt == 0 {
+		wikiDoc.CreateAt = model.GetMillis()
+		wikiDoc.UpdateAt = wikiDoc.CreateAt
+	} else if wikiDoc.UpdateAt < wikiDoc.CreateAt {
+		wikiDoc.UpdateAt = wikiDoc.CreateAt
+	}
+
+	return wikiDoc, nil
+}
 
-	newID, err := s.store.Create(wikiDoc)
+// storeCreated stores a wikiDoc checked by checkCreatable, along with its later revisions, oldest
+// first, and its attachments, and publishes its creation in its last revision.
+func (s *wikiDocsService) storeCreated(wikiDoc WikiDoc, revisions []WikiDoc, attachments []Attachment, quiet bool) (string, error) {
+	newID, err := s.store.CreateWithRevisions(wikiDoc, revisions, attachments, wikiDoc.OwnerUserID)
 	if err != nil {
 		return "", err
 	}
+	if len(revisions) > 0 {
+		wikiDoc = revisions[len(revisions)-1]
+	}
 	wikiDoc.ID = newID
 
+	// The wikiDoc is saved by now: links that fail to be stored or resolved are logged, and fixed the
+	// next time the wikiDoc or the targets are saved.
+	if err = s.updateLinks(wikiDoc); err != nil {
+		s.logger.Warnf("failed to store the links of wikiDoc %s: %v", newID, err)
+	}
+	if err = s.resolveLinks(wikiDoc.TeamID, wikiDoc.Name); err != nil {
+		s.logger.Warnf("failed to resolve the links to wikiDoc %s: %v", newID, err)
+	}
+
+	s.publishChange(WikiDocChange{
+		Type:    ChangeTypeCreated,
+		ActorID: wikiDoc.OwnerUserID,
+		WikiDoc: wikiDoc,
+	})
+	if !quiet {
+		s.announce(wikiDoc, false)
+		s.notifyMentions(WikiDoc{}, wikiDoc, wikiDoc.OwnerUserID)
+	}
+
 	return newID, nil
 }
 
@@ -85,33 +266,711 @@ func (s *wikiDocsService) GetWikiDocs(requesterInfo RequesterInfo, options WikiD
 	if err != nil {
 		return nil, errors.Wrap(err, "can't get wikiDoc runs from the store")
 	}
+
+	var highlights map[string]SearchHighlight
+	if terms := SearchTerms(options.SearchTerm); len(terms) > 0 {
+		highlights = make(map[string]SearchHighlight, len(results.Items))
+		for _, wikiDoc := range results.Items {
+			highlights[wikiDoc.ID] = HighlightWikiDoc(wikiDoc, terms)
+		}
+	}
+
 	return &GetWikiDocsResults{
 		TotalCount: results.TotalCount,
 		PageCount:  results.PageCount,
 		HasMore:    results.HasMore,
 		Items:      results.Items,
+		Highlights: highlights,
 	}, nil
 }
 
-func (s *wikiDocsService) Update(wikiDoc WikiDoc) error {
+func (s *wikiDocsService) Update(wikiDoc WikiDoc, userID string) (WikiDoc, error) {
 	if wikiDoc.DeleteAt != 0 {
-		return errors.New("cannot update a wikiDoc that is archived")
+		return WikiDoc{}, errors.New("cannot update a wikiDoc that is archived")
+	}
+
+	previous, err := s.store.Get(wikiDoc.ID)
+	if err != nil {
+		return WikiDoc{}, err
+	}
+	if previous.UpdateAt != wikiDoc.UpdateAt {
+		return WikiDoc{}, errors.Wrapf(ErrConflict, "wikiDoc '%s' was modified since %d", wikiDoc.ID, wikiDoc.UpdateAt)
+	}
+
+	wikiDoc.Status = NormalizeStatus(wikiDoc.Status)
+	if err = checkTransition(previous.Status, wikiDoc.Status); err != nil {
+		return WikiDoc{}, err
+	}
+
+	// Approvals are given to a version of the doc: edits need to be reviewed again, so an edit
+	// published at once has no approvals.
+	edited := reviewedFieldsChanged(previous, wikiDoc)
+	if wikiDoc.Status == StatusPublished && previous.Status != StatusPublished {
+		if err = s.checkApprovals(wikiDoc, edited); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	// UpdateAt doubles as the version of the doc, so it must change on every write.
+	previousUpdateAt := wikiDoc.UpdateAt
+	wikiDoc.UpdateAt = nextUpdateAt(previousUpdateAt, model.GetMillis())
+
+	if err = s.store.Update(wikiDoc, previousUpdateAt, userID, edited); err != nil {
+		return WikiDoc{}, err
+	}
+
+	if wikiDoc.Content != previous.Content {
+		// The wikiDoc is saved by now, so failing to reanchor its comments must not report the
+		// update as failed: the comments keep their previous anchors.
+		if err = s.reanchorComments(wikiDoc); err != nil {
+			s.logger.Warnf("failed to anchor the comments of wikiDoc %s: %v", wikiDoc.ID, err)
+		}
+		if err = s.updateLinks(wikiDoc); err != nil {
+			s.logger.Warnf("failed to store the links of wikiDoc %s: %v", wikiDoc.ID, err)
+		}
+	}
+
+	// The links to the previous name may now resolve to another wikiDoc, or to none.
+	if wikiDoc.Name != previous.Name {
+		if err = s.resolveLinks(wikiDoc.TeamID, wikiDoc.Name, previous.Name); err != nil {
+			s.logger.Warnf("failed to resolve the links to wikiDoc %s: %v", wikiDoc.ID, err)
+		}
+	}
+
+	changeType := ChangeTypeUpdated
+	if previous.Status != wikiDoc.Status {
+		changeType = ChangeTypeStatusChanged
+	}
+
+	s.publishChange(WikiDocChange{
+		Type:     changeType,
+		ActorID:  userID,
+		WikiDoc:  wikiDoc,
+		Previous: &previous,
+	})
+	if wikiDoc.Status == StatusPublished && previous.Status != StatusPublished {
+		s.announce(wikiDoc, true)
+	}
+
+	// The change is saved even if its watchers cannot be notified.
+	if err = s.queueNotifications(previous, wikiDoc, userID); err != nil {
+		s.logger.Warnf("failed to notify the watchers of wikiDoc %s: %v", wikiDoc.ID, err)
+	}
+	if wikiDoc.Content != previous.Content {
+		s.notifyMentions(previous, wikiDoc, userID)
+	}
+
+	return wikiDoc, nil
+}
+
+func (s *wikiDocsService) SetRestricted(wikiDoc WikiDoc, restricted bool, userID string) (WikiDoc, error) {
+	if wikiDoc.DeleteAt != 0 {
+		return WikiDoc{}, errors.New("cannot restrict a wikiDoc that is archived")
+	}
+
+	if wikiDoc.Restricted == restricted {
+		return wikiDoc, nil
+	}
+
+	now := model.GetMillis()
+	if err := s.store.SetRestricted(wikiDoc.ID, restricted, now); err != nil {
+		return WikiDoc{}, err
+	}
+
+	previous := wikiDoc
+	wikiDoc.Restricted = restricted
+	wikiDoc.UpdateAt = nextUpdateAt(previous.UpdateAt, now)
+
+	s.publishChange(WikiDocChange{
+		Type:     ChangeTypeAccessChanged,
+		ActorID:  userID,
+		WikiDoc:  wikiDoc,
+		Previous: &previous,
+	})
+
+	return wikiDoc, nil
+}
+
+// reanchorComments moves the comment threads of a wikiDoc to where the text they quote is in its new
+// content.
+func (s *wikiDocsService) reanchorComments(wikiDoc WikiDoc) error {
+	comments, err := s.commentStore.GetComments(wikiDoc.ID)
+	if err != nil {
+		return err
+	}
+
+	var moved []Comment
+	for _, comment := range comments {
+		if ReanchorComment(&comment, wikiDoc.Content) {
+			moved = append(moved, comment)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	return s.commentStore.UpdateAnchors(moved)
+}
+
+// reviewedFieldsChanged returns true if an update changes the fields of a wikiDoc that reviewers
+// approve, which resets its reviews.
+func reviewedFieldsChanged(previous, wikiDoc WikiDoc) bool {
+	return wikiDoc.Name != previous.Name || wikiDoc.Description != previous.Description || wikiDoc.Content != previous.Content
+}
+
+// checkApprovals returns ErrApprovalsRequired if the current version of the wikiDoc was approved by
+// fewer reviewers than its channel requires for publishing. The approvals of an edited wikiDoc are not counted, since
+// the edit resets them.
+func (s *wikiDocsService) checkApprovals(wikiDoc WikiDoc, edited bool) error {
+	settings, err := s.settingsStore.GetChannelSettings(wikiDoc.ChannelID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the settings of channel '%s'", wikiDoc.ChannelID)
+	}
+
+	if settings.RequiredApprovals == 0 {
+		return nil
+	}
+
+	approvals := 0
+	if wikiDoc.ID != "" && !edited {
+		if approvals, err = s.reviewStore.CountApprovals(wikiDoc.ID, wikiDoc.UpdateAt); err != nil {
+			return errors.Wrapf(err, "failed to count the approvals of wikiDoc '%s'", wikiDoc.ID)
+		}
+	}
+
+	if approvals < settings.RequiredApprovals {
+		return errors.Wrapf(ErrApprovalsRequired, "wikiDoc has %d of the %d approvals required", approvals, settings.RequiredApprovals)
+	}
+
+	return nil
+}
+
+func (s *wikiDocsService) Duplicate(wikiDoc WikiDoc, options DuplicateOptions, userID string) (string, error) {
+	if wikiDoc.DeleteAt != 0 {
+		return "", errors.New("cannot duplicate a wikiDoc that is archived")
+	}
+
+	channelID := options.ChannelID
+	if channelID == "" {
+		channelID = wikiDoc.ChannelID
+	}
+
+	channel, err := s.api.Channel.Get(channelID)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get channel %s", channelID)
+	}
+	if options.TeamID != "" && options.TeamID != channel.TeamId {
+		return "", errors.Wrap(ErrMalformedWikiDoc, "channel not in given team")
+	}
+
+	name := strings.TrimSpace(options.Name)
+	if name == "" {
+		name = wikiDoc.Name
+		if channel.Id == wikiDoc.ChannelID {
+			name = fmt.Sprintf("%s (copy)", wikiDoc.Name)
+		}
+	}
+
+	duplicate, err := s.checkCreatable(WikiDoc{
+		ID:          model.NewId(),
+		Name:        name,
+		Description: wikiDoc.Description,
+		Status:      StatusDraft,
+		OwnerUserID: userID,
+		TeamID:      channel.TeamId,
+		ChannelID:   channel.Id,
+		SourceID:    wikiDoc.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The files are only copied once the copy is known to be valid, since they cannot be deleted.
+	attachments, content, err := s.copyAttachments(wikiDoc, duplicate.ID, userID)
+	if err != nil {
+		return "", err
+	}
+	duplicate.Content = content
+
+	return s.storeCreated(duplicate, nil, attachments, false)
+}
+
+func (s *wikiDocsService) Delete(id string, userID string, options DeleteOptions) error {
+	wikiDoc, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if wikiDoc.DeleteAt != 0 {
+		return errors.Wrapf(ErrWikiDocInTrash, "wikiDoc '%s' was already deleted", wikiDoc.ID)
+	}
+
+	// Descendants deleted along with the wikiDoc share its DeleteAt, so they are restored with it.
+	deleteAt := model.GetMillis()
+
+	channelWikiDocs, err := s.store.GetWikiDocsForChannel(wikiDoc.ChannelID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the wikiDocs of the channel")
+	}
+
+	var descendants []WikiDoc
+	children := childrenOf(channelWikiDocs, wikiDoc.ID)
+	if len(children) > 0 {
+		switch options.Children {
+		case ChildrenCascade:
+			byID := make(map[string]WikiDoc, len(channelWikiDocs))
+			for _, channelWikiDoc := range channelWikiDocs {
+				byID[channelWikiDoc.ID] = channelWikiDoc
+			}
+
+			for _, descendantID := range descendantsOf(channelWikiDocs, wikiDoc.ID) {
+				descendants = append(descendants, byID[descendantID])
+			}
+		case ChildrenReparent:
+			var siblings []string
+			for _, siblingID := range childrenOf(channelWikiDocs, wikiDoc.ParentID) {
+				if siblingID == wikiDoc.ID {
+					siblings = append(siblings, children...)
+					continue
+				}
+				siblings = append(siblings, siblingID)
+			}
+
+			moved := make(map[string]int64, len(children))
+			for _, channelWikiDoc := range channelWikiDocs {
+				if channelWikiDoc.ParentID == wikiDoc.ID {
+					moved[channelWikiDoc.ID] = channelWikiDoc.UpdateAt
+				}
+			}
+
+			if err = s.store.Reorder(wikiDoc.ParentID, siblings, moved, deleteAt); err != nil {
+				return errors.Wrap(err, "failed to reparent the children of the wikiDoc")
+			}
+
+			for _, channelWikiDoc := range channelWikiDocs {
+				if channelWikiDoc.ParentID != wikiDoc.ID {
+					continue
+				}
+
+				previous := channelWikiDoc
+				channelWikiDoc.ParentID = wikiDoc.ParentID
+				channelWikiDoc.UpdateAt = nextUpdateAt(previous.UpdateAt, deleteAt)
+				s.publishChange(WikiDocChange{
+					Type:     ChangeTypeMoved,
+					ActorID:  userID,
+					WikiDoc:  channelWikiDoc,
+					Previous: &previous,
+				})
+			}
+		default:
+			return errors.Wrapf(ErrWikiDocHasChildren, "wikiDoc '%s' has %d children", wikiDoc.ID, len(children))
+		}
 	}
 
-	wikiDoc.UpdateAt = model.GetMillis()
+	return s.archive(wikiDoc, descendants, userID, deleteAt)
+}
+
+func (s *wikiDocsService) archive(wikiDoc WikiDoc, descendants []WikiDoc, userID string, deleteAt int64) error {
+	descendantIDs := make([]string, 0, len(descendants))
+	for _, descendant := range descendants {
+		descendantIDs = append(descendantIDs, descendant.ID)
+	}
+
+	if err := s.store.Archive(wikiDoc.ID, wikiDoc.UpdateAt, descendantIDs, deleteAt); err != nil {
+		return err
+	}
+
+	var names []string
+	for _, archived := range append(descendants, wikiDoc) {
+		previous := archived
+		archived.DeleteAt = deleteAt
+		archived.UpdateAt = nextUpdateAt(previous.UpdateAt, deleteAt)
+		names = append(names, archived.Name)
+
+		s.publishChange(WikiDocChange{
+			Type:     ChangeTypeDeleted,
+			ActorID:  userID,
+			WikiDoc:  archived,
+			Previous: &previous,
+		})
+	}
+
+	// The links to the wikiDocs in the trash move to the other wikiDocs with their names, if any.
+	if err := s.resolveLinks(wikiDoc.TeamID, names...); err != nil {
+		s.logger.Warnf("failed to resolve the links to wikiDoc %s: %v", wikiDoc.ID, err)
+	}
+
+	return nil
+}
+
+func (s *wikiDocsService) GetTrash(channelID string) ([]WikiDoc, error) {
+	return s.store.GetArchivedWikiDocsForChannel(channelID)
+}
+
+func (s *wikiDocsService) Restore(id string, userID string) (WikiDoc, error) {
+	wikiDoc, deletedWith, err := s.getTrashed(id)
+	if err != nil {
+		return WikiDoc{}, err
+	}
+
+	channelWikiDocs, err := s.store.GetWikiDocsForChannel(wikiDoc.ChannelID)
+	if err != nil {
+		return WikiDoc{}, errors.Wrap(err, "failed to get the wikiDocs of the channel")
+	}
+
+	parentExists := false
+	for _, channelWikiDoc := range channelWikiDocs {
+		if channelWikiDoc.ID == wikiDoc.ParentID {
+			parentExists = true
+			break
+		}
+	}
+
+	if wikiDoc.ParentID != "" && !parentExists {
+		topLevel := append(childrenOf(channelWikiDocs, ""), wikiDoc.ID)
+		if err = s.store.Reorder("", topLevel, nil, 0); err != nil {
+			return WikiDoc{}, errors.Wrap(err, "failed to move the wikiDoc to the top level")
+		}
+	}
+
+	now := model.GetMillis()
+	var names []string
+	for _, restored := range append([]WikiDoc{wikiDoc}, deletedWith...) {
+		if err = s.store.Unarchive(restored.ID, now); err != nil {
+			return WikiDoc{}, err
+		}
+		names = append(names, restored.Name)
+
+		previous := restored
+		restored.DeleteAt = 0
+		restored.UpdateAt = nextUpdateAt(previous.UpdateAt, now)
+		if restored.ID == wikiDoc.ID && !parentExists {
+			restored.ParentID = ""
+		}
+
+		s.publishChange(WikiDocChange{
+			Type:     ChangeTypeRestored,
+			ActorID:  userID,
+			WikiDoc:  restored,
+			Previous: &previous,
+		})
+	}
+
+	if err = s.resolveLinks(wikiDoc.TeamID, names...); err != nil {
+		s.logger.Warnf("failed to resolve the links to wikiDoc %s: %v", wikiDoc.ID, err)
+	}
+
+	return s.store.Get(wikiDoc.ID)
+}
+
+func (s *wikiDocsService) Purge(id string, userID string) error {
+	wikiDoc, deletedWith, err := s.getTrashed(id)
+	if err != nil {
+		return err
+	}
+
+	// Descendants go first so that a failure never leaves them without their parent.
+	for _, purged := range append(deletedWith, wikiDoc) {
+		if err = s.purge(purged, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *wikiDocsService) PurgeTrash(before int64) (int, error) {
+	ids, err := s.store.GetArchivedWikiDocIDs(before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get the wikiDocs to purge")
+	}
+
+	for i, id := range ids {
+		wikiDoc, err := s.store.Get(id)
+		if err != nil {
+			return i, errors.Wrapf(err, "failed to get wikiDoc '%s' to purge", id)
+		}
 
-	if err := s.store.Update(wikiDoc); err != nil {
+		if err = s.purge(wikiDoc, ""); err != nil {
+			return i, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+func (s *wikiDocsService) purge(wikiDoc WikiDoc, userID string) error {
+	if err := s.store.Delete(wikiDoc.ID); err != nil {
 		return err
 	}
 
+	s.publishChange(WikiDocChange{
+		Type:     ChangeTypePurged,
+		ActorID:  userID,
+		WikiDoc:  wikiDoc,
+		Previous: &wikiDoc,
+	})
+
 	return nil
 }
 
-func (s *wikiDocsService) Duplicate(wikiDoc WikiDoc, userID string) (string, error) {
-	//TODO implement me
-	panic("implement me")
+// getTrashed returns a wikiDoc in the trash, and the descendants that were deleted along with it,
+// deepest first.
+func (s *wikiDocsService) getTrashed(id string) (WikiDoc, []WikiDoc, error) {
+	wikiDoc, err := s.store.Get(id)
+	if err != nil {
+		return WikiDoc{}, nil, err
+	}
+
+	if wikiDoc.DeleteAt == 0 {
+		return WikiDoc{}, nil, errors.Wrapf(ErrWikiDocNotInTrash, "wikiDoc '%s' is not deleted", wikiDoc.ID)
+	}
+
+	trash, err := s.store.GetArchivedWikiDocsForChannel(wikiDoc.ChannelID)
+	if err != nil {
+		return WikiDoc{}, nil, errors.Wrap(err, "failed to get the trash of the channel")
+	}
+
+	byID := map[string]WikiDoc{}
+	var deletedTogether []WikiDoc
+	for _, trashed := range trash {
+		if trashed.DeleteAt == wikiDoc.DeleteAt {
+			byID[trashed.ID] = trashed
+			deletedTogether = append(deletedTogether, trashed)
+		}
+	}
+
+	var deletedWith []WikiDoc
+	for _, descendantID := range descendantsOf(deletedTogether, wikiDoc.ID) {
+		deletedWith = append(deletedWith, byID[descendantID])
+	}
+
+	return wikiDoc, deletedWith, nil
+}
+
+// nextUpdateAt returns the UpdateAt of a wikiDoc written at the given time, which must be greater
+// than its previous UpdateAt since it doubles as its version.
+func nextUpdateAt(previous, now int64) int64 {
+	if now <= previous {
+		return previous + 1
+	}
+	return now
+}
+
+func (s *wikiDocsService) GetTree(channelID string) ([]*WikiDocNode, error) {
+	wikiDocs, err := s.store.GetWikiDocsForChannel(channelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the wikiDocs of the channel")
+	}
+
+	return BuildWikiDocTree(wikiDocs), nil
 }
 
-func (s *wikiDocsService) Delete(id string) error {
-	return s.store.Delete(id)
+func (s *wikiDocsService) GetChannelIDs(teamID string) ([]string, error) {
+	return s.store.GetChannelIDs(teamID)
+}
+
+func (s *wikiDocsService) Move(wikiDoc WikiDoc, parentID string, position int, userID string) (WikiDoc, error) {
+	if wikiDoc.DeleteAt != 0 {
+		return WikiDoc{}, errors.New("cannot move a wikiDoc that is archived")
+	}
+
+	if parentID != "" {
+		if err := s.checkParent(wikiDoc.ChannelID, parentID); err != nil {
+			return WikiDoc{}, err
+		}
+	}
+
+	channelWikiDocs, err := s.store.GetWikiDocsForChannel(wikiDoc.ChannelID)
+	if err != nil {
+		return WikiDoc{}, errors.Wrap(err, "failed to get the wikiDocs of the channel")
+	}
+
+	if err = checkNoCycle(channelWikiDocs, wikiDoc.ID, parentID); err != nil {
+		return WikiDoc{}, err
+	}
+
+	var siblings []string
+	for _, siblingID := range childrenOf(channelWikiDocs, parentID) {
+		if siblingID != wikiDoc.ID {
+			siblings = append(siblings, siblingID)
+		}
+	}
+	siblings = insertAt(siblings, wikiDoc.ID, position)
+
+	now := model.GetMillis()
+	if err = s.store.Reorder(parentID, siblings, map[string]int64{wikiDoc.ID: wikiDoc.UpdateAt}, now); err != nil {
+		return WikiDoc{}, errors.Wrapf(err, "failed to move wikiDoc '%s'", wikiDoc.ID)
+	}
+
+	previous := wikiDoc
+	wikiDoc.ParentID = parentID
+	wikiDoc.UpdateAt = nextUpdateAt(previous.UpdateAt, now)
+	for i, siblingID := range siblings {
+		if siblingID == wikiDoc.ID {
+			wikiDoc.SortOrder = i
+		}
+	}
+
+	s.publishChange(WikiDocChange{
+		Type:     ChangeTypeMoved,
+		ActorID:  userID,
+		WikiDoc:  wikiDoc,
+		Previous: &previous,
+	})
+
+	return wikiDoc, nil
+}
+
+// checkParent verifies that parentID is a wikiDoc of the given channel that is not deleted.
+func (s *wikiDocsService) checkParent(channelID, parentID string) error {
+	parent, err := s.store.Get(parentID)
+	if errors.Is(err, ErrNotFound) {
+		return errors.Wrapf(ErrMalformedWikiDoc, "parent wikiDoc '%s' does not exist", parentID)
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to get parent wikiDoc '%s'", parentID)
+	}
+
+	if parent.ChannelID != channelID || parent.DeleteAt != 0 {
+		return errors.Wrap(ErrMalformedWikiDoc, "parent must be a wikiDoc of the same channel")
+	}
+
+	return nil
+}
+
+func (s *wikiDocsService) GetRevisions(wikiDocID string) ([]WikiDocRevision, error) {
+	return s.store.GetRevisions(wikiDocID)
+}
+
+func (s *wikiDocsService) GetRevision(wikiDocID string, revision int64) (WikiDocRevision, error) {
+	return s.store.GetRevision(wikiDocID, revision)
+}
+
+func (s *wikiDocsService) GetStatusChanges(wikiDocID string) ([]WikiDocStatusChange, error) {
+	return s.store.GetStatusChanges(wikiDocID)
+}
+
+func (s *wikiDocsService) RestoreRevision(wikiDoc WikiDoc, revision int64, userID string) (WikiDoc, error) {
+	rev, err := s.store.GetRevision(wikiDoc.ID, revision)
+	if err != nil {
+		return WikiDoc{}, err
+	}
+
+	wikiDoc.Name = rev.Name
+	wikiDoc.Description = rev.Description
+	wikiDoc.Content = rev.Content
+
+	restoredWikiDoc, err := s.Update(wikiDoc, userID)
+	if err != nil {
+		return WikiDoc{}, errors.Wrapf(err, "failed to restore revision %d", revision)
+	}
+
+	return restoredWikiDoc, nil
+}
+
+func (s *wikiDocsService) Diff(wikiDocID string, from, to int64, options DiffOptions) (*WikiDocDiff, error) {
+	fromDoc, fromVersion, err := s.getVersion(wikiDocID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toDoc, toVersion, err := s.getVersion(wikiDocID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WikiDocDiff{
+		WikiDocID:   wikiDocID,
+		From:        fromVersion,
+		To:          toVersion,
+		Name:        DiffText(fromDoc.Name, toDoc.Name, options),
+		Description: DiffText(fromDoc.Description, toDoc.Description, options),
+		Content:     DiffText(fromDoc.Content, toDoc.Content, options),
+	}, nil
+}
+
+// getVersion returns the state of a wikiDoc at the given revision, or its current state for revision 0.
+func (s *wikiDocsService) getVersion(wikiDocID string, revision int64) (WikiDoc, WikiDocVersion, error) {
+	if revision == 0 {
+		wikiDoc, err := s.store.Get(wikiDocID)
+		if err != nil {
+			return WikiDoc{}, WikiDocVersion{}, err
+		}
+
+		return wikiDoc, WikiDocVersion{Current: true, UpdateAt: wikiDoc.UpdateAt}, nil
+	}
+
+	rev, err := s.store.GetRevision(wikiDocID, revision)
+	if err != nil {
+		return WikiDoc{}, WikiDocVersion{}, err
+	}
+
+	wikiDoc := WikiDoc{
+		ID:          wikiDocID,
+		Name:        rev.Name,
+		Content:     rev.Content,
+		Description: rev.Description,
+		Status:      rev.Status,
+	}
+
+	return wikiDoc, WikiDocVersion{Revision: rev.Revision, UpdateAt: rev.CreateAt}, nil
+}
+
+// publishChange notifies the members of the wikiDoc's channel that it changed.
+func (s *wikiDocsService) publishChange(change WikiDocChange) {
+	s.api.Frontend.PublishWebSocketEvent(WikiDocChangedWSEvent, change.webSocketPayload(), &model.WebsocketBroadcast{
+		ChannelId: change.WikiDoc.ChannelID,
+	})
+}
+
+func (s *wikiDocsService) OpenCreateWikiDocDialog(triggerID string) error {
+	statusOptions := make([]*model.PostActionOptions, 0, len(creatableStatuses))
+	for _, status := range creatableStatuses {
+		statusOptions = append(statusOptions, &model.PostActionOptions{Text: status, Value: status})
+	}
+
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s/api/v0/wikiDocs/dialog", root.Manifest.Id),
+		Dialog: model.Dialog{
+			Title:       "Create wiki doc",
+			SubmitLabel: "Create",
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Name",
+					Name:        DialogFieldNameKey,
+					Type:        "text",
+					MaxLength:   1024,
+				},
+				{
+					DisplayName: "Description",
+					Name:        DialogFieldDescriptionKey,
+					Type:        "textarea",
+					Optional:    true,
+					MaxLength:   4096,
+				},
+				{
+					DisplayName: "Content",
+					Name:        DialogFieldContentKey,
+					Type:        "textarea",
+					Optional:    true,
+					MaxLength:   65535,
+					HelpText:    "Markdown is supported.",
+				},
+				{
+					DisplayName: "Status",
+					Name:        DialogFieldStatusKey,
+					Type:        "select",
+					Options:     statusOptions,
+					Default:     StatusDraft,
+				},
+			},
+		},
+	}
+
+	if err := s.api.Frontend.OpenInteractiveDialog(dialog); err != nil {
+		return errors.Wrap(err, "failed to open the create wikiDoc dialog")
+	}
+
+	return nil
 }
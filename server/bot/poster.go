@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// Poster interface - a small subset of the plugin posting API.
+type Poster interface {
+	// EphemeralPost sends an ephemeral message to a user, in the given channel.
+	EphemeralPost(userID, channelID string, post *model.Post)
+
+	// DM sends a direct message from the bot to a user.
+	DM(userID string, post *model.Post) error
+
+	// Post creates a post from the bot in a channel.
+	Post(channelID string, post *model.Post) error
+}
+
+// EphemeralPost sends an ephemeral message to a user, in the given channel.
+func (b *Bot) EphemeralPost(userID, channelID string, post *model.Post) {
+	post.UserId = b.botUserID
+	post.ChannelId = channelID
+
+	b.pluginAPI.Post.SendEphemeralPost(userID, post)
+}
+
+// DM sends a direct message from the bot to a user.
+func (b *Bot) DM(userID string, post *model.Post) error {
+	if err := b.pluginAPI.Post.DM(b.botUserID, userID, post); err != nil {
+		return errors.Wrapf(err, "failed to send a direct message to user %s", userID)
+	}
+
+	return nil
+}
+
+// Post creates a post from the bot in a channel.
+func (b *Bot) Post(channelID string, post *model.Post) error {
+	post.UserId = b.botUserID
+	post.ChannelId = channelID
+
+	if err := b.pluginAPI.Post.CreatePost(post); err != nil {
+		return errors.Wrapf(err, "failed to post in channel %s", channelID)
+	}
+
+	return nil
+}
@@ -0,0 +1,444 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const helpText = "###### Mattermost Wiki Plugin - Slash Command Help\n" +
+	"* `/wiki create` - Open a dialog to create a wiki doc in this channel. \n" +
+	"* `/wiki list` - List the wiki docs of this channel. \n" +
+	"* `/wiki show <name>` - Show a wiki doc of this channel. \n" +
+	"* `/wiki search <terms>` - Search the wiki docs of this channel. \n" +
+	"* `/wiki publish <name>` - Publish a wiki doc of this channel. \n" +
+	"* `/wiki unpublish <name>` - Move a wiki doc of this channel back to draft. \n" +
+	"* `/wiki delete [--cascade|--reparent] <name>` - Move a wiki doc of this channel to the trash, and its sub-pages with `--cascade`, or moving them up with `--reparent`. \n" +
+	"* `/wiki help` - Show this help text. \n"
+
+// searchResultsLimit is the number of wikiDocs listed by /wiki search.
+const searchResultsLimit = 10
+
+// GetCommand returns the slash command registered by the plugin.
+func GetCommand() *model.Command {
+	return &model.Command{
+		Trigger:          "wiki",
+		DisplayName:      "Wiki",
+		Description:      "Wiki",
+		AutoComplete:     true,
+		AutoCompleteDesc: "Available commands: create, list, show, search, publish, unpublish, delete, help",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getAutocompleteData(),
+	}
+}
+
+func getAutocompleteData() *model.AutocompleteData {
+	command := model.NewAutocompleteData("wiki", "[command]",
+		"Available commands: create, list, show, search, publish, unpublish, delete, help")
+
+	create := model.NewAutocompleteData("create", "", "Create a wiki doc in this channel")
+	command.AddCommand(create)
+
+	list := model.NewAutocompleteData("list", "", "List the wiki docs of this channel")
+	command.AddCommand(list)
+
+	show := model.NewAutocompleteData("show", "<name>", "Show a wiki doc of this channel")
+	show.AddTextArgument("Name of the wiki doc", "<name>", "")
+	command.AddCommand(show)
+
+	search := model.NewAutocompleteData("search", "<terms>", "Search the wiki docs of this channel")
+	search.AddTextArgument("Words to search for", "<terms>", "")
+	command.AddCommand(search)
+
+	publish := model.NewAutocompleteData("publish", "<name>", "Publish a wiki doc of this channel")
+	publish.AddTextArgument("Name of the wiki doc", "<name>", "")
+	command.AddCommand(publish)
+
+	unpublish := model.NewAutocompleteData("unpublish", "<name>", "Move a wiki doc of this channel back to draft")
+	unpublish.AddTextArgument("Name of the wiki doc", "<name>", "")
+	command.AddCommand(unpublish)
+
+	deleteCommand := model.NewAutocompleteData("delete", "[--cascade|--reparent] <name>", "Move a wiki doc of this channel to the trash")
+	deleteCommand.AddTextArgument("Name of the wiki doc, optionally preceded by --cascade or --reparent", "[--cascade|--reparent] <name>", "")
+	command.AddCommand(deleteCommand)
+
+	help := model.NewAutocompleteData("help", "", "Show help")
+	command.AddCommand(help)
+
+	return command
+}
+
+// Runner handles commands.
+type Runner struct {
+	context        *plugin.Context
+	args           *model.CommandArgs
+	pluginAPI      *pluginapi.Client
+	logger         bot.Logger
+	poster         bot.Poster
+	wikiDocService app.WikiDocService
+	permissions    *app.PermissionsService
+}
+
+// NewCommandRunner creates a command runner.
+func NewCommandRunner(
+	ctx *plugin.Context,
+	args *model.CommandArgs,
+	api *pluginapi.Client,
+	logger bot.Logger,
+	poster bot.Poster,
+	wikiDocService app.WikiDocService,
+	permissions *app.PermissionsService,
+) *Runner {
+	return &Runner{
+		context:        ctx,
+		args:           args,
+		pluginAPI:      api,
+		logger:         logger,
+		poster:         poster,
+		wikiDocService: wikiDocService,
+		permissions:    permissions,
+	}
+}
+
+func (r *Runner) postCommandResponse(text string) {
+	post := &model.Post{
+		Message: text,
+	}
+	r.poster.EphemeralPost(r.args.UserId, r.args.ChannelId, post)
+}
+
+func (r *Runner) warnUserAndLogErrorf(format string, args ...interface{}) {
+	r.logger.Errorf(format, args...)
+	r.poster.EphemeralPost(r.args.UserId, r.args.ChannelId, &model.Post{
+		Message: "Your request could not be completed. Check the system logs for more information.",
+	})
+}
+
+// Execute should be called by the plugin when a command invocation is received from the Mattermost server.
+func (r *Runner) Execute() error {
+	split := strings.Fields(r.args.Command)
+	command := split[0]
+	if command != "/wiki" {
+		return nil
+	}
+
+	cmd := ""
+	parameters := []string{}
+	if len(split) > 1 {
+		cmd = split[1]
+		parameters = split[2:]
+	}
+
+	switch cmd {
+	case "create":
+		r.actionCreate()
+	case "list":
+		r.actionList()
+	case "show":
+		r.actionShow(parameters)
+	case "search":
+		r.actionSearch(parameters)
+	case "publish":
+		r.actionSetStatus(parameters, app.StatusPublished)
+	case "unpublish":
+		r.actionSetStatus(parameters, app.StatusDraft)
+	case "delete":
+		r.actionDelete(parameters)
+	default:
+		r.postCommandResponse(helpText)
+	}
+
+	return nil
+}
+
+func (r *Runner) actionCreate() {
+	wikiDoc := app.WikiDoc{
+		OwnerUserID: r.args.UserId,
+		TeamID:      r.args.TeamId,
+		ChannelID:   r.args.ChannelId,
+	}
+	if err := r.permissions.WikiDocCreate(wikiDoc); err != nil {
+		r.postCommandResponse("You do not have permission to create wiki docs in this channel.")
+		return
+	}
+
+	if err := r.wikiDocService.OpenCreateWikiDocDialog(r.args.TriggerId); err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+}
+
+func (r *Runner) actionList() {
+	if err := r.permissions.WikiDocList(r.args.UserId, r.args.ChannelId); err != nil {
+		r.postCommandResponse("You do not have permission to list the wiki docs of this channel.")
+		return
+	}
+
+	tree, err := r.wikiDocService.GetTree(r.args.ChannelId)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	tree, err = r.permissions.FilterWikiDocTree(r.args.UserId, r.args.ChannelId, tree)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	if len(tree) == 0 {
+		r.postCommandResponse("There are no wiki docs in this channel. Create one with `/wiki create`.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("###### Wiki docs of this channel\n")
+	writeWikiDocTree(&b, tree, 0)
+
+	r.postCommandResponse(b.String())
+}
+
+// writeWikiDocTree writes a markdown list of the nodes, with sub-pages indented under their parent.
+func writeWikiDocTree(b *strings.Builder, nodes []*app.WikiDocNode, depth int) {
+	for _, node := range nodes {
+		fmt.Fprintf(b, "%s* %s\n", strings.Repeat("  ", depth), formatWikiDoc(node.WikiDoc))
+		writeWikiDocTree(b, node.Children, depth+1)
+	}
+}
+
+// formatWikiDoc formats the name, status and description of a wikiDoc on a single line.
+func formatWikiDoc(wikiDoc app.WikiDoc) string {
+	line := fmt.Sprintf("**%s** (%s)", wikiDoc.Name, wikiDoc.Status)
+	if description := strings.Join(strings.Fields(wikiDoc.Description), " "); description != "" {
+		line += " - " + description
+	}
+	return line
+}
+
+func (r *Runner) actionShow(args []string) {
+	wikiDoc, ok := r.findWikiDoc(args, "show")
+	if !ok {
+		return
+	}
+
+	if err := r.permissions.WikiDocView(r.args.UserId, wikiDoc.ID); err != nil {
+		r.postCommandResponse("You do not have permission to view this wiki doc.")
+		return
+	}
+
+	wikiDoc, err := r.wikiDocService.Get(wikiDoc.ID)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### %s\n", wikiDoc.Name)
+	fmt.Fprintf(&b, "_Status: %s_\n", wikiDoc.Status)
+	if wikiDoc.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", wikiDoc.Description)
+	}
+	if wikiDoc.Content != "" {
+		fmt.Fprintf(&b, "\n---\n%s\n", wikiDoc.Content)
+	}
+
+	r.postCommandResponse(b.String())
+}
+
+func (r *Runner) actionSearch(args []string) {
+	if len(args) == 0 {
+		r.postCommandResponse("Please specify what to search for: `/wiki search <terms>`.")
+		return
+	}
+
+	if err := r.permissions.WikiDocList(r.args.UserId, r.args.ChannelId); err != nil {
+		r.postCommandResponse("You do not have permission to search the wiki docs of this channel.")
+		return
+	}
+
+	requesterInfo, err := r.permissions.GetRequesterInfo(r.args.UserId)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	options, err := app.WikiDocFilterOptions{
+		ChannelId:  r.args.ChannelId,
+		PerPage:    searchResultsLimit,
+		SearchTerm: strings.Join(args, " "),
+	}.Validate()
+	if err != nil {
+		r.postCommandResponse(fmt.Sprintf("Invalid search: %v", err))
+		return
+	}
+
+	results, err := r.wikiDocService.GetWikiDocs(requesterInfo, options)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	if len(results.Items) == 0 {
+		r.postCommandResponse(fmt.Sprintf("No wiki docs of this channel match `%s`.", options.SearchTerm))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "###### Wiki docs matching `%s`\n", options.SearchTerm)
+	for _, wikiDoc := range results.Items {
+		fmt.Fprintf(&b, "* %s\n", formatWikiDoc(wikiDoc))
+		if highlight := results.Highlights[wikiDoc.ID]; highlight.Content != "" {
+			fmt.Fprintf(&b, "  > %s\n", highlight.Content)
+		}
+	}
+	if results.HasMore {
+		fmt.Fprintf(&b, "\nShowing the first %d of %d results.\n", len(results.Items), results.TotalCount)
+	}
+
+	r.postCommandResponse(b.String())
+}
+
+func (r *Runner) actionSetStatus(args []string, status string) {
+	command := "publish"
+	if status == app.StatusDraft {
+		command = "unpublish"
+	}
+
+	wikiDoc, ok := r.findWikiDoc(args, command)
+	if !ok {
+		return
+	}
+
+	var err error
+	if status == app.StatusPublished {
+		err = r.permissions.WikiDocMakePublic(r.args.UserId, wikiDoc)
+	} else {
+		err = r.permissions.WikiDocMakePrivate(r.args.UserId, wikiDoc)
+	}
+	if err != nil {
+		r.postCommandResponse(fmt.Sprintf("You do not have permission to %s this wiki doc.", command))
+		return
+	}
+
+	if wikiDoc.Status == status {
+		r.postCommandResponse(fmt.Sprintf("**%s** is already %s.", wikiDoc.Name, status))
+		return
+	}
+
+	wikiDoc, err = r.wikiDocService.Get(wikiDoc.ID)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	wikiDoc.Status = status
+	if _, err = r.wikiDocService.Update(wikiDoc, r.args.UserId); err != nil {
+		if errors.Is(err, app.ErrConflict) {
+			r.postCommandResponse("The wiki doc was modified by someone else in the meantime. Please try again.")
+			return
+		}
+		if errors.Is(err, app.ErrInvalidTransition) {
+			r.postCommandResponse(fmt.Sprintf("**%s** is %s, it can only move to: %s.",
+				wikiDoc.Name, app.NormalizeStatus(wikiDoc.Status), strings.Join(app.AllowedTransitions(wikiDoc.Status), ", ")))
+			return
+		}
+		if errors.Is(err, app.ErrApprovalsRequired) {
+			r.postCommandResponse(fmt.Sprintf("**%s** must be approved by more reviewers before it can be published.", wikiDoc.Name))
+			return
+		}
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	r.postCommandResponse(fmt.Sprintf("**%s** is now %s.", wikiDoc.Name, status))
+}
+
+func (r *Runner) actionDelete(args []string) {
+	options := app.DeleteOptions{}
+	if len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		options.Children = app.ChildrenPolicy(strings.TrimPrefix(args[0], "--"))
+		if options.Children == "" || !app.ValidChildrenPolicy(options.Children) {
+			r.postCommandResponse(fmt.Sprintf("Unknown option `%s`. Use `--cascade` or `--reparent`.", args[0]))
+			return
+		}
+		args = args[1:]
+	}
+
+	wikiDoc, ok := r.findWikiDoc(args, "delete")
+	if !ok {
+		return
+	}
+
+	if err := r.permissions.DeleteWikiDocWithOptions(r.args.UserId, wikiDoc, options); err != nil {
+		r.postCommandResponse("You do not have permission to delete this wiki doc, or one of its sub-pages.")
+		return
+	}
+
+	if err := r.wikiDocService.Delete(wikiDoc.ID, r.args.UserId, options); err != nil {
+		if errors.Is(err, app.ErrWikiDocHasChildren) {
+			r.postCommandResponse(fmt.Sprintf("**%s** has sub-pages. Use `/wiki delete --cascade %s` to delete them too, "+
+				"or `/wiki delete --reparent %s` to move them up.", wikiDoc.Name, wikiDoc.Name, wikiDoc.Name))
+			return
+		}
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return
+	}
+
+	r.postCommandResponse(fmt.Sprintf("**%s** has been moved to the trash.", wikiDoc.Name))
+}
+
+// findWikiDoc returns the wikiDoc of the current channel named by args, matched case-insensitively
+// among the ones the user can read. It replies to the user and returns false if there is no such wikiDoc, or more than one.
+func (r *Runner) findWikiDoc(args []string, command string) (app.WikiDoc, bool) {
+	name := strings.Join(args, " ")
+	if name == "" {
+		r.postCommandResponse(fmt.Sprintf("Please specify the name of the wiki doc: `/wiki %s <name>`.", command))
+		return app.WikiDoc{}, false
+	}
+
+	tree, err := r.wikiDocService.GetTree(r.args.ChannelId)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return app.WikiDoc{}, false
+	}
+
+	// Only the wikiDocs the user can read are matched, not to reveal the names of the others.
+	tree, err = r.permissions.FilterWikiDocTree(r.args.UserId, r.args.ChannelId, tree)
+	if err != nil {
+		r.warnUserAndLogErrorf("Error: %v", err)
+		return app.WikiDoc{}, false
+	}
+
+	matches := findWikiDocsNamed(tree, name)
+
+	switch len(matches) {
+	case 0:
+		r.postCommandResponse(fmt.Sprintf("There is no wiki doc named **%s** in this channel. See `/wiki list`.", name))
+		return app.WikiDoc{}, false
+	case 1:
+		return matches[0], true
+	default:
+		r.postCommandResponse(fmt.Sprintf("There are %d wiki docs named **%s** in this channel. Rename them to use this command.", len(matches), name))
+		return app.WikiDoc{}, false
+	}
+}
+
+// findWikiDocsNamed returns the wikiDocs of the tree named name, ignoring case and extra whitespace.
+func findWikiDocsNamed(nodes []*app.WikiDocNode, name string) []app.WikiDoc {
+	var matches []app.WikiDoc
+	for _, node := range nodes {
+		if strings.EqualFold(strings.Join(strings.Fields(node.Name), " "), name) {
+			matches = append(matches, node.WikiDoc)
+		}
+		matches = append(matches, findWikiDocsNamed(node.Children, name)...)
+	}
+	return matches
+}
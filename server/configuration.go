@@ -2,6 +2,7 @@ package main
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -18,6 +19,39 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// TrashRetentionDays is the number of days deleted wikiDocs stay in the trash before being
+	// permanently purged. Defaults to defaultTrashRetentionDays when not set.
+	TrashRetentionDays int
+
+	// NotificationWindowMinutes is how long the changes of the wikiDocs a user watches are batched
+	// before being sent to them. Defaults to defaultNotificationWindowMinutes when not set.
+	NotificationWindowMinutes int
+}
+
+// defaultTrashRetentionDays is the trash retention used when none is configured.
+const defaultTrashRetentionDays = 30
+
+// defaultNotificationWindowMinutes is the notification window used when none is configured.
+const defaultNotificationWindowMinutes = 5
+
+// trashRetention returns how long deleted wikiDocs stay in the trash.
+func (c *configuration) trashRetention() time.Duration {
+	days := c.TrashRetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// notificationWindow returns how long the changes of watched wikiDocs are batched.
+func (c *configuration) notificationWindow() time.Duration {
+	minutes := c.NotificationWindowMinutes
+	if minutes <= 0 {
+		minutes = defaultNotificationWindowMinutes
+	}
+
+	return time.Duration(minutes) * time.Minute
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
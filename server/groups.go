@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+// groupsChangedEventID identifies the cluster event telling the other nodes that the groups of a user
+// may have changed.
+const groupsChangedEventID = "groups_changed"
+
+// Group sync adds and removes users from the teams and channels of their groups, so these hooks are
+// the sign that the groups of a user changed. The cached groups of the user are dropped on every
+// node, see app.GroupCacheTTL.
+
+// UserHasJoinedChannel is invoked after the membership has been committed to the database.
+func (p *Plugin) UserHasJoinedChannel(c *plugin.Context, channelMember *model.ChannelMember, actor *model.User) {
+	p.invalidateUserGroups(channelMember.UserId)
+}
+
+// UserHasLeftChannel is invoked after the membership has been removed from the database.
+func (p *Plugin) UserHasLeftChannel(c *plugin.Context, channelMember *model.ChannelMember, actor *model.User) {
+	p.invalidateUserGroups(channelMember.UserId)
+}
+
+// UserHasJoinedTeam is invoked after the membership has been committed to the database.
+func (p *Plugin) UserHasJoinedTeam(c *plugin.Context, teamMember *model.TeamMember, actor *model.User) {
+	p.invalidateUserGroups(teamMember.UserId)
+}
+
+// UserHasLeftTeam is invoked after the membership has been removed from the database.
+func (p *Plugin) UserHasLeftTeam(c *plugin.Context, teamMember *model.TeamMember, actor *model.User) {
+	p.invalidateUserGroups(teamMember.UserId)
+}
+
+// UserHasLoggedIn is invoked after a user has logged in, when an LDAP login may have synced their
+// groups.
+func (p *Plugin) UserHasLoggedIn(c *plugin.Context, user *model.User) {
+	p.invalidateUserGroups(user.Id)
+}
+
+// OnPluginClusterEvent is invoked when another node of the cluster publishes an event of the plugin.
+func (p *Plugin) OnPluginClusterEvent(c *plugin.Context, ev model.PluginClusterEvent) {
+	if ev.Id == groupsChangedEventID && p.memberService != nil {
+		p.memberService.InvalidateUserGroups(string(ev.Data))
+	}
+}
+
+// invalidateUserGroups drops the cached groups of a user on this node and tells the other nodes to
+// do the same.
+func (p *Plugin) invalidateUserGroups(userID string) {
+	if p.memberService == nil {
+		return
+	}
+
+	p.memberService.InvalidateUserGroups(userID)
+
+	err := p.API.PublishPluginClusterEvent(
+		model.PluginClusterEvent{Id: groupsChangedEventID, Data: []byte(userID)},
+		model.PluginClusterEventSendOptions{SendType: model.PluginClusterEventSendTypeReliable},
+	)
+	if err != nil {
+		p.bot.Errorf("failed to publish the groups change of user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	// trashPurgeJobKey identifies the trash purge job, which the cluster runs on one node at a time.
+	trashPurgeJobKey = "CPI_TrashPurgeJob"
+
+	// trashPurgeInterval is how often deleted wikiDocs past their retention are purged.
+	trashPurgeInterval = time.Hour
+
+	// notificationsJobKey identifies the job sending the notifications of watched wikiDocs.
+	notificationsJobKey = "CPI_NotificationsJob"
+
+	// notificationsInterval is how often the notifications past their window are sent.
+	notificationsInterval = time.Minute
+
+	// reportsJobKey identifies the job finding the broken links and orphaned wikiDocs of channel wikis.
+	reportsJobKey = "CPI_ReportsJob"
+
+	// reportsInterval is how often the reports of channel wikis are generated.
+	reportsInterval = 24 * time.Hour
+)
+
+// purgeTrash permanently deletes the wikiDocs that have been in the trash for longer than the
+// configured retention.
+func (p *Plugin) purgeTrash() {
+	before := time.Now().Add(-p.getConfiguration().trashRetention())
+
+	purged, err := p.wikiDocsService.PurgeTrash(before.UnixMilli())
+	if err != nil {
+		p.bot.Errorf("failed to purge the trash after %d wikiDocs: %v", purged, err)
+		return
+	}
+
+	if purged > 0 {
+		p.bot.Infof("purged %d wikiDocs from the trash", purged)
+	}
+}
+
+// sendNotifications sends the changes of watched wikiDocs to the watchers whose oldest change is
+// older than the configured window.
+func (p *Plugin) sendNotifications() {
+	before := time.Now().Add(-p.getConfiguration().notificationWindow())
+
+	sent, err := p.watchService.SendNotifications(before.UnixMilli())
+	if err != nil {
+		p.bot.Errorf("failed to send notifications after %d messages: %v", sent, err)
+		return
+	}
+
+	if sent > 0 {
+		p.bot.Debugf("sent %d notifications of watched wikiDocs", sent)
+	}
+}
+
+// generateReports scans the wikiDocs of every team and stores the reports of their channels.
+func (p *Plugin) generateReports() {
+	count, err := p.reportService.GenerateAll()
+	if err != nil {
+		p.bot.Errorf("failed to generate the wiki reports after %d channels: %v", count, err)
+		return
+	}
+
+	p.bot.Debugf("generated the wiki reports of %d channels", count)
+}
@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
 
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/api"
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/command"
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/sqlstore"
 
 	"github.com/sirupsen/logrus"
@@ -29,12 +31,23 @@ type Plugin struct {
 	// setConfiguration for usage.
 	configuration *configuration
 
-	handler         *api.Handler
-	wikiDocsService app.WikiDocService
-	permissions     *app.PermissionsService
+	handler                *api.Handler
+	wikiDocsService        app.WikiDocService
+	reviewService          app.ReviewService
+	memberService          app.WikiDocMemberService
+	commentService         app.CommentService
+	watchService           app.WatchService
+	reportService          app.ReportService
+	attachmentService      app.AttachmentService
+	channelSettingsService app.ChannelSettingsService
+	permissions            *app.PermissionsService
 
 	bot       *bot.Bot
 	pluginAPI *pluginapi.Client
+
+	trashPurgeJob    *cluster.Job
+	notificationsJob *cluster.Job
+	reportsJob       *cluster.Job
 }
 
 // ServeHTTP routes incoming HTTP requests to the plugin's REST API.
@@ -50,8 +63,17 @@ func (p *Plugin) OnActivate() error {
 	logger := logrus.StandardLogger()
 	pluginapi.ConfigureLogrus(logger, pluginAPIClient)
 
+	botID, err := pluginAPIClient.Bot.EnsureBot(&model.Bot{
+		Username:    "wiki",
+		DisplayName: "Wiki",
+		Description: "Sends notifications about wiki docs.",
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to ensure the wiki bot")
+	}
+
 	apiClient := sqlstore.NewClient(pluginAPIClient)
-	p.bot = bot.New(pluginAPIClient, "no id")
+	p.bot = bot.New(pluginAPIClient, botID)
 
 	sqlStore, err := sqlstore.New(apiClient, p.bot)
 	if err != nil {
@@ -59,10 +81,25 @@ func (p *Plugin) OnActivate() error {
 	}
 
 	wikiDocStore := sqlstore.NewWikiDocStore(apiClient, p.bot, sqlStore)
+	reviewStore := sqlstore.NewReviewStore(apiClient, p.bot, sqlStore)
+	channelSettingsStore := sqlstore.NewChannelSettingsStore(apiClient, p.bot, sqlStore)
+	memberStore := sqlstore.NewWikiDocMemberStore(apiClient, p.bot, sqlStore)
+	commentStore := sqlstore.NewCommentStore(apiClient, p.bot, sqlStore)
+	watchStore := sqlstore.NewWatchStore(apiClient, p.bot, sqlStore)
+	linkStore := sqlstore.NewLinkStore(apiClient, p.bot, sqlStore)
+	reportStore := sqlstore.NewReportStore(apiClient, p.bot, sqlStore)
+	attachmentStore := sqlstore.NewAttachmentStore(apiClient, p.bot, sqlStore)
 
-	p.wikiDocsService = app.NewWikiDocService(wikiDocStore, p.bot, pluginAPIClient)
+	p.wikiDocsService = app.NewWikiDocService(wikiDocStore, reviewStore, channelSettingsStore, commentStore, watchStore, linkStore, attachmentStore, p.bot, p.bot, pluginAPIClient)
+	p.reviewService = app.NewReviewService(reviewStore, p.wikiDocsService, p.bot, p.bot, pluginAPIClient)
+	p.channelSettingsService = app.NewChannelSettingsService(channelSettingsStore)
+	p.memberService = app.NewWikiDocMemberService(memberStore, pluginAPIClient)
+	p.commentService = app.NewCommentService(commentStore, p.wikiDocsService)
+	p.reportService = app.NewReportService(reportStore, p.wikiDocsService, p.bot, p.bot, pluginAPIClient)
+	p.attachmentService = app.NewAttachmentService(attachmentStore, pluginAPIClient)
 
-	p.permissions = app.NewPermissionsService(p.wikiDocsService, pluginAPIClient)
+	p.permissions = app.NewPermissionsService(p.wikiDocsService, p.memberService, pluginAPIClient)
+	p.watchService = app.NewWatchService(watchStore, p.wikiDocsService, p.permissions, p.bot, p.bot, pluginAPIClient)
 
 	mutex, err := cluster.NewMutex(p.API, "CPI_dbMutex")
 	if err != nil {
@@ -84,7 +121,139 @@ func (p *Plugin) OnActivate() error {
 		pluginAPIClient,
 		p.bot,
 	)
+
+	api.NewReviewHandler(
+		p.handler.APIRouter,
+		p.reviewService,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		botID,
+		p.bot,
+	)
+
+	api.NewWikiDocMemberHandler(
+		p.handler.APIRouter,
+		p.memberService,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewCommentHandler(
+		p.handler.APIRouter,
+		p.commentService,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewWatchHandler(
+		p.handler.APIRouter,
+		p.watchService,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewAttachmentHandler(
+		p.handler.APIRouter,
+		p.attachmentService,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewExportHandler(
+		p.handler.APIRouter,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewImportHandler(
+		p.handler.APIRouter,
+		p.wikiDocsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewReportHandler(
+		p.handler.APIRouter,
+		p.reportService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	api.NewChannelSettingsHandler(
+		p.handler.APIRouter,
+		p.channelSettingsService,
+		p.permissions,
+		pluginAPIClient,
+		p.bot,
+	)
+
+	if err := p.pluginAPI.SlashCommand.Register(command.GetCommand()); err != nil {
+		return errors.Wrapf(err, "failed to register command")
+	}
+
+	p.trashPurgeJob, err = cluster.Schedule(p.API, trashPurgeJobKey, cluster.MakeWaitForInterval(trashPurgeInterval), p.purgeTrash)
+	if err != nil {
+		return errors.Wrapf(err, "failed to schedule the trash purge job")
+	}
+
+	p.notificationsJob, err = cluster.Schedule(p.API, notificationsJobKey, cluster.MakeWaitForInterval(notificationsInterval), p.sendNotifications)
+	if err != nil {
+		return errors.Wrapf(err, "failed to schedule the notifications job")
+	}
+
+	p.reportsJob, err = cluster.Schedule(p.API, reportsJobKey, cluster.MakeWaitForInterval(reportsInterval), p.generateReports)
+	if err != nil {
+		return errors.Wrapf(err, "failed to schedule the reports job")
+	}
+
+	return nil
+}
+
+// OnDeactivate Called when this plugin is deactivated.
+func (p *Plugin) OnDeactivate() error {
+	if p.trashPurgeJob != nil {
+		if err := p.trashPurgeJob.Close(); err != nil {
+			return errors.Wrapf(err, "failed to close the trash purge job")
+		}
+	}
+
+	if p.notificationsJob != nil {
+		if err := p.notificationsJob.Close(); err != nil {
+			return errors.Wrapf(err, "failed to close the notifications job")
+		}
+	}
+
+	if p.reportsJob != nil {
+		if err := p.reportsJob.Close(); err != nil {
+			return errors.Wrapf(err, "failed to close the reports job")
+		}
+	}
+
 	return nil
 }
 
+// ExecuteCommand executes a command that has been previously registered via the RegisterCommand.
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	runner := command.NewCommandRunner(c, args, p.pluginAPI, p.bot, p.bot, p.wikiDocsService, p.permissions)
+
+	if err := runner.Execute(); err != nil {
+		return nil, model.NewAppError("CPI Wiki.ExecuteCommand", "Unable to execute command.", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return &model.CommandResponse{}, nil
+}
+
 // See https://developers.mattermost.com/extend/plugins/server/reference/
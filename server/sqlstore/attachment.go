@@ -0,0 +1,127 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// attachmentStore is a sql store for the attachments of wikiDocs. Use NewAttachmentStore to create
+// it.
+type attachmentStore struct {
+	pluginAPI        PluginAPIClient
+	log              bot.Logger
+	store            *SQLStore
+	queryBuilder     sq.StatementBuilderType
+	attachmentSelect sq.SelectBuilder
+}
+
+// Ensure attachmentStore implements the app.AttachmentStore interface.
+var _ app.AttachmentStore = (*attachmentStore)(nil)
+
+// NewAttachmentStore creates a new store for the attachments of wikiDocs.
+func NewAttachmentStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.AttachmentStore {
+	attachmentSelect := sqlStore.builder.
+		Select(
+			"a.ID",
+			"a.WikiDocID",
+			"a.FileID",
+			"a.Name",
+			"a.MimeType",
+			"a.Size",
+			"a.UserID",
+			"a.CreateAt",
+		).
+		From("CPI_WikiDocAttachments a")
+
+	return &attachmentStore{
+		pluginAPI:        pluginAPI,
+		log:              log,
+		store:            sqlStore,
+		queryBuilder:     sqlStore.builder,
+		attachmentSelect: attachmentSelect,
+	}
+}
+
+// GetAttachments retrieves the attachments of a wikiDoc, oldest first.
+func (s *attachmentStore) GetAttachments(wikiDocID string) ([]app.Attachment, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	attachments := []app.Attachment{}
+	err := s.store.selectBuilder(s.store.db, &attachments, s.attachmentSelect.
+		Where(sq.Eq{"a.WikiDocID": wikiDocID}).
+		OrderBy("a.CreateAt ASC", "a.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get attachments of wikiDoc '%s'", wikiDocID)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment retrieves an attachment.
+func (s *attachmentStore) GetAttachment(id string) (app.Attachment, error) {
+	if id == "" {
+		return app.Attachment{}, errors.New("ID cannot be empty")
+	}
+
+	var attachment app.Attachment
+	err := s.store.getBuilder(s.store.db, &attachment, s.attachmentSelect.Where(sq.Eq{"a.ID": id}))
+	if err == sql.ErrNoRows {
+		return app.Attachment{}, errors.Wrapf(app.ErrNotFound, "attachment '%s' does not exist", id)
+	} else if err != nil {
+		return app.Attachment{}, errors.Wrapf(err, "failed to get attachment '%s'", id)
+	}
+
+	return attachment, nil
+}
+
+// CreateAttachment stores a new attachment.
+func (s *attachmentStore) CreateAttachment(attachment app.Attachment) error {
+	return s.store.insertAttachment(s.store.db, attachment)
+}
+
+// insertAttachment stores a new attachment, within a transaction or not.
+func (sqlStore *SQLStore) insertAttachment(e execer, attachment app.Attachment) error {
+	if attachment.ID == "" || attachment.WikiDocID == "" || attachment.FileID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	_, err := sqlStore.execBuilder(e, sq.
+		Insert("CPI_WikiDocAttachments").
+		SetMap(map[string]interface{}{
+			"ID":        attachment.ID,
+			"WikiDocID": attachment.WikiDocID,
+			"FileID":    attachment.FileID,
+			"Name":      attachment.Name,
+			"MimeType":  attachment.MimeType,
+			"Size":      attachment.Size,
+			"UserID":    attachment.UserID,
+			"CreateAt":  attachment.CreateAt,
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to store attachment '%s'", attachment.ID)
+	}
+
+	return nil
+}
+
+// DeleteAttachment deletes an attachment.
+func (s *attachmentStore) DeleteAttachment(id string) error {
+	if id == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiDocAttachments").
+		Where(sq.Eq{"ID": id}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete attachment '%s'", id)
+	}
+
+	return nil
+}
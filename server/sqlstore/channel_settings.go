@@ -0,0 +1,104 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// channelSettingsStore is a sql store for the wiki settings of channels. Use NewChannelSettingsStore
+// to create it.
+type channelSettingsStore struct {
+	pluginAPI    PluginAPIClient
+	log          bot.Logger
+	store        *SQLStore
+	queryBuilder sq.StatementBuilderType
+}
+
+// Ensure channelSettingsStore implements the app.ChannelSettingsStore interface.
+var _ app.ChannelSettingsStore = (*channelSettingsStore)(nil)
+
+// NewChannelSettingsStore creates a new store for the wiki settings of channels.
+func NewChannelSettingsStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.ChannelSettingsStore {
+	return &channelSettingsStore{
+		pluginAPI:    pluginAPI,
+		log:          log,
+		store:        sqlStore,
+		queryBuilder: sqlStore.builder,
+	}
+}
+
+// GetChannelSettings retrieves the settings of a channel, the defaults if none were saved.
+func (s *channelSettingsStore) GetChannelSettings(channelID string) (app.ChannelSettings, error) {
+	if channelID == "" {
+		return app.ChannelSettings{}, errors.New("channel ID cannot be empty")
+	}
+
+	settings := app.ChannelSettings{ChannelID: channelID}
+	err := s.store.getBuilder(s.store.db, &settings, s.queryBuilder.
+		Select(
+			"c.ChannelID",
+			"c.RequiredApprovals",
+			"c.DisableAnnouncements",
+			"c.UpdateAt",
+		).
+		From("CPI_WikiChannelSettings c").
+		Where(sq.Eq{"c.ChannelID": channelID}))
+	if err != nil && err != sql.ErrNoRows {
+		return app.ChannelSettings{}, errors.Wrapf(err, "failed to get settings of channel '%s'", channelID)
+	}
+
+	return settings, nil
+}
+
+// SaveChannelSettings creates or replaces the settings of a channel.
+func (s *channelSettingsStore) SaveChannelSettings(settings app.ChannelSettings) error {
+	if settings.ChannelID == "" {
+		return errors.New("channel ID cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	var count int
+	err = s.store.getBuilder(tx, &count, s.queryBuilder.
+		Select("COUNT(*)").
+		From("CPI_WikiChannelSettings").
+		Where(sq.Eq{"ChannelID": settings.ChannelID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to check settings of channel '%s'", settings.ChannelID)
+	}
+
+	values := map[string]interface{}{
+		"RequiredApprovals":    settings.RequiredApprovals,
+		"DisableAnnouncements": settings.DisableAnnouncements,
+		"UpdateAt":             settings.UpdateAt,
+	}
+
+	if count == 0 {
+		values["ChannelID"] = settings.ChannelID
+		_, err = s.store.execBuilder(tx, sq.
+			Insert("CPI_WikiChannelSettings").
+			SetMap(values))
+	} else {
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiChannelSettings").
+			SetMap(values).
+			Where(sq.Eq{"ChannelID": settings.ChannelID}))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to store settings of channel '%s'", settings.ChannelID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
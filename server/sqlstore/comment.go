@@ -0,0 +1,230 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// sqlComment is a comment as stored, with its anchor flattened into columns.
+type sqlComment struct {
+	app.Comment
+
+	AnchorRevision int64
+	AnchorHeading  string
+	AnchorQuote    string
+	AnchorOffset   int
+	AnchorDetached bool
+}
+
+// commentStore is a sql store for the comments of wikiDocs. Use NewCommentStore to create it.
+type commentStore struct {
+	pluginAPI     PluginAPIClient
+	log           bot.Logger
+	store         *SQLStore
+	queryBuilder  sq.StatementBuilderType
+	commentSelect sq.SelectBuilder
+}
+
+// Ensure commentStore implements the app.CommentStore interface.
+var _ app.CommentStore = (*commentStore)(nil)
+
+// NewCommentStore creates a new store for the comments of wikiDocs.
+func NewCommentStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.CommentStore {
+	commentSelect := sqlStore.builder.
+		Select(
+			"c.ID",
+			"c.WikiDocID",
+			"c.ParentID",
+			"c.UserID",
+			"c.Message",
+			"c.AnchorRevision",
+			"c.AnchorHeading",
+			"c.AnchorQuote",
+			"c.AnchorOffset",
+			"c.AnchorDetached",
+			"c.ResolvedAt",
+			"c.ResolvedBy",
+			"c.CreateAt",
+			"c.UpdateAt",
+		).
+		From("CPI_WikiDocComments c")
+
+	return &commentStore{
+		pluginAPI:     pluginAPI,
+		log:           log,
+		store:         sqlStore,
+		queryBuilder:  sqlStore.builder,
+		commentSelect: commentSelect,
+	}
+}
+
+// GetComments retrieves the comments of a wikiDoc, oldest first.
+func (s *commentStore) GetComments(wikiDocID string) ([]app.Comment, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	var rawComments []sqlComment
+	err := s.store.selectBuilder(s.store.db, &rawComments, s.commentSelect.
+		Where(sq.Eq{"c.WikiDocID": wikiDocID}).
+		OrderBy("c.CreateAt ASC", "c.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get comments of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	comments := make([]app.Comment, 0, len(rawComments))
+	for _, rawComment := range rawComments {
+		comments = append(comments, toComment(rawComment))
+	}
+
+	return comments, nil
+}
+
+// GetComment retrieves a comment.
+func (s *commentStore) GetComment(id string) (app.Comment, error) {
+	if id == "" {
+		return app.Comment{}, errors.New("ID cannot be empty")
+	}
+
+	var rawComment sqlComment
+	err := s.store.getBuilder(s.store.db, &rawComment, s.commentSelect.Where(sq.Eq{"c.ID": id}))
+	if err == sql.ErrNoRows {
+		return app.Comment{}, errors.Wrapf(app.ErrNotFound, "comment does not exist for id '%s'", id)
+	} else if err != nil {
+		return app.Comment{}, errors.Wrapf(err, "failed to get comment by id '%s'", id)
+	}
+
+	return toComment(rawComment), nil
+}
+
+// CreateComment stores a new comment.
+func (s *commentStore) CreateComment(comment app.Comment) error {
+	if comment.ID == "" || comment.WikiDocID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	rawComment := toSQLComment(comment)
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Insert("CPI_WikiDocComments").
+		SetMap(map[string]interface{}{
+			"ID":             rawComment.ID,
+			"WikiDocID":      rawComment.WikiDocID,
+			"ParentID":       rawComment.ParentID,
+			"UserID":         rawComment.UserID,
+			"Message":        rawComment.Message,
+			"AnchorRevision": rawComment.AnchorRevision,
+			"AnchorHeading":  rawComment.AnchorHeading,
+			"AnchorQuote":    rawComment.AnchorQuote,
+			"AnchorOffset":   rawComment.AnchorOffset,
+			"AnchorDetached": rawComment.AnchorDetached,
+			"ResolvedAt":     rawComment.ResolvedAt,
+			"ResolvedBy":     rawComment.ResolvedBy,
+			"CreateAt":       rawComment.CreateAt,
+			"UpdateAt":       rawComment.UpdateAt,
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to store comment on wikiDoc '%s'", comment.WikiDocID)
+	}
+
+	return nil
+}
+
+// UpdateComment stores the message and the resolution of a comment.
+func (s *commentStore) UpdateComment(comment app.Comment) error {
+	if comment.ID == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Update("CPI_WikiDocComments").
+		SetMap(map[string]interface{}{
+			"Message":    comment.Message,
+			"ResolvedAt": comment.ResolvedAt,
+			"ResolvedBy": comment.ResolvedBy,
+			"UpdateAt":   comment.UpdateAt,
+		}).
+		Where(sq.Eq{"ID": comment.ID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to update comment with id '%s'", comment.ID)
+	}
+
+	return nil
+}
+
+// UpdateAnchors stores the anchors of the given threads.
+func (s *commentStore) UpdateAnchors(comments []app.Comment) error {
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	for _, comment := range comments {
+		rawComment := toSQLComment(comment)
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocComments").
+			SetMap(map[string]interface{}{
+				"AnchorHeading":  rawComment.AnchorHeading,
+				"AnchorOffset":   rawComment.AnchorOffset,
+				"AnchorDetached": rawComment.AnchorDetached,
+			}).
+			Where(sq.Eq{"ID": comment.ID}))
+		if err != nil {
+			return errors.Wrapf(err, "failed to update anchor of comment with id '%s'", comment.ID)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// DeleteComment deletes a comment along with its replies.
+func (s *commentStore) DeleteComment(id string) error {
+	if id == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiDocComments").
+		Where(sq.Or{sq.Eq{"ID": id}, sq.Eq{"ParentID": id}}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete comment with id '%s'", id)
+	}
+
+	return nil
+}
+
+func toSQLComment(comment app.Comment) sqlComment {
+	rawComment := sqlComment{Comment: comment}
+	if comment.Anchor != nil {
+		rawComment.AnchorRevision = comment.Anchor.Revision
+		rawComment.AnchorHeading = comment.Anchor.Heading
+		rawComment.AnchorQuote = comment.Anchor.Quote
+		rawComment.AnchorOffset = comment.Anchor.Offset
+		rawComment.AnchorDetached = comment.Anchor.Detached
+	}
+
+	return rawComment
+}
+
+func toComment(rawComment sqlComment) app.Comment {
+	comment := rawComment.Comment
+	if comment.IsThread() {
+		comment.Anchor = &app.CommentAnchor{
+			Revision: rawComment.AnchorRevision,
+			Heading:  rawComment.AnchorHeading,
+			Quote:    rawComment.AnchorQuote,
+			Offset:   rawComment.AnchorOffset,
+			Detached: rawComment.AnchorDetached,
+		}
+	}
+
+	return comment
+}
@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// GetGroupGrants retrieves the roles granted to groups on a wikiDoc, or on the channel wiki when
+// wikiDocID is empty, oldest first.
+func (s *wikiDocMemberStore) GetGroupGrants(channelID, wikiDocID string) ([]app.GroupGrant, error) {
+	if channelID == "" {
+		return nil, errors.New("channel ID cannot be empty")
+	}
+
+	grants := []app.GroupGrant{}
+	err := s.store.selectBuilder(s.store.db, &grants, s.groupGrantSelect.
+		Where(sq.Eq{"g.ChannelID": channelID, "g.WikiDocID": wikiDocID}).
+		OrderBy("g.CreateAt ASC", "g.GroupID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get group grants of wikiDoc '%s' in channel '%s'", wikiDocID, channelID)
+	}
+
+	return grants, nil
+}
+
+// GetGroupGrantsForGroups retrieves the roles granted to some groups in a channel, on the channel
+// wiki and on its wikiDocs.
+func (s *wikiDocMemberStore) GetGroupGrantsForGroups(channelID string, groupIDs []string) ([]app.GroupGrant, error) {
+	if channelID == "" {
+		return nil, errors.New("channel ID cannot be empty")
+	}
+
+	grants := []app.GroupGrant{}
+	if len(groupIDs) == 0 {
+		return grants, nil
+	}
+
+	err := s.store.selectBuilder(s.store.db, &grants, s.groupGrantSelect.
+		Where(sq.Eq{"g.ChannelID": channelID, "g.GroupID": groupIDs}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get group grants in channel '%s'", channelID)
+	}
+
+	return grants, nil
+}
+
+// SaveGroupGrant grants a role to a group, replacing the role it had and keeping the time it was
+// first granted one.
+func (s *wikiDocMemberStore) SaveGroupGrant(grant app.GroupGrant) (app.GroupGrant, error) {
+	if grant.GroupID == "" || grant.ChannelID == "" {
+		return app.GroupGrant{}, errors.New("IDs cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return app.GroupGrant{}, errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	var existing app.GroupGrant
+	err = s.store.getBuilder(tx, &existing, s.groupGrantSelect.
+		Where(sq.Eq{"g.GroupID": grant.GroupID, "g.ChannelID": grant.ChannelID, "g.WikiDocID": grant.WikiDocID}))
+	if err != nil && err != sql.ErrNoRows {
+		return app.GroupGrant{}, errors.Wrapf(err, "failed to get role of group '%s' on wikiDoc '%s'", grant.GroupID, grant.WikiDocID)
+	}
+
+	if err == sql.ErrNoRows {
+		_, err = s.store.execBuilder(tx, sq.
+			Insert("CPI_WikiGroupGrants").
+			SetMap(map[string]interface{}{
+				"GroupID":   grant.GroupID,
+				"ChannelID": grant.ChannelID,
+				"WikiDocID": grant.WikiDocID,
+				"Role":      grant.Role,
+				"CreateAt":  grant.CreateAt,
+				"UpdateAt":  grant.UpdateAt,
+			}))
+	} else {
+		grant.CreateAt = existing.CreateAt
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiGroupGrants").
+			SetMap(map[string]interface{}{
+				"Role":     grant.Role,
+				"UpdateAt": grant.UpdateAt,
+			}).
+			Where(sq.Eq{"GroupID": grant.GroupID, "ChannelID": grant.ChannelID, "WikiDocID": grant.WikiDocID}))
+	}
+	if err != nil {
+		return app.GroupGrant{}, errors.Wrapf(err, "failed to store role of group '%s' on wikiDoc '%s'", grant.GroupID, grant.WikiDocID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return app.GroupGrant{}, errors.Wrap(err, "could not commit transaction")
+	}
+
+	return grant, nil
+}
+
+// DeleteGroupGrant revokes the role of a group on a wikiDoc, or on the channel wiki when wikiDocID
+// is empty.
+func (s *wikiDocMemberStore) DeleteGroupGrant(groupID, channelID, wikiDocID string) error {
+	if groupID == "" || channelID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiGroupGrants").
+		Where(sq.Eq{"GroupID": groupID, "ChannelID": channelID, "WikiDocID": wikiDocID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete role of group '%s' on wikiDoc '%s'", groupID, wikiDocID)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package sqlstore
+
+import (
+	"strings"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// linkStore is a sql store for the [[links]] between wikiDocs. Use NewLinkStore to create it.
+type linkStore struct {
+	pluginAPI    PluginAPIClient
+	log          bot.Logger
+	store        *SQLStore
+	queryBuilder sq.StatementBuilderType
+	linkSelect   sq.SelectBuilder
+}
+
+// Ensure linkStore implements the app.LinkStore interface.
+var _ app.LinkStore = (*linkStore)(nil)
+
+// NewLinkStore creates a new store for the links between wikiDocs.
+func NewLinkStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.LinkStore {
+	linkSelect := sqlStore.builder.
+		Select(
+			"l.SourceID",
+			"l.Target",
+			"l.Label",
+			"l.TargetID",
+			"l.TeamID",
+			"l.CreateAt",
+		).
+		From("CPI_WikiDocLinks l")
+
+	return &linkStore{
+		pluginAPI:    pluginAPI,
+		log:          log,
+		store:        sqlStore,
+		queryBuilder: sqlStore.builder,
+		linkSelect:   linkSelect,
+	}
+}
+
+// GetLinks retrieves the links of a wikiDoc, in order of their targets.
+func (s *linkStore) GetLinks(sourceID string) ([]app.WikiDocLink, error) {
+	if sourceID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	links := []app.WikiDocLink{}
+	err := s.store.selectBuilder(s.store.db, &links, s.linkSelect.
+		Where(sq.Eq{"l.SourceID": sourceID}).
+		OrderBy("l.Target ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get links of wikiDoc '%s'", sourceID)
+	}
+
+	return links, nil
+}
+
+// GetBacklinks retrieves the links resolving to a wikiDoc.
+func (s *linkStore) GetBacklinks(targetID string) ([]app.WikiDocLink, error) {
+	if targetID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	links := []app.WikiDocLink{}
+	err := s.store.selectBuilder(s.store.db, &links, s.linkSelect.
+		Where(sq.Eq{"l.TargetID": targetID}).
+		OrderBy("l.SourceID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get links to wikiDoc '%s'", targetID)
+	}
+
+	return links, nil
+}
+
+// SaveLinks replaces the links of a wikiDoc.
+func (s *linkStore) SaveLinks(sourceID string, links []app.WikiDocLink) error {
+	if sourceID == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	_, err = s.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocLinks").
+		Where(sq.Eq{"SourceID": sourceID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete links of wikiDoc '%s'", sourceID)
+	}
+
+	if len(links) > 0 {
+		insert := sq.
+			Insert("CPI_WikiDocLinks").
+			Columns("SourceID", "Target", "Label", "TargetID", "TeamID", "CreateAt")
+		for _, link := range links {
+			insert = insert.Values(sourceID, link.Target, link.Label, link.TargetID, link.TeamID, link.CreateAt)
+		}
+
+		if _, err = s.store.execBuilder(tx, insert); err != nil {
+			return errors.Wrapf(err, "failed to store links of wikiDoc '%s'", sourceID)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// GetLinksByTarget retrieves the links of a team whose target is the given name, ignoring case, with
+// the channel of their source.
+func (s *linkStore) GetLinksByTarget(teamID, name string) ([]app.WikiDocLink, error) {
+	if teamID == "" {
+		return nil, errors.New("team ID cannot be empty")
+	}
+
+	links := []app.WikiDocLink{}
+	err := s.store.selectBuilder(s.store.db, &links, s.linkSelect.
+		Column("s.ChannelID AS SourceChannelID").
+		Join("CPI_WikiDocs s ON s.ID = l.SourceID").
+		Where(sq.Eq{"l.TeamID": teamID}).
+		Where(sq.Expr("LOWER(l.Target) = ?", strings.ToLower(strings.TrimSpace(name)))))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get links to '%s'", name)
+	}
+
+	return links, nil
+}
+
+// SetLinkTargets points the given links, found by their source and target, to their TargetID.
+func (s *linkStore) SetLinkTargets(links []app.WikiDocLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	for _, link := range links {
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocLinks").
+			Set("TargetID", link.TargetID).
+			Where(sq.Eq{"SourceID": link.SourceID, "Target": link.Target}))
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve link of wikiDoc '%s' to '%s'", link.SourceID, link.Target)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// GetWikiDocsByName retrieves the wikiDocs of a team that are not in the trash and have the given
+// name, ignoring case, oldest first. The content of the wikiDocs is not retrieved.
+func (s *linkStore) GetWikiDocsByName(teamID, name string) ([]app.WikiDoc, error) {
+	if teamID == "" {
+		return nil, errors.New("team ID cannot be empty")
+	}
+
+	wikiDocs := []app.WikiDoc{}
+	err := s.store.selectBuilder(s.store.db, &wikiDocs, s.queryBuilder.
+		Select("w.ID", "w.Name", "w.TeamID", "w.ChannelID", "w.CreateAt").
+		From("CPI_WikiDocs w").
+		Where(sq.Eq{"w.TeamID": teamID, "w.DeleteAt": 0}).
+		Where(sq.Expr("LOWER(w.Name) = ?", strings.ToLower(strings.TrimSpace(name)))).
+		OrderBy("w.CreateAt ASC", "w.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get wikiDocs named '%s'", name)
+	}
+
+	return wikiDocs, nil
+}
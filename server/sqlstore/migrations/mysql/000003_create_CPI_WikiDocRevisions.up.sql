@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocRevisions (
+    ID VARCHAR(26) PRIMARY KEY,
+    WikiDocID VARCHAR(26) NOT NULL,
+    Revision BIGINT NOT NULL,
+    Name VARCHAR(1024) NOT NULL,
+    Content TEXT NOT NULL,
+    Description VARCHAR(4096) NOT NULL,
+    Status VARCHAR(26) NOT NULL,
+    UserID VARCHAR(26) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UNIQUE INDEX CPI_WikiDocRevisions_WikiDocID_Revision (WikiDocID, Revision)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,4 @@
+ALTER TABLE CPI_WikiDocs
+    DROP INDEX CPI_WikiDocs_ParentID,
+    DROP COLUMN ParentID,
+    DROP COLUMN SortOrder;
@@ -0,0 +1,4 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN ParentID VARCHAR(26) NOT NULL DEFAULT '',
+    ADD COLUMN SortOrder INT NOT NULL DEFAULT 0,
+    ADD INDEX CPI_WikiDocs_ParentID (ParentID);
@@ -0,0 +1 @@
+ALTER TABLE CPI_WikiDocs DROP INDEX CPI_WikiDocs_FullText;
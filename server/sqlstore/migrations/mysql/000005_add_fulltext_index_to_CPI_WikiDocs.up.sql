@@ -0,0 +1 @@
+ALTER TABLE CPI_WikiDocs ADD FULLTEXT INDEX CPI_WikiDocs_FullText (Name, Description, Content);
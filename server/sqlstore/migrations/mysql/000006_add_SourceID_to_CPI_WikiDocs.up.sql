@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN SourceID VARCHAR(26) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocStatusChanges (
+    ID VARCHAR(26) PRIMARY KEY,
+    WikiDocID VARCHAR(26) NOT NULL,
+    FromStatus VARCHAR(26) NOT NULL,
+    ToStatus VARCHAR(26) NOT NULL,
+    UserID VARCHAR(26) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    INDEX CPI_WikiDocStatusChanges_WikiDocID (WikiDocID)
+) DEFAULT CHARACTER SET utf8mb4;
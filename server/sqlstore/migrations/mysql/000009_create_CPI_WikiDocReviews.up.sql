@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocReviews (
+    ID VARCHAR(26) PRIMARY KEY,
+    WikiDocID VARCHAR(26) NOT NULL,
+    ReviewerID VARCHAR(26) NOT NULL,
+    RequesterID VARCHAR(26) NOT NULL,
+    State VARCHAR(32) NOT NULL,
+    Comment TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    UNIQUE INDEX CPI_WikiDocReviews_WikiDocID_ReviewerID (WikiDocID, ReviewerID)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiChannelSettings (
+    ChannelID VARCHAR(26) PRIMARY KEY,
+    RequiredApprovals INT NOT NULL DEFAULT 0,
+    UpdateAt BIGINT NOT NULL
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocMembers (
+    WikiDocID VARCHAR(26) NOT NULL,
+    UserID VARCHAR(26) NOT NULL,
+    Role VARCHAR(32) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    PRIMARY KEY (WikiDocID, UserID),
+    INDEX CPI_WikiDocMembers_UserID (UserID)
+) DEFAULT CHARACTER SET utf8mb4;
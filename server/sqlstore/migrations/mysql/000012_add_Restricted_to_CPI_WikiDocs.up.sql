@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN Restricted BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiGroupGrants (
+    GroupID VARCHAR(26) NOT NULL,
+    ChannelID VARCHAR(26) NOT NULL,
+    WikiDocID VARCHAR(26) NOT NULL DEFAULT '',
+    Role VARCHAR(32) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    PRIMARY KEY (GroupID, ChannelID, WikiDocID),
+    INDEX CPI_WikiGroupGrants_ChannelID (ChannelID)
+) DEFAULT CHARACTER SET utf8mb4;
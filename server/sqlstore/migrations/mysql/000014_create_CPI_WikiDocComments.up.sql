@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocComments (
+    ID VARCHAR(26) PRIMARY KEY,
+    WikiDocID VARCHAR(26) NOT NULL,
+    ParentID VARCHAR(26) NOT NULL DEFAULT '',
+    UserID VARCHAR(26) NOT NULL,
+    Message TEXT NOT NULL,
+    AnchorRevision BIGINT NOT NULL DEFAULT 0,
+    AnchorHeading TEXT NOT NULL,
+    AnchorQuote TEXT NOT NULL,
+    AnchorOffset INT NOT NULL DEFAULT 0,
+    AnchorDetached BOOLEAN NOT NULL DEFAULT FALSE,
+    ResolvedAt BIGINT NOT NULL DEFAULT 0,
+    ResolvedBy VARCHAR(26) NOT NULL DEFAULT '',
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    INDEX CPI_WikiDocComments_WikiDocID (WikiDocID)
+) DEFAULT CHARACTER SET utf8mb4;
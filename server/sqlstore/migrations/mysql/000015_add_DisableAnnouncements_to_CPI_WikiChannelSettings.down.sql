@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiChannelSettings
+    DROP COLUMN DisableAnnouncements;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiWatches (
+    UserID VARCHAR(26) NOT NULL,
+    ChannelID VARCHAR(26) NOT NULL,
+    WikiDocID VARCHAR(26) NOT NULL DEFAULT '',
+    Scope VARCHAR(32) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (UserID, ChannelID, WikiDocID),
+    INDEX CPI_WikiWatches_ChannelID (ChannelID)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiNotifications (
+    ID VARCHAR(26) PRIMARY KEY,
+    UserID VARCHAR(26) NOT NULL,
+    WikiDocID VARCHAR(26) NOT NULL,
+    ActorID VARCHAR(26) NOT NULL,
+    Fields VARCHAR(128) NOT NULL,
+    PreviousStatus VARCHAR(32) NOT NULL,
+    Status VARCHAR(32) NOT NULL,
+    Excerpt TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    INDEX CPI_WikiNotifications_UserID_CreateAt (UserID, CreateAt)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocLinks (
+    SourceID VARCHAR(26) NOT NULL,
+    Target VARCHAR(255) NOT NULL,
+    Label VARCHAR(255) NOT NULL DEFAULT '',
+    TargetID VARCHAR(26) NOT NULL DEFAULT '',
+    TeamID VARCHAR(26) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (SourceID, Target),
+    INDEX CPI_WikiDocLinks_TargetID (TargetID)
+) DEFAULT CHARACTER SET utf8mb4;
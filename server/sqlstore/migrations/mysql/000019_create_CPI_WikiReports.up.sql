@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiReports (
+    ChannelID VARCHAR(26) NOT NULL,
+    TeamID VARCHAR(26) NOT NULL,
+    WikiDocCount INT NOT NULL DEFAULT 0,
+    Issues MEDIUMTEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (ChannelID),
+    INDEX CPI_WikiReports_TeamID (TeamID)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocAttachments (
+    ID VARCHAR(26) NOT NULL,
+    WikiDocID VARCHAR(26) NOT NULL,
+    FileID VARCHAR(26) NOT NULL,
+    Name VARCHAR(256) NOT NULL,
+    MimeType VARCHAR(256) NOT NULL DEFAULT '',
+    Size BIGINT NOT NULL DEFAULT 0,
+    UserID VARCHAR(26) NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (ID),
+    INDEX CPI_WikiDocAttachments_WikiDocID (WikiDocID)
+) DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocReviews
+    DROP COLUMN WikiDocUpdateAt;
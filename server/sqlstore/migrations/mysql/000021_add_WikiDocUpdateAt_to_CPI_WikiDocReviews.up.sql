@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocReviews
+    ADD COLUMN WikiDocUpdateAt BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS CPI_WikiDocRevisions;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocRevisions (
+    ID TEXT PRIMARY KEY,
+    WikiDocID TEXT NOT NULL,
+    Revision BIGINT NOT NULL,
+    Name TEXT NOT NULL,
+    Content TEXT NOT NULL,
+    Description TEXT NOT NULL,
+    Status TEXT NOT NULL,
+    UserID TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS CPI_WikiDocRevisions_WikiDocID_Revision ON CPI_WikiDocRevisions (WikiDocID, Revision);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS CPI_WikiDocs_ParentID;
+
+ALTER TABLE CPI_WikiDocs
+    DROP COLUMN IF EXISTS ParentID,
+    DROP COLUMN IF EXISTS SortOrder;
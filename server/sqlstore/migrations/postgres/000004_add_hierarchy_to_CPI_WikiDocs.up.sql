@@ -0,0 +1,5 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN IF NOT EXISTS ParentID TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS SortOrder INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocs_ParentID ON CPI_WikiDocs (ParentID);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS CPI_WikiDocs_SearchVector;
+
+DROP TRIGGER IF EXISTS CPI_WikiDocs_SearchVector_Trigger ON CPI_WikiDocs;
+
+DROP FUNCTION IF EXISTS CPI_WikiDocs_SearchVector_Update();
+
+ALTER TABLE CPI_WikiDocs DROP COLUMN IF EXISTS SearchVector;
@@ -0,0 +1,24 @@
+ALTER TABLE CPI_WikiDocs ADD COLUMN IF NOT EXISTS SearchVector tsvector;
+
+CREATE OR REPLACE FUNCTION CPI_WikiDocs_SearchVector_Update() RETURNS trigger AS $$
+BEGIN
+    NEW.SearchVector :=
+        setweight(to_tsvector('english', COALESCE(NEW.Name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.Description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.Content, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS CPI_WikiDocs_SearchVector_Trigger ON CPI_WikiDocs;
+
+CREATE TRIGGER CPI_WikiDocs_SearchVector_Trigger
+    BEFORE INSERT OR UPDATE OF Name, Description, Content ON CPI_WikiDocs
+    FOR EACH ROW EXECUTE PROCEDURE CPI_WikiDocs_SearchVector_Update();
+
+UPDATE CPI_WikiDocs SET SearchVector =
+    setweight(to_tsvector('english', COALESCE(Name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(Description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(Content, '')), 'C');
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocs_SearchVector ON CPI_WikiDocs USING GIN (SearchVector);
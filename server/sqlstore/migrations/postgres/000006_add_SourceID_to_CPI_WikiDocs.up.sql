@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN IF NOT EXISTS SourceID TEXT NOT NULL DEFAULT '';
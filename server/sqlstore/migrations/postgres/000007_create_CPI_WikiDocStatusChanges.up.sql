@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocStatusChanges (
+    ID TEXT PRIMARY KEY,
+    WikiDocID TEXT NOT NULL,
+    FromStatus TEXT NOT NULL,
+    ToStatus TEXT NOT NULL,
+    UserID TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocStatusChanges_WikiDocID ON CPI_WikiDocStatusChanges (WikiDocID);
@@ -0,0 +1 @@
+UPDATE CPI_WikiDocs SET Status = 'Private' WHERE Status = 'Draft';
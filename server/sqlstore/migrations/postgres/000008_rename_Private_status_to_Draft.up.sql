@@ -0,0 +1 @@
+UPDATE CPI_WikiDocs SET Status = 'Draft' WHERE Status = 'Private';
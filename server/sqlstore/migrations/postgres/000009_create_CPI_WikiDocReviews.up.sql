@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocReviews (
+    ID TEXT PRIMARY KEY,
+    WikiDocID TEXT NOT NULL,
+    ReviewerID TEXT NOT NULL,
+    RequesterID TEXT NOT NULL,
+    State TEXT NOT NULL,
+    Comment TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS CPI_WikiDocReviews_WikiDocID_ReviewerID ON CPI_WikiDocReviews (WikiDocID, ReviewerID);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiChannelSettings (
+    ChannelID TEXT PRIMARY KEY,
+    RequiredApprovals INTEGER NOT NULL DEFAULT 0,
+    UpdateAt BIGINT NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocMembers (
+    WikiDocID TEXT NOT NULL,
+    UserID TEXT NOT NULL,
+    Role TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    PRIMARY KEY (WikiDocID, UserID)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocMembers_UserID ON CPI_WikiDocMembers (UserID);
@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocs
+    DROP COLUMN IF EXISTS Restricted;
@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocs
+    ADD COLUMN IF NOT EXISTS Restricted BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiGroupGrants (
+    GroupID TEXT NOT NULL,
+    ChannelID TEXT NOT NULL,
+    WikiDocID TEXT NOT NULL DEFAULT '',
+    Role TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL,
+    PRIMARY KEY (GroupID, ChannelID, WikiDocID)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiGroupGrants_ChannelID ON CPI_WikiGroupGrants (ChannelID);
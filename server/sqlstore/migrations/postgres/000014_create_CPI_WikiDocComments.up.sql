@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocComments (
+    ID TEXT PRIMARY KEY,
+    WikiDocID TEXT NOT NULL,
+    ParentID TEXT NOT NULL DEFAULT '',
+    UserID TEXT NOT NULL,
+    Message TEXT NOT NULL,
+    AnchorRevision BIGINT NOT NULL DEFAULT 0,
+    AnchorHeading TEXT NOT NULL DEFAULT '',
+    AnchorQuote TEXT NOT NULL DEFAULT '',
+    AnchorOffset INT NOT NULL DEFAULT 0,
+    AnchorDetached BOOLEAN NOT NULL DEFAULT FALSE,
+    ResolvedAt BIGINT NOT NULL DEFAULT 0,
+    ResolvedBy TEXT NOT NULL DEFAULT '',
+    CreateAt BIGINT NOT NULL,
+    UpdateAt BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocComments_WikiDocID ON CPI_WikiDocComments (WikiDocID);
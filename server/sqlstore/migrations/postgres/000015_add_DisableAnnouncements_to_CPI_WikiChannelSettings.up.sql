@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiChannelSettings
+    ADD COLUMN IF NOT EXISTS DisableAnnouncements BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiWatches (
+    UserID TEXT NOT NULL,
+    ChannelID TEXT NOT NULL,
+    WikiDocID TEXT NOT NULL DEFAULT '',
+    Scope TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (UserID, ChannelID, WikiDocID)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiWatches_ChannelID ON CPI_WikiWatches (ChannelID);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiNotifications (
+    ID TEXT PRIMARY KEY,
+    UserID TEXT NOT NULL,
+    WikiDocID TEXT NOT NULL,
+    ActorID TEXT NOT NULL,
+    Fields TEXT NOT NULL,
+    PreviousStatus TEXT NOT NULL,
+    Status TEXT NOT NULL,
+    Excerpt TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiNotifications_UserID_CreateAt ON CPI_WikiNotifications (UserID, CreateAt);
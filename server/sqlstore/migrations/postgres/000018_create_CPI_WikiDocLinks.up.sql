@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocLinks (
+    SourceID TEXT NOT NULL,
+    Target TEXT NOT NULL,
+    Label TEXT NOT NULL DEFAULT '',
+    TargetID TEXT NOT NULL DEFAULT '',
+    TeamID TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (SourceID, Target)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocLinks_TargetID ON CPI_WikiDocLinks (TargetID);
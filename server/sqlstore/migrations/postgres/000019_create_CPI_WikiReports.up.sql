@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiReports (
+    ChannelID TEXT NOT NULL,
+    TeamID TEXT NOT NULL,
+    WikiDocCount INT NOT NULL DEFAULT 0,
+    Issues TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (ChannelID)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiReports_TeamID ON CPI_WikiReports (TeamID);
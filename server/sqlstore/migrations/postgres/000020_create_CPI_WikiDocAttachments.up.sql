@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS CPI_WikiDocAttachments (
+    ID TEXT NOT NULL,
+    WikiDocID TEXT NOT NULL,
+    FileID TEXT NOT NULL,
+    Name TEXT NOT NULL,
+    MimeType TEXT NOT NULL DEFAULT '',
+    Size BIGINT NOT NULL DEFAULT 0,
+    UserID TEXT NOT NULL,
+    CreateAt BIGINT NOT NULL,
+    PRIMARY KEY (ID)
+);
+
+CREATE INDEX IF NOT EXISTS CPI_WikiDocAttachments_WikiDocID ON CPI_WikiDocAttachments (WikiDocID);
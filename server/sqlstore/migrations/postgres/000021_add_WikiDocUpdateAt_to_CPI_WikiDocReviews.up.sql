@@ -0,0 +1,2 @@
+ALTER TABLE CPI_WikiDocReviews
+    ADD COLUMN IF NOT EXISTS WikiDocUpdateAt BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,160 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// reportStore is a sql store for the reports of channel wikis. Use NewReportStore to create it.
+type reportStore struct {
+	pluginAPI    PluginAPIClient
+	log          bot.Logger
+	store        *SQLStore
+	queryBuilder sq.StatementBuilderType
+	reportSelect sq.SelectBuilder
+}
+
+// sqlReport is the row of a report, with its issues as JSON.
+type sqlReport struct {
+	ChannelID    string
+	TeamID       string
+	WikiDocCount int
+	Issues       string
+	CreateAt     int64
+}
+
+// Ensure reportStore implements the app.ReportStore interface.
+var _ app.ReportStore = (*reportStore)(nil)
+
+// NewReportStore creates a new store for the reports of channel wikis.
+func NewReportStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.ReportStore {
+	reportSelect := sqlStore.builder.
+		Select(
+			"r.ChannelID",
+			"r.TeamID",
+			"r.WikiDocCount",
+			"r.Issues",
+			"r.CreateAt",
+		).
+		From("CPI_WikiReports r")
+
+	return &reportStore{
+		pluginAPI:    pluginAPI,
+		log:          log,
+		store:        sqlStore,
+		queryBuilder: sqlStore.builder,
+		reportSelect: reportSelect,
+	}
+}
+
+// GetReport retrieves the last report of a channel.
+func (s *reportStore) GetReport(channelID string) (app.WikiReport, error) {
+	if channelID == "" {
+		return app.WikiReport{}, errors.New("channel ID cannot be empty")
+	}
+
+	var row sqlReport
+	err := s.store.getBuilder(s.store.db, &row, s.reportSelect.Where(sq.Eq{"r.ChannelID": channelID}))
+	if err == sql.ErrNoRows {
+		return app.WikiReport{}, errors.Wrapf(app.ErrNotFound, "report of channel '%s' does not exist", channelID)
+	} else if err != nil {
+		return app.WikiReport{}, errors.Wrapf(err, "failed to get report of channel '%s'", channelID)
+	}
+
+	report := app.WikiReport{
+		ChannelID:    row.ChannelID,
+		TeamID:       row.TeamID,
+		WikiDocCount: row.WikiDocCount,
+		Issues:       []app.ReportIssue{},
+		CreateAt:     row.CreateAt,
+	}
+	if err = json.Unmarshal([]byte(row.Issues), &report.Issues); err != nil {
+		return app.WikiReport{}, errors.Wrapf(err, "failed to decode issues of channel '%s'", channelID)
+	}
+
+	return report, nil
+}
+
+// SaveReport creates or replaces the report of a channel.
+func (s *reportStore) SaveReport(report app.WikiReport) error {
+	if report.ChannelID == "" {
+		return errors.New("channel ID cannot be empty")
+	}
+
+	issues, err := json.Marshal(report.Issues)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode issues of channel '%s'", report.ChannelID)
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	_, err = s.store.execBuilder(tx, sq.
+		Delete("CPI_WikiReports").
+		Where(sq.Eq{"ChannelID": report.ChannelID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete report of channel '%s'", report.ChannelID)
+	}
+
+	_, err = s.store.execBuilder(tx, sq.
+		Insert("CPI_WikiReports").
+		SetMap(map[string]interface{}{
+			"ChannelID":    report.ChannelID,
+			"TeamID":       report.TeamID,
+			"WikiDocCount": report.WikiDocCount,
+			"Issues":       string(issues),
+			"CreateAt":     report.CreateAt,
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to store report of channel '%s'", report.ChannelID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// GetTeamIDs retrieves the teams with wikiDocs outside of the trash.
+func (s *reportStore) GetTeamIDs() ([]string, error) {
+	teamIDs := []string{}
+	err := s.store.selectBuilder(s.store.db, &teamIDs, s.queryBuilder.
+		Select("DISTINCT TeamID").
+		From("CPI_WikiDocs").
+		Where(sq.Eq{"DeleteAt": 0}).
+		OrderBy("TeamID ASC"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get teams with wikiDocs")
+	}
+
+	return teamIDs, nil
+}
+
+// GetWikiDocsForTeam retrieves the wikiDocs of a team that are not in the trash, with their
+// content, oldest first.
+func (s *reportStore) GetWikiDocsForTeam(teamID string) ([]app.WikiDoc, error) {
+	if teamID == "" {
+		return nil, errors.New("team ID cannot be empty")
+	}
+
+	wikiDocs := []app.WikiDoc{}
+	err := s.store.selectBuilder(s.store.db, &wikiDocs, s.queryBuilder.
+		Select("w.ID", "w.Name", "w.Content", "w.Status", "w.TeamID", "w.ChannelID", "w.Restricted", "w.CreateAt").
+		From("CPI_WikiDocs w").
+		Where(sq.Eq{"w.TeamID": teamID, "w.DeleteAt": 0}).
+		OrderBy("w.CreateAt ASC", "w.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get wikiDocs of team '%s'", teamID)
+	}
+
+	return wikiDocs, nil
+}
@@ -0,0 +1,157 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// reviewStore is a sql store for the reviews of wikiDocs. Use NewReviewStore to create it.
+type reviewStore struct {
+	pluginAPI    PluginAPIClient
+	log          bot.Logger
+	store        *SQLStore
+	queryBuilder sq.StatementBuilderType
+	reviewSelect sq.SelectBuilder
+}
+
+// Ensure reviewStore implements the app.ReviewStore interface.
+var _ app.ReviewStore = (*reviewStore)(nil)
+
+// NewReviewStore creates a new store for the reviews of wikiDocs.
+func NewReviewStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.ReviewStore {
+	reviewSelect := sqlStore.builder.
+		Select(
+			"r.ID",
+			"r.WikiDocID",
+			"r.ReviewerID",
+			"r.RequesterID",
+			"r.State",
+			"r.Comment",
+			"r.WikiDocUpdateAt",
+			"r.CreateAt",
+			"r.UpdateAt",
+		).
+		From("CPI_WikiDocReviews r")
+
+	return &reviewStore{
+		pluginAPI:    pluginAPI,
+		log:          log,
+		store:        sqlStore,
+		queryBuilder: sqlStore.builder,
+		reviewSelect: reviewSelect,
+	}
+}
+
+// GetReviews retrieves the reviews of a wikiDoc, oldest first.
+func (s *reviewStore) GetReviews(wikiDocID string) ([]app.Review, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	reviews := []app.Review{}
+	err := s.store.selectBuilder(s.store.db, &reviews, s.reviewSelect.
+		Where(sq.Eq{"r.WikiDocID": wikiDocID}).
+		OrderBy("r.CreateAt ASC", "r.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get reviews of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return reviews, nil
+}
+
+// GetReview retrieves the review of a wikiDoc by a reviewer.
+func (s *reviewStore) GetReview(wikiDocID, reviewerID string) (app.Review, error) {
+	if wikiDocID == "" || reviewerID == "" {
+		return app.Review{}, errors.New("IDs cannot be empty")
+	}
+
+	var review app.Review
+	err := s.store.getBuilder(s.store.db, &review, s.reviewSelect.
+		Where(sq.Eq{"r.WikiDocID": wikiDocID, "r.ReviewerID": reviewerID}))
+	if err == sql.ErrNoRows {
+		return app.Review{}, errors.Wrapf(app.ErrNotFound, "user '%s' was not asked to review wikiDoc '%s'", reviewerID, wikiDocID)
+	} else if err != nil {
+		return app.Review{}, errors.Wrapf(err, "failed to get review of wikiDoc '%s' by user '%s'", wikiDocID, reviewerID)
+	}
+
+	return review, nil
+}
+
+// SaveReview creates the review of a wikiDoc by a reviewer, or replaces the existing one, keeping
+// its ID and creation time.
+func (s *reviewStore) SaveReview(review app.Review) (app.Review, error) {
+	if review.WikiDocID == "" || review.ReviewerID == "" {
+		return app.Review{}, errors.New("IDs cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return app.Review{}, errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	var existing app.Review
+	err = s.store.getBuilder(tx, &existing, s.reviewSelect.
+		Where(sq.Eq{"r.WikiDocID": review.WikiDocID, "r.ReviewerID": review.ReviewerID}))
+	if err != nil && err != sql.ErrNoRows {
+		return app.Review{}, errors.Wrapf(err, "failed to get review of wikiDoc '%s' by user '%s'", review.WikiDocID, review.ReviewerID)
+	}
+
+	if err == sql.ErrNoRows {
+		review.ID = model.NewId()
+		_, err = s.store.execBuilder(tx, sq.
+			Insert("CPI_WikiDocReviews").
+			SetMap(map[string]interface{}{
+				"ID":              review.ID,
+				"WikiDocID":       review.WikiDocID,
+				"ReviewerID":      review.ReviewerID,
+				"RequesterID":     review.RequesterID,
+				"State":           review.State,
+				"Comment":         review.Comment,
+				"WikiDocUpdateAt": review.WikiDocUpdateAt,
+				"CreateAt":        review.CreateAt,
+				"UpdateAt":        review.UpdateAt,
+			}))
+	} else {
+		review.ID = existing.ID
+		review.CreateAt = existing.CreateAt
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocReviews").
+			SetMap(map[string]interface{}{
+				"RequesterID":     review.RequesterID,
+				"State":           review.State,
+				"Comment":         review.Comment,
+				"WikiDocUpdateAt": review.WikiDocUpdateAt,
+				"UpdateAt":        review.UpdateAt,
+			}).
+			Where(sq.Eq{"ID": review.ID}))
+	}
+	if err != nil {
+		return app.Review{}, errors.Wrapf(err, "failed to store review of wikiDoc '%s' by user '%s'", review.WikiDocID, review.ReviewerID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return app.Review{}, errors.Wrap(err, "could not commit transaction")
+	}
+
+	return review, nil
+}
+
+// CountApprovals returns the number of reviewers who approved the version updateAt of a wikiDoc.
+func (s *reviewStore) CountApprovals(wikiDocID string, updateAt int64) (int, error) {
+	var count int
+	err := s.store.getBuilder(s.store.db, &count, s.queryBuilder.
+		Select("COUNT(*)").
+		From("CPI_WikiDocReviews").
+		Where(sq.Eq{"WikiDocID": wikiDocID, "State": app.ReviewStateApproved, "WikiDocUpdateAt": updateAt}))
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to count approvals of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,220 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// watchStore is a sql store for the watches of wikiDocs and the notifications waiting to be sent.
+// Use NewWatchStore to create it.
+type watchStore struct {
+	pluginAPI          PluginAPIClient
+	log                bot.Logger
+	store              *SQLStore
+	queryBuilder       sq.StatementBuilderType
+	watchSelect        sq.SelectBuilder
+	notificationSelect sq.SelectBuilder
+}
+
+// Ensure watchStore implements the app.WatchStore interface.
+var _ app.WatchStore = (*watchStore)(nil)
+
+// NewWatchStore creates a new store for the watches of wikiDocs.
+func NewWatchStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.WatchStore {
+	watchSelect := sqlStore.builder.
+		Select(
+			"w.UserID",
+			"w.ChannelID",
+			"w.WikiDocID",
+			"w.Scope",
+			"w.CreateAt",
+		).
+		From("CPI_WikiWatches w")
+
+	notificationSelect := sqlStore.builder.
+		Select(
+			"n.ID",
+			"n.UserID",
+			"n.WikiDocID",
+			"n.ActorID",
+			"n.Fields",
+			"n.PreviousStatus",
+			"n.Status",
+			"n.Excerpt",
+			"n.CreateAt",
+		).
+		From("CPI_WikiNotifications n")
+
+	return &watchStore{
+		pluginAPI:          pluginAPI,
+		log:                log,
+		store:              sqlStore,
+		queryBuilder:       sqlStore.builder,
+		watchSelect:        watchSelect,
+		notificationSelect: notificationSelect,
+	}
+}
+
+// GetWatches retrieves the watches of a user in a channel, oldest first.
+func (s *watchStore) GetWatches(userID, channelID string) ([]app.Watch, error) {
+	if userID == "" || channelID == "" {
+		return nil, errors.New("IDs cannot be empty")
+	}
+
+	watches := []app.Watch{}
+	err := s.store.selectBuilder(s.store.db, &watches, s.watchSelect.
+		Where(sq.Eq{"w.UserID": userID, "w.ChannelID": channelID}).
+		OrderBy("w.CreateAt ASC", "w.WikiDocID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get watches of user '%s' in channel '%s'", userID, channelID)
+	}
+
+	return watches, nil
+}
+
+// GetWatcherIDs retrieves the users watching a wikiDoc of a channel.
+func (s *watchStore) GetWatcherIDs(channelID, wikiDocID string, ancestorIDs []string) ([]string, error) {
+	if channelID == "" || wikiDocID == "" {
+		return nil, errors.New("IDs cannot be empty")
+	}
+
+	watched := sq.Or{
+		sq.Eq{"Scope": app.WatchScopeChannel},
+		sq.Eq{"WikiDocID": wikiDocID},
+	}
+	if len(ancestorIDs) > 0 {
+		watched = append(watched, sq.Eq{"Scope": app.WatchScopeTree, "WikiDocID": ancestorIDs})
+	}
+
+	userIDs := []string{}
+	err := s.store.selectBuilder(s.store.db, &userIDs, s.queryBuilder.
+		Select("DISTINCT UserID").
+		From("CPI_WikiWatches").
+		Where(sq.Eq{"ChannelID": channelID}).
+		Where(watched))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get watchers of wikiDoc '%s'", wikiDocID)
+	}
+
+	return userIDs, nil
+}
+
+// SaveWatch creates a watch, or replaces the scope of an existing one.
+func (s *watchStore) SaveWatch(watch app.Watch) error {
+	if watch.UserID == "" || watch.ChannelID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	var existing app.Watch
+	err = s.store.getBuilder(tx, &existing, s.watchSelect.
+		Where(sq.Eq{"w.UserID": watch.UserID, "w.ChannelID": watch.ChannelID, "w.WikiDocID": watch.WikiDocID}))
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(err, "failed to get watch of user '%s' on wikiDoc '%s'", watch.UserID, watch.WikiDocID)
+	}
+
+	if err == sql.ErrNoRows {
+		_, err = s.store.execBuilder(tx, sq.
+			Insert("CPI_WikiWatches").
+			SetMap(map[string]interface{}{
+				"UserID":    watch.UserID,
+				"ChannelID": watch.ChannelID,
+				"WikiDocID": watch.WikiDocID,
+				"Scope":     watch.Scope,
+				"CreateAt":  watch.CreateAt,
+			}))
+	} else {
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiWatches").
+			Set("Scope", watch.Scope).
+			Where(sq.Eq{"UserID": watch.UserID, "ChannelID": watch.ChannelID, "WikiDocID": watch.WikiDocID}))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to store watch of user '%s' on wikiDoc '%s'", watch.UserID, watch.WikiDocID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// DeleteWatch removes the watch of a user on a wikiDoc, or on the channel wiki when wikiDocID is
+// empty.
+func (s *watchStore) DeleteWatch(userID, channelID, wikiDocID string) error {
+	if userID == "" || channelID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiWatches").
+		Where(sq.Eq{"UserID": userID, "ChannelID": channelID, "WikiDocID": wikiDocID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete watch of user '%s' on wikiDoc '%s'", userID, wikiDocID)
+	}
+
+	return nil
+}
+
+// AddNotifications queues notifications to be sent.
+func (s *watchStore) AddNotifications(notifications []app.WikiDocNotification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	insert := sq.
+		Insert("CPI_WikiNotifications").
+		Columns("ID", "UserID", "WikiDocID", "ActorID", "Fields", "PreviousStatus", "Status", "Excerpt", "CreateAt")
+	for _, n := range notifications {
+		insert = insert.Values(n.ID, n.UserID, n.WikiDocID, n.ActorID, n.Fields, n.PreviousStatus, n.Status, n.Excerpt, n.CreateAt)
+	}
+
+	if _, err := s.store.execBuilder(s.store.db, insert); err != nil {
+		return errors.Wrap(err, "failed to store notifications")
+	}
+
+	return nil
+}
+
+// GetDueNotifications retrieves every queued notification of the users whose oldest queued
+// notification was created before the given time, oldest first.
+func (s *watchStore) GetDueNotifications(before int64) ([]app.WikiDocNotification, error) {
+	notifications := []app.WikiDocNotification{}
+	err := s.store.selectBuilder(s.store.db, &notifications, s.notificationSelect.
+		Where(sq.Expr(`n.UserID IN (SELECT UserID
+					 FROM CPI_WikiNotifications
+					 GROUP BY UserID
+					 HAVING MIN(CreateAt) < ?)`, before)).
+		OrderBy("n.CreateAt ASC", "n.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get due notifications")
+	}
+
+	return notifications, nil
+}
+
+// DeleteNotifications removes sent notifications.
+func (s *watchStore) DeleteNotifications(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiNotifications").
+		Where(sq.Eq{"ID": ids}))
+	if err != nil {
+		return errors.Wrap(err, "failed to delete notifications")
+	}
+
+	return nil
+}
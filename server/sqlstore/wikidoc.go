@@ -6,9 +6,11 @@ import (
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
 	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/pkg/errors"
 	"math"
+	"strings"
 )
 
 type sqlWikiDoc struct {
@@ -22,12 +24,17 @@ type wikiDocStore struct {
 	store         *SQLStore
 	queryBuilder  sq.StatementBuilderType
 	wikiDocSelect sq.SelectBuilder
+
+	// wikiDocSummarySelect selects every field of wikiDocs but their content.
+	wikiDocSummarySelect sq.SelectBuilder
 }
 
 // Ensure wikiDocStore implements the wikiDoc.Store interface.
 var _ app.WikiDocStore = (*wikiDocStore)(nil)
 
-func applyWikiDocFilterOptionsSort(builder sq.SelectBuilder, options app.WikiDocFilterOptions) (sq.SelectBuilder, error) {
+// applyWikiDocFilterOptionsSort applies the sort and pagination options. The relevance expression
+// is required to sort by relevance.
+func applyWikiDocFilterOptionsSort(builder sq.SelectBuilder, options app.WikiDocFilterOptions, relevance sq.Sqlizer) (sq.SelectBuilder, error) {
 	var sort string
 	switch options.Sort {
 	case app.SortByID:
@@ -38,6 +45,16 @@ func applyWikiDocFilterOptionsSort(builder sq.SelectBuilder, options app.WikiDoc
 		sort = "Name"
 	case app.SortByStatus:
 		sort = "Status"
+	case app.SortByOwnerUserID:
+		sort = "OwnerUserID"
+	case app.SortByTeamID:
+		sort = "TeamID"
+	case app.SortByChannelID:
+		sort = "ChannelID"
+	case app.SortByRelevance:
+		if relevance == nil {
+			return sq.SelectBuilder{}, errors.New("sorting by relevance requires a search term")
+		}
 	case "":
 		// Default to a stable sort if none explicitly provided.
 		sort = "ID"
@@ -58,7 +75,15 @@ func applyWikiDocFilterOptionsSort(builder sq.SelectBuilder, options app.WikiDoc
 		return sq.SelectBuilder{}, errors.Errorf("unsupported direction parameter '%s'", options.Direction)
 	}
 
-	builder = builder.OrderByClause(fmt.Sprintf("%s %s", sort, direction))
+	if options.Sort == app.SortByRelevance {
+		relevanceSQL, args, err := relevance.ToSql()
+		if err != nil {
+			return sq.SelectBuilder{}, errors.Wrap(err, "failed to build relevance sort")
+		}
+		builder = builder.OrderByClause(fmt.Sprintf("%s %s, ID ASC", relevanceSQL, direction), args...)
+	} else {
+		builder = builder.OrderByClause(fmt.Sprintf("%s %s", sort, direction))
+	}
 
 	page := options.Page
 	perPage := options.PerPage
@@ -81,29 +106,71 @@ func NewWikiDocStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLSto
 	wikiDocSelect := sqlStore.builder.
 		Select(
 			"w.ID",
+			"w.Name",
+			"w.Content",
+			"w.Description",
+			"w.Status",
+			"w.OwnerUserID",
 			"w.TeamID",
 			"w.ChannelID",
+			"w.ParentID",
+			"w.SortOrder",
+			"w.SourceID",
+			"w.Restricted",
 			"w.CreateAt",
 			"w.UpdateAt",
+			"w.DeleteAt",
+		).
+		From("CPI_WikiDocs w")
+
+	wikiDocSummarySelect := sqlStore.builder.
+		Select(
+			"w.ID",
+			"w.Name",
+			"w.Description",
+			"w.Status",
+			"w.OwnerUserID",
+			"w.TeamID",
+			"w.ChannelID",
+			"w.ParentID",
+			"w.SortOrder",
+			"w.SourceID",
+			"w.Restricted",
+			"w.CreateAt",
+			"w.UpdateAt",
+			"w.DeleteAt",
 		).
 		From("CPI_WikiDocs w")
 
 	newStore := &wikiDocStore{
-		pluginAPI:     pluginAPI,
-		log:           log,
-		store:         sqlStore,
-		queryBuilder:  sqlStore.builder,
-		wikiDocSelect: wikiDocSelect,
+		pluginAPI:            pluginAPI,
+		log:                  log,
+		store:                sqlStore,
+		queryBuilder:         sqlStore.builder,
+		wikiDocSelect:        wikiDocSelect,
+		wikiDocSummarySelect: wikiDocSummarySelect,
 	}
 	return newStore
 }
 
 // Create creates a new wikiDoc
 func (p *wikiDocStore) Create(wikiDoc app.WikiDoc) (id string, err error) {
-	/*if wikiDoc.ID != "" {
-		return "", errors.New("ID should be empty")
-	}*/
-	wikiDoc.ID = model.NewId()
+	return p.CreateWithAttachments(wikiDoc, nil)
+}
+
+// CreateWithAttachments creates a new wikiDoc along with its attachments, in a single transaction.
+func (p *wikiDocStore) CreateWithAttachments(wikiDoc app.WikiDoc, attachments []app.Attachment) (id string, err error) {
+	return p.CreateWithRevisions(wikiDoc, nil, attachments, "")
+}
+
+// CreateWithRevisions creates a new wikiDoc along with its later versions and its attachments, in a
+// single transaction. The later versions, oldest first, are recorded as revisions authored by userID,
+// and the wikiDoc is left in the last one.
+func (p *wikiDocStore) CreateWithRevisions(wikiDoc app.WikiDoc, revisions []app.WikiDoc, attachments []app.Attachment, userID string) (id string, err error) {
+	// The ID can be chosen beforehand, as when duplicating a wikiDoc whose attachments are copied.
+	if wikiDoc.ID == "" {
+		wikiDoc.ID = model.NewId()
+	}
 
 	rawWikiDoc, err := toSQLWikiDoc(wikiDoc)
 	if err != nil {
@@ -116,6 +183,15 @@ func (p *wikiDocStore) Create(wikiDoc app.WikiDoc) (id string, err error) {
 	}
 	defer p.store.finalizeTransaction(tx)
 
+	// New wikiDocs are placed after their siblings.
+	err = p.store.getBuilder(tx, &rawWikiDoc.SortOrder, sq.
+		Select("COALESCE(MAX(SortOrder) + 1, 0)").
+		From("CPI_WikiDocs").
+		Where(sq.Eq{"ChannelID": rawWikiDoc.ChannelID, "ParentID": rawWikiDoc.ParentID, "DeleteAt": 0}))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the position of the new wikiDoc")
+	}
+
 	_, err = p.store.execBuilder(tx, sq.
 		Insert("CPI_WikiDocs").
 		SetMap(map[string]interface{}{
@@ -126,6 +202,10 @@ func (p *wikiDocStore) Create(wikiDoc app.WikiDoc) (id string, err error) {
 			"OwnerUserID": rawWikiDoc.OwnerUserID,
 			"TeamID":      rawWikiDoc.TeamID,
 			"ChannelID":   rawWikiDoc.ChannelID,
+			"ParentID":    rawWikiDoc.ParentID,
+			"SortOrder":   rawWikiDoc.SortOrder,
+			"SourceID":    rawWikiDoc.SourceID,
+			"Restricted":  rawWikiDoc.Restricted,
 			"Description": rawWikiDoc.Description,
 			"CreateAt":    rawWikiDoc.CreateAt,
 			"UpdateAt":    rawWikiDoc.UpdateAt,
@@ -135,6 +215,55 @@ func (p *wikiDocStore) Create(wikiDoc app.WikiDoc) (id string, err error) {
 		return "", errors.Wrap(err, "failed to store new wikiDoc")
 	}
 
+	if err = p.insertRevision(tx, rawWikiDoc.WikiDoc, rawWikiDoc.OwnerUserID, rawWikiDoc.CreateAt); err != nil {
+		return "", err
+	}
+
+	if err = p.insertStatusChange(tx, rawWikiDoc.ID, "", rawWikiDoc.Status, rawWikiDoc.OwnerUserID, rawWikiDoc.CreateAt); err != nil {
+		return "", err
+	}
+
+	previousStatus := rawWikiDoc.Status
+	for _, revision := range revisions {
+		revision.ID = rawWikiDoc.ID
+		rawRevision, err := toSQLWikiDoc(revision)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = p.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocs").
+			SetMap(map[string]interface{}{
+				"Name":        rawRevision.Name,
+				"Content":     rawRevision.Content,
+				"Status":      rawRevision.Status,
+				"Description": rawRevision.Description,
+				"UpdateAt":    rawRevision.UpdateAt,
+			}).
+			Where(sq.Eq{"ID": rawRevision.ID}))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to store revision of new wikiDoc with id '%s'", rawRevision.ID)
+		}
+
+		if err = p.insertRevision(tx, rawRevision.WikiDoc, userID, rawRevision.UpdateAt); err != nil {
+			return "", err
+		}
+
+		if previousStatus != rawRevision.Status {
+			if err = p.insertStatusChange(tx, rawRevision.ID, previousStatus, rawRevision.Status, userID, rawRevision.UpdateAt); err != nil {
+				return "", err
+			}
+			previousStatus = rawRevision.Status
+		}
+	}
+
+	for _, attachment := range attachments {
+		attachment.WikiDocID = rawWikiDoc.ID
+		if err = p.store.insertAttachment(tx, attachment); err != nil {
+			return "", err
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
 		return "", errors.Wrap(err, "could not commit transaction")
 	}
@@ -174,14 +303,12 @@ func (p *wikiDocStore) Get(id string) (app.WikiDoc, error) {
 	return wikiDoc, nil
 }
 
-// GetWikiDocs retrieves all wikiDocs that are not deleted.
-// Members are not retrieved for this as the query would be large and we don't need it for this for now.
-// This is only used for the keywords feature
+// GetWikiDocs retrieves the wikiDocs that are not deleted and that the requester can read.
 func (p *wikiDocStore) GetWikiDocs(requesterInfo app.RequesterInfo, options app.WikiDocFilterOptions) (*app.GetWikiDocsResults, error) {
 	queryForTotal := p.store.builder.
 		Select("COUNT(*)").
-		From("CPI_WikiDocs AS w") /*.
-		Join("Channels AS c ON (c.Id = w.ChannelId)")*/
+		From("CPI_WikiDocs AS w").
+		Where(sq.Eq{"w.DeleteAt": 0})
 
 	queryForResults := p.store.builder.
 		Select(
@@ -193,6 +320,10 @@ func (p *wikiDocStore) GetWikiDocs(requesterInfo app.RequesterInfo, options app.
 			"w.OwnerUserID",
 			"w.TeamID",
 			"w.ChannelID",
+			"w.ParentID",
+			"w.SortOrder",
+			"w.SourceID",
+			"w.Restricted",
 			"w.CreateAt",
 			"w.UpdateAt",
 			"w.DeleteAt",
@@ -200,6 +331,11 @@ func (p *wikiDocStore) GetWikiDocs(requesterInfo app.RequesterInfo, options app.
 		From("CPI_WikiDocs AS w").
 		Where(sq.Eq{"w.DeleteAt": 0})
 
+	if permissionsExpr := p.buildPermissionsExpr(requesterInfo); permissionsExpr != nil {
+		queryForResults = queryForResults.Where(permissionsExpr)
+		queryForTotal = queryForTotal.Where(permissionsExpr)
+	}
+
 	if options.OwnerID != "" {
 		queryForResults = queryForResults.Where(sq.Eq{"w.OwnerUserID": options.OwnerID})
 		queryForTotal = queryForTotal.Where(sq.Eq{"w.OwnerUserID": options.OwnerID})
@@ -215,7 +351,18 @@ func (p *wikiDocStore) GetWikiDocs(requesterInfo app.RequesterInfo, options app.
 		queryForTotal = queryForTotal.Where(sq.Eq{"w.ChannelID": options.ChannelId})
 	}
 
-	queryForResults, err := applyWikiDocFilterOptionsSort(queryForResults, options)
+	if len(options.Statuses) > 0 {
+		queryForResults = queryForResults.Where(sq.Eq{"w.Status": options.Statuses})
+		queryForTotal = queryForTotal.Where(sq.Eq{"w.Status": options.Statuses})
+	}
+
+	searchFilter, relevance := p.buildSearchExprs(options.SearchTerm)
+	if searchFilter != nil {
+		queryForResults = queryForResults.Where(searchFilter)
+		queryForTotal = queryForTotal.Where(searchFilter)
+	}
+
+	queryForResults, err := applyWikiDocFilterOptionsSort(queryForResults, options, relevance)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to apply sort options")
 	}
@@ -257,37 +404,81 @@ func (p *wikiDocStore) GetWikiDocs(requesterInfo app.RequesterInfo, options app.
 	}, nil
 }
 
+// buildSearchExprs returns the full-text search filter and relevance expressions for the search
+// term, or nils if it holds no words. Every word must match, as a prefix, the name, description or
+// content of a wikiDoc.
+func (p *wikiDocStore) buildSearchExprs(searchTerm string) (filter sq.Sqlizer, relevance sq.Sqlizer) {
+	terms := app.SearchTerms(searchTerm)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if p.store.db.DriverName() == model.DatabaseDriverMysql {
+		words := make([]string, len(terms))
+		for i, term := range terms {
+			words[i] = "+" + term + "*"
+		}
+
+		match := sq.Expr("MATCH(w.Name, w.Description, w.Content) AGAINST (? IN BOOLEAN MODE)", strings.Join(words, " "))
+		return match, match
+	}
+
+	words := make([]string, len(terms))
+	for i, term := range terms {
+		words[i] = term + ":*"
+	}
+	query := strings.Join(words, " & ")
+
+	return sq.Expr("w.SearchVector @@ to_tsquery('english', ?)", query),
+		sq.Expr("ts_rank(w.SearchVector, to_tsquery('english', ?))", query)
+}
+
+// buildPermissionsExpr restricts a query to the wikiDocs the requester can read, following the same
+// rules as app.PermissionsService.WikiDocView. Returns nil for admins, who can read every wikiDoc.
 func (p *wikiDocStore) buildPermissionsExpr(info app.RequesterInfo) sq.Sqlizer {
 	if info.IsAdmin {
 		return nil
 	}
 
-	// Guests must be channel members
-	if info.IsGuest {
-		return sq.Expr(`(
-			w.Status = "Published"
-			AND
-			  EXISTS(SELECT 1
-						 FROM ChannelMembers as cm
-						 WHERE cm.ChannelId = w.ChannelID
-						   AND cm.UserId = ?)
-		)`, info.UserID)
-	}
-
-	// 1. Is the user a channel member? If so, they have permission to view the run.
-	// 2. Is the wikiDoc open to everyone on the team, or is the user a member of the wikiDoc?
-	//    If so, they have permission to view the run.
-	return sq.Expr(`
-        (
-			EXISTS(SELECT 1
+	// Members of a wikiDoc can read it whatever their other permissions, directly or through their
+	// groups.
+	isMember := sq.Or{sq.Expr(`EXISTS(SELECT 1
+					 FROM CPI_WikiDocMembers as m
+					 WHERE m.WikiDocID = w.ID
+					   AND m.UserID = ?)`, info.UserID)}
+	if len(info.GroupIDs) > 0 {
+		isMember = append(isMember, sq.Expr(`EXISTS(SELECT 1
+					 FROM CPI_WikiGroupGrants as g
+					 WHERE g.WikiDocID = w.ID
+					   AND ?)`, sq.Eq{"g.GroupID": info.GroupIDs}))
+	}
+
+	isChannelMember := sq.Expr(`EXISTS(SELECT 1
 					 FROM ChannelMembers as cm
 					 WHERE cm.ChannelId = w.ChannelID
-					   AND cm.UserId = ?)
-		)`, info.UserID)
+					   AND cm.UserId = ?)`, info.UserID)
+
+	// Otherwise the wikiDoc must not be restricted to its members, and the user must be a member of
+	// its channel, where guests only read public wikiDocs, or of a group granted the channel wiki.
+	var channelMembership sq.Sqlizer = isChannelMember
+	if info.IsGuest {
+		channelMembership = sq.And{isChannelMember, sq.Eq{"w.Status": app.PublicStatuses()}}
+	}
+	if len(info.GroupIDs) > 0 {
+		channelMembership = sq.Or{channelMembership, sq.Expr(`EXISTS(SELECT 1
+					 FROM CPI_WikiGroupGrants as g
+					 WHERE g.ChannelID = w.ChannelID
+					   AND g.WikiDocID = ''
+					   AND ?)`, sq.Eq{"g.GroupID": info.GroupIDs})}
+	}
+
+	return sq.Or{isMember, sq.And{sq.Eq{"w.Restricted": false}, channelMembership}}
 }
 
-// Update updates a wikidoc
-func (p *wikiDocStore) Update(wikiDoc app.WikiDoc) (err error) {
+// Update updates a wikidoc and records the new state as a revision authored by userID, resetting
+// its reviews to pending if resetReviews is set. The update only applies if the stored wikiDoc
+// still has previousUpdateAt, otherwise app.ErrConflict is returned.
+func (p *wikiDocStore) Update(wikiDoc app.WikiDoc, previousUpdateAt int64, userID string, resetReviews bool) (err error) {
 	if wikiDoc.ID == "" {
 		return errors.New("id should not be empty")
 	}
@@ -303,7 +494,17 @@ func (p *wikiDocStore) Update(wikiDoc app.WikiDoc) (err error) {
 	}
 	defer p.store.finalizeTransaction(tx)
 
-	_, err = p.store.execBuilder(tx, sq.
+	// A missing row is reported after the update below, as a conflict or as not found.
+	var previousStatus string
+	err = p.store.getBuilder(tx, &previousStatus, sq.
+		Select("Status").
+		From("CPI_WikiDocs").
+		Where(sq.Eq{"ID": rawWikiDoc.ID, "UpdateAt": previousUpdateAt}))
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(err, "failed to get status of wikiDoc with id '%s'", rawWikiDoc.ID)
+	}
+
+	result, err := p.store.execBuilder(tx, sq.
 		Update("CPI_WikiDocs").
 		SetMap(map[string]interface{}{
 			"Name":        rawWikiDoc.Name,
@@ -316,11 +517,51 @@ func (p *wikiDocStore) Update(wikiDoc app.WikiDoc) (err error) {
 			"UpdateAt":    rawWikiDoc.UpdateAt,
 			"DeleteAt":    rawWikiDoc.DeleteAt,
 		}).
-		Where(sq.Eq{"ID": rawWikiDoc.ID}))
+		Where(sq.Eq{"ID": rawWikiDoc.ID, "UpdateAt": previousUpdateAt}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to update wikiDoc with id '%s'", rawWikiDoc.ID)
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return errors.Wrapf(err, "failed to update wikiDoc with id '%s'", rawWikiDoc.ID)
 	}
+	if rowsAffected == 0 {
+		var count int
+		if err = p.store.getBuilder(tx, &count, sq.Select("COUNT(*)").From("CPI_WikiDocs").Where(sq.Eq{"ID": rawWikiDoc.ID})); err != nil {
+			return errors.Wrapf(err, "failed to check wikiDoc with id '%s'", rawWikiDoc.ID)
+		}
+		if count == 0 {
+			return errors.Wrapf(app.ErrNotFound, "wikiDoc does not exist for id '%s'", rawWikiDoc.ID)
+		}
+		return errors.Wrapf(app.ErrConflict, "wikiDoc with id '%s' was modified since %d", rawWikiDoc.ID, previousUpdateAt)
+	}
+
+	if err = p.insertRevision(tx, rawWikiDoc.WikiDoc, userID, rawWikiDoc.UpdateAt); err != nil {
+		return err
+	}
+
+	if previousStatus != rawWikiDoc.Status {
+		if err = p.insertStatusChange(tx, rawWikiDoc.ID, previousStatus, rawWikiDoc.Status, userID, rawWikiDoc.UpdateAt); err != nil {
+			return err
+		}
+	}
+
+	if resetReviews {
+		_, err = p.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocReviews").
+			SetMap(map[string]interface{}{
+				"State":    app.ReviewStatePending,
+				"Comment":  "",
+				"UpdateAt": rawWikiDoc.UpdateAt,
+			}).
+			Where(sq.Eq{"WikiDocID": rawWikiDoc.ID}).
+			Where(sq.NotEq{"State": app.ReviewStatePending}))
+		if err != nil {
+			return errors.Wrapf(err, "failed to reset reviews of wikiDoc with id '%s'", rawWikiDoc.ID)
+		}
+	}
 
 	if err = tx.Commit(); err != nil {
 		return errors.Wrap(err, "could not commit transaction")
@@ -329,30 +570,205 @@ func (p *wikiDocStore) Update(wikiDoc app.WikiDoc) (err error) {
 	return nil
 }
 
-// Archive archives a wikiDoc.
-func (p *wikiDocStore) Archive(id string) error {
+// Archive moves a wikiDoc to the trash, if it is still at updateAt, and its descendants along with it.
+// UpdateAt is increased even if the clock went backwards, as it is the version of the wikiDoc.
+func (p *wikiDocStore) Archive(id string, updateAt int64, descendantIDs []string, deleteAt int64) error {
+	if id == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	tx, err := p.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer p.store.finalizeTransaction(tx)
+
+	values := map[string]interface{}{
+		"DeleteAt": deleteAt,
+		"UpdateAt": sq.Expr("GREATEST(UpdateAt + 1, ?)", deleteAt),
+	}
+
+	result, err := p.store.execBuilder(tx, sq.
+		Update("CPI_WikiDocs").
+		SetMap(values).
+		Where(sq.Eq{"ID": id, "UpdateAt": updateAt, "DeleteAt": 0}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete wikiDoc with id '%s'", id)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete wikiDoc with id '%s'", id)
+	}
+	if rowsAffected == 0 {
+		return errors.Wrapf(app.ErrConflict, "wikiDoc with id '%s' was modified since %d", id, updateAt)
+	}
+
+	if len(descendantIDs) > 0 {
+		_, err = p.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocs").
+			SetMap(values).
+			Where(sq.Eq{"ID": descendantIDs, "DeleteAt": 0}))
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete the descendants of wikiDoc with id '%s'", id)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// Unarchive takes a wikiDoc out of the trash.
+func (p *wikiDocStore) Unarchive(id string, updateAt int64) error {
 	if id == "" {
 		return errors.New("ID cannot be empty")
 	}
 
 	_, err := p.store.execBuilder(p.store.db, sq.
 		Update("CPI_WikiDocs").
-		Set("DeleteAt", model.GetMillis()).
+		SetMap(map[string]interface{}{
+			"DeleteAt": 0,
+			"UpdateAt": sq.Expr("GREATEST(UpdateAt + 1, ?)", updateAt),
+		}).
 		Where(sq.Eq{"ID": id}))
 
 	if err != nil {
-		return errors.Wrapf(err, "failed to delete wikiDoc with id '%s'", id)
+		return errors.Wrapf(err, "failed to restore wikiDoc with id '%s'", id)
 	}
 
 	return nil
 }
 
-func (p *wikiDocStore) Delete(id string) error {
+// SetRestricted changes whether a wikiDoc is restricted to its members. UpdateAt is increased even if
+// the clock went backwards, as it is the version of the wikiDoc.
+func (p *wikiDocStore) SetRestricted(id string, restricted bool, updateAt int64) error {
 	if id == "" {
 		return errors.New("ID cannot be empty")
 	}
 
 	_, err := p.store.execBuilder(p.store.db, sq.
+		Update("CPI_WikiDocs").
+		SetMap(map[string]interface{}{
+			"Restricted": restricted,
+			"UpdateAt":   sq.Expr("GREATEST(UpdateAt + 1, ?)", updateAt),
+		}).
+		Where(sq.Eq{"ID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to restrict wikiDoc with id '%s'", id)
+	}
+
+	return nil
+}
+
+// Delete permanently deletes a wikiDoc, its revisions, its status changes, its reviews, its members,
+// its group grants, its comments, its watches, its queued notifications, its links and its
+// attachments. The links to the wikiDoc are kept, but resolve to no wikiDoc anymore. The files of
+// the attachments stay in the storage of the server, the Mattermost file API cannot delete them.
+func (p *wikiDocStore) Delete(id string) error {
+	if id == "" {
+		return errors.New("ID cannot be empty")
+	}
+
+	tx, err := p.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer p.store.finalizeTransaction(tx)
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocRevisions").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete revisions of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocStatusChanges").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete status changes of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocReviews").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete reviews of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocMembers").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete members of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiGroupGrants").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete group grants of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocComments").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete comments of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiWatches").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete watches of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiNotifications").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete notifications of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocLinks").
+		Where(sq.Eq{"SourceID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete links of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Update("CPI_WikiDocLinks").
+		Set("TargetID", "").
+		Where(sq.Eq{"TargetID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to unresolve links to wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Delete("CPI_WikiDocAttachments").
+		Where(sq.Eq{"WikiDocID": id}))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete attachments of wikiDoc with id '%s'", id)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
 		Delete("CPI_WikiDocs").
 		Where(sq.Eq{"ID": id}))
 
@@ -360,9 +776,269 @@ func (p *wikiDocStore) Delete(id string) error {
 		return errors.Wrapf(err, "failed to delete wikiDoc with id '%s'", id)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// GetWikiDocsForChannel retrieves all wikiDocs of a channel that are not deleted, without their content.
+func (p *wikiDocStore) GetWikiDocsForChannel(channelID string) ([]app.WikiDoc, error) {
+	if channelID == "" {
+		return nil, errors.New("channel ID cannot be empty")
+	}
+
+	wikiDocs := []app.WikiDoc{}
+	err := p.store.selectBuilder(p.store.db, &wikiDocs, p.wikiDocSummarySelect.
+		Where(sq.Eq{"w.ChannelID": channelID, "w.DeleteAt": 0}).
+		OrderBy("w.SortOrder ASC", "w.CreateAt ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get wikiDocs of channel '%s'", channelID)
+	}
+
+	return wikiDocs, nil
+}
+
+// GetChannelIDs retrieves the channels of a team with wikiDocs that are not deleted.
+func (p *wikiDocStore) GetChannelIDs(teamID string) ([]string, error) {
+	if teamID == "" {
+		return nil, errors.New("team ID cannot be empty")
+	}
+
+	channelIDs := []string{}
+	err := p.store.selectBuilder(p.store.db, &channelIDs, p.queryBuilder.
+		Select("DISTINCT ChannelID").
+		From("CPI_WikiDocs").
+		Where(sq.Eq{"TeamID": teamID, "DeleteAt": 0}).
+		OrderBy("ChannelID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get channels of team '%s'", teamID)
+	}
+
+	return channelIDs, nil
+}
+
+// GetArchivedWikiDocsForChannel retrieves the wikiDocs of a channel that are in the trash, without
+// their content, most recently deleted first.
+func (p *wikiDocStore) GetArchivedWikiDocsForChannel(channelID string) ([]app.WikiDoc, error) {
+	if channelID == "" {
+		return nil, errors.New("channel ID cannot be empty")
+	}
+
+	wikiDocs := []app.WikiDoc{}
+	err := p.store.selectBuilder(p.store.db, &wikiDocs, p.wikiDocSummarySelect.
+		Where(sq.Eq{"w.ChannelID": channelID}).
+		Where(sq.NotEq{"w.DeleteAt": 0}).
+		OrderBy("w.DeleteAt DESC", "w.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get archived wikiDocs of channel '%s'", channelID)
+	}
+
+	return wikiDocs, nil
+}
+
+// GetArchivedWikiDocIDs retrieves the IDs of the wikiDocs moved to the trash before deleteAt.
+func (p *wikiDocStore) GetArchivedWikiDocIDs(before int64) ([]string, error) {
+	ids := []string{}
+	err := p.store.selectBuilder(p.store.db, &ids, p.queryBuilder.
+		Select("ID").
+		From("CPI_WikiDocs").
+		Where(sq.NotEq{"DeleteAt": 0}).
+		Where(sq.Lt{"DeleteAt": before}).
+		OrderBy("DeleteAt ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get wikiDocs archived before %d", before)
+	}
+
+	return ids, nil
+}
+
+// Reorder places the given wikiDocs under parentID, in the given order. The moved wikiDocs, by ID,
+// are only placed if the stored wikiDoc still has the given UpdateAt, otherwise app.ErrConflict is
+// returned. Their UpdateAt is increased to updateAt, or by one if the clock went backwards.
+func (p *wikiDocStore) Reorder(parentID string, orderedIDs []string, moved map[string]int64, updateAt int64) error {
+	tx, err := p.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer p.store.finalizeTransaction(tx)
+
+	for i, id := range orderedIDs {
+		values := map[string]interface{}{
+			"ParentID":  parentID,
+			"SortOrder": i,
+		}
+		where := sq.Eq{"ID": id}
+		previousUpdateAt, isMoved := moved[id]
+		if isMoved {
+			values["UpdateAt"] = sq.Expr("GREATEST(UpdateAt + 1, ?)", updateAt)
+			where["UpdateAt"] = previousUpdateAt
+		}
+
+		result, err := p.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocs").
+			SetMap(values).
+			Where(where))
+		if err != nil {
+			return errors.Wrapf(err, "failed to set position of wikiDoc with id '%s'", id)
+		}
+		if !isMoved {
+			continue
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return errors.Wrapf(err, "failed to set position of wikiDoc with id '%s'", id)
+		}
+		if rowsAffected == 0 {
+			return errors.Wrapf(app.ErrConflict, "wikiDoc with id '%s' was modified since %d", id, previousUpdateAt)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// insertRevision records the given state of a wikiDoc as its next revision.
+// It must be called within the transaction that writes the wikiDoc.
+func (p *wikiDocStore) insertRevision(tx *sqlx.Tx, wikiDoc app.WikiDoc, userID string, createAt int64) error {
+	var lastRevision int64
+	err := p.store.getBuilder(tx, &lastRevision, sq.
+		Select("COALESCE(MAX(Revision), 0)").
+		From("CPI_WikiDocRevisions").
+		Where(sq.Eq{"WikiDocID": wikiDoc.ID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get last revision of wikiDoc with id '%s'", wikiDoc.ID)
+	}
+
+	_, err = p.store.execBuilder(tx, sq.
+		Insert("CPI_WikiDocRevisions").
+		SetMap(map[string]interface{}{
+			"ID":          model.NewId(),
+			"WikiDocID":   wikiDoc.ID,
+			"Revision":    lastRevision + 1,
+			"Name":        wikiDoc.Name,
+			"Content":     wikiDoc.Content,
+			"Description": wikiDoc.Description,
+			"Status":      wikiDoc.Status,
+			"UserID":      userID,
+			"CreateAt":    createAt,
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to store revision of wikiDoc with id '%s'", wikiDoc.ID)
+	}
+
 	return nil
 }
 
+// insertStatusChange records a status transition of a wikiDoc.
+// It must be called within the transaction that writes the wikiDoc.
+func (p *wikiDocStore) insertStatusChange(tx *sqlx.Tx, wikiDocID, fromStatus, toStatus, userID string, createAt int64) error {
+	_, err := p.store.execBuilder(tx, sq.
+		Insert("CPI_WikiDocStatusChanges").
+		SetMap(map[string]interface{}{
+			"ID":         model.NewId(),
+			"WikiDocID":  wikiDocID,
+			"FromStatus": fromStatus,
+			"ToStatus":   toStatus,
+			"UserID":     userID,
+			"CreateAt":   createAt,
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to store status change of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return nil
+}
+
+// GetStatusChanges retrieves the status transitions of a wikiDoc, newest first.
+func (p *wikiDocStore) GetStatusChanges(wikiDocID string) ([]app.WikiDocStatusChange, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	changes := []app.WikiDocStatusChange{}
+	err := p.store.selectBuilder(p.store.db, &changes, p.queryBuilder.
+		Select(
+			"s.ID",
+			"s.WikiDocID",
+			"s.FromStatus",
+			"s.ToStatus",
+			"s.UserID",
+			"s.CreateAt",
+		).
+		From("CPI_WikiDocStatusChanges s").
+		Where(sq.Eq{"s.WikiDocID": wikiDocID}).
+		OrderBy("s.CreateAt DESC", "s.ID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get status changes of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return changes, nil
+}
+
+// GetRevisions retrieves the revisions of a wikiDoc, newest first. Content is not populated.
+func (p *wikiDocStore) GetRevisions(wikiDocID string) ([]app.WikiDocRevision, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	revisions := []app.WikiDocRevision{}
+	err := p.store.selectBuilder(p.store.db, &revisions, p.queryBuilder.
+		Select(
+			"r.ID",
+			"r.WikiDocID",
+			"r.Revision",
+			"r.Name",
+			"r.Description",
+			"r.Status",
+			"r.UserID",
+			"r.CreateAt",
+		).
+		From("CPI_WikiDocRevisions r").
+		Where(sq.Eq{"r.WikiDocID": wikiDocID}).
+		OrderBy("r.Revision DESC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get revisions of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision retrieves a single revision of a wikiDoc.
+func (p *wikiDocStore) GetRevision(wikiDocID string, revision int64) (app.WikiDocRevision, error) {
+	if wikiDocID == "" {
+		return app.WikiDocRevision{}, errors.New("ID cannot be empty")
+	}
+
+	var rev app.WikiDocRevision
+	err := p.store.getBuilder(p.store.db, &rev, p.queryBuilder.
+		Select(
+			"r.ID",
+			"r.WikiDocID",
+			"r.Revision",
+			"r.Name",
+			"r.Content",
+			"r.Description",
+			"r.Status",
+			"r.UserID",
+			"r.CreateAt",
+		).
+		From("CPI_WikiDocRevisions r").
+		Where(sq.Eq{"r.WikiDocID": wikiDocID, "r.Revision": revision}))
+	if err == sql.ErrNoRows {
+		return app.WikiDocRevision{}, errors.Wrapf(app.ErrNotFound, "revision %d does not exist for wikiDoc '%s'", revision, wikiDocID)
+	} else if err != nil {
+		return app.WikiDocRevision{}, errors.Wrapf(err, "failed to get revision %d of wikiDoc '%s'", revision, wikiDocID)
+	}
+
+	return rev, nil
+}
+
 func toSQLWikiDoc(wikiDocs app.WikiDoc) (*sqlWikiDoc, error) {
 	return &sqlWikiDoc{
 		WikiDoc: wikiDocs,
@@ -0,0 +1,184 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/app"
+	"github.com/CyberPeace-Institute/mattermost-plugin-wiki/server/bot"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// wikiDocMemberStore is a sql store for the members of wikiDocs and the roles granted to groups. Use NewWikiDocMemberStore to create it.
+type wikiDocMemberStore struct {
+	pluginAPI    PluginAPIClient
+	log          bot.Logger
+	store        *SQLStore
+	queryBuilder sq.StatementBuilderType
+	memberSelect sq.SelectBuilder
+
+	groupGrantSelect sq.SelectBuilder
+}
+
+// Ensure wikiDocMemberStore implements the app.WikiDocMemberStore interface.
+var _ app.WikiDocMemberStore = (*wikiDocMemberStore)(nil)
+
+// NewWikiDocMemberStore creates a new store for the members of wikiDocs.
+func NewWikiDocMemberStore(pluginAPI PluginAPIClient, log bot.Logger, sqlStore *SQLStore) app.WikiDocMemberStore {
+	memberSelect := sqlStore.builder.
+		Select(
+			"m.WikiDocID",
+			"m.UserID",
+			"m.Role",
+			"m.CreateAt",
+			"m.UpdateAt",
+		).
+		From("CPI_WikiDocMembers m")
+
+	groupGrantSelect := sqlStore.builder.
+		Select(
+			"g.GroupID",
+			"g.ChannelID",
+			"g.WikiDocID",
+			"g.Role",
+			"g.CreateAt",
+			"g.UpdateAt",
+		).
+		From("CPI_WikiGroupGrants g")
+
+	return &wikiDocMemberStore{
+		pluginAPI:    pluginAPI,
+		log:          log,
+		store:        sqlStore,
+		queryBuilder: sqlStore.builder,
+		memberSelect: memberSelect,
+
+		groupGrantSelect: groupGrantSelect,
+	}
+}
+
+// GetMembers retrieves the members of a wikiDoc, oldest first.
+func (s *wikiDocMemberStore) GetMembers(wikiDocID string) ([]app.WikiDocMember, error) {
+	if wikiDocID == "" {
+		return nil, errors.New("ID cannot be empty")
+	}
+
+	members := []app.WikiDocMember{}
+	err := s.store.selectBuilder(s.store.db, &members, s.memberSelect.
+		Where(sq.Eq{"m.WikiDocID": wikiDocID}).
+		OrderBy("m.CreateAt ASC", "m.UserID ASC"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get members of wikiDoc with id '%s'", wikiDocID)
+	}
+
+	return members, nil
+}
+
+// GetRole retrieves the role of a user on a wikiDoc, empty if they have none.
+func (s *wikiDocMemberStore) GetRole(wikiDocID, userID string) (app.WikiDocRole, error) {
+	if wikiDocID == "" || userID == "" {
+		return "", errors.New("IDs cannot be empty")
+	}
+
+	var role app.WikiDocRole
+	err := s.store.getBuilder(s.store.db, &role, s.queryBuilder.
+		Select("Role").
+		From("CPI_WikiDocMembers").
+		Where(sq.Eq{"WikiDocID": wikiDocID, "UserID": userID}))
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "failed to get role of user '%s' on wikiDoc '%s'", userID, wikiDocID)
+	}
+
+	return role, nil
+}
+
+// GetRolesInChannel retrieves the roles of a user on the wikiDocs of a channel, by wikiDoc ID.
+func (s *wikiDocMemberStore) GetRolesInChannel(userID, channelID string) (map[string]app.WikiDocRole, error) {
+	if userID == "" || channelID == "" {
+		return nil, errors.New("IDs cannot be empty")
+	}
+
+	members := []app.WikiDocMember{}
+	err := s.store.selectBuilder(s.store.db, &members, s.memberSelect.
+		Join("CPI_WikiDocs w ON w.ID = m.WikiDocID").
+		Where(sq.Eq{"m.UserID": userID, "w.ChannelID": channelID}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get roles of user '%s' in channel '%s'", userID, channelID)
+	}
+
+	roles := make(map[string]app.WikiDocRole, len(members))
+	for _, member := range members {
+		roles[member.WikiDocID] = member.Role
+	}
+
+	return roles, nil
+}
+
+// SaveMember grants a role to a user on a wikiDoc, replacing the role they had and keeping the time
+// they were first granted one.
+func (s *wikiDocMemberStore) SaveMember(member app.WikiDocMember) (app.WikiDocMember, error) {
+	if member.WikiDocID == "" || member.UserID == "" {
+		return app.WikiDocMember{}, errors.New("IDs cannot be empty")
+	}
+
+	tx, err := s.store.db.Beginx()
+	if err != nil {
+		return app.WikiDocMember{}, errors.Wrap(err, "could not begin transaction")
+	}
+	defer s.store.finalizeTransaction(tx)
+
+	var existing app.WikiDocMember
+	err = s.store.getBuilder(tx, &existing, s.memberSelect.
+		Where(sq.Eq{"m.WikiDocID": member.WikiDocID, "m.UserID": member.UserID}))
+	if err != nil && err != sql.ErrNoRows {
+		return app.WikiDocMember{}, errors.Wrapf(err, "failed to get role of user '%s' on wikiDoc '%s'", member.UserID, member.WikiDocID)
+	}
+
+	if err == sql.ErrNoRows {
+		_, err = s.store.execBuilder(tx, sq.
+			Insert("CPI_WikiDocMembers").
+			SetMap(map[string]interface{}{
+				"WikiDocID": member.WikiDocID,
+				"UserID":    member.UserID,
+				"Role":      member.Role,
+				"CreateAt":  member.CreateAt,
+				"UpdateAt":  member.UpdateAt,
+			}))
+	} else {
+		member.CreateAt = existing.CreateAt
+		_, err = s.store.execBuilder(tx, sq.
+			Update("CPI_WikiDocMembers").
+			SetMap(map[string]interface{}{
+				"Role":     member.Role,
+				"UpdateAt": member.UpdateAt,
+			}).
+			Where(sq.Eq{"WikiDocID": member.WikiDocID, "UserID": member.UserID}))
+	}
+	if err != nil {
+		return app.WikiDocMember{}, errors.Wrapf(err, "failed to store role of user '%s' on wikiDoc '%s'", member.UserID, member.WikiDocID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return app.WikiDocMember{}, errors.Wrap(err, "could not commit transaction")
+	}
+
+	return member, nil
+}
+
+// DeleteMember revokes the role of a user on a wikiDoc.
+func (s *wikiDocMemberStore) DeleteMember(wikiDocID, userID string) error {
+	if wikiDocID == "" || userID == "" {
+		return errors.New("IDs cannot be empty")
+	}
+
+	_, err := s.store.execBuilder(s.store.db, sq.
+		Delete("CPI_WikiDocMembers").
+		Where(sq.Eq{"WikiDocID": wikiDocID, "UserID": userID}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete role of user '%s' on wikiDoc '%s'", userID, wikiDocID)
+	}
+
+	return nil
+}
@@ -23,8 +23,11 @@ export interface FetchWikiDocsReturn {
 }
 
 export enum WikiDocStatus {
-    Private = 'Private',
+    Draft = 'Draft',
+    InReview = 'In Review',
     Published = 'Published',
+    Deprecated = 'Deprecated',
+    Archived = 'Archived',
 }
 
 export function getWikiDocStatuses() {